@@ -1,8 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 	"workshop2/internal/app/api"
+	"workshop2/internal/app/config"
+	"workshop2/internal/app/logging"
+
+	"github.com/sirupsen/logrus"
 )
 
 func init() {
@@ -10,9 +21,44 @@ func init() {
 }
 
 func main() {
-	server := api.New()
+	cfg, opts, err := config.Load(os.Args[0], os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	if err := server.Start(); err != nil {
+	if opts.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := logging.Configure(os.Stderr, cfg.Log.Level); err != nil {
 		log.Fatal(err)
 	}
+
+	if err := run(cfg); err != nil {
+		logrus.WithError(err).Fatal("server stopped")
+	}
+}
+
+func run(cfg config.Config) error {
+	if cfg.Auth.SigningKey == config.DefaultSigningKey {
+		logrus.Warn("tokens are signed with the built-in development key; set auth.signing_key")
+	}
+
+	server, err := api.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return server.Run(ctx)
 }
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+	"workshop2/internal/app/logging"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// AccessLogMiddleware gives every request an ID, taken from a well-formed
+// X-Request-ID header or generated, echoes it in the response and logs one
+// line per request once it is done. Bodies, headers and query strings are
+// never logged, so passwords and tokens stay out of the log.
+type AccessLogMiddleware struct{}
+
+func (mw *AccessLogMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		info := &accessInfo{}
+		ctx := logging.WithRequestID(r.Context(), id)
+		r = r.WithContext(context.WithValue(ctx, accessInfoKey{}, info))
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		entry := logging.FromRequest(r).WithFields(logrus.Fields{
+			"method":     r.Method,
+			"route":      info.route,
+			"status":     status,
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"bytes":      recorder.bytes,
+			"username":   info.getUsername(),
+		})
+		if status >= http.StatusInternalServerError {
+			entry.Error("request")
+		} else {
+			entry.Info("request")
+		}
+	})
+}
+
+// RouteMiddleware records the matched route template for the access log.
+type RouteMiddleware struct{}
+
+func (mw *RouteMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := r.Context().Value(accessInfoKey{}).(*accessInfo); ok {
+			if route := mux.CurrentRoute(r); route != nil {
+				info.route, _ = route.GetPathTemplate()
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type accessInfoKey struct{}
+
+// accessInfo is filled in by the inner middlewares. The username is set
+// behind the request timeout, which runs handlers in another goroutine.
+type accessInfo struct {
+	route string
+
+	mu       sync.Mutex
+	username string
+}
+
+func (i *accessInfo) getUsername() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.username
+}
+
+func setAccessUsername(r *http.Request, username string) {
+	if info, ok := r.Context().Value(accessInfoKey{}).(*accessInfo); ok {
+		info.mu.Lock()
+		info.username = username
+		info.mu.Unlock()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder passes flushes and hijacks through so that streams and
+// websockets keep working behind it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+
+	return n, err
+}
+
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("connection does not support hijacking")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		w.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"workshop2/internal/app/logging"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger := logrus.StandardLogger()
+	out, formatter, level := logger.Out, logger.Formatter, logger.Level
+	t.Cleanup(func() {
+		logger.SetOutput(out)
+		logger.SetFormatter(formatter)
+		logger.SetLevel(level)
+	})
+
+	if err := logging.Configure(&buf, "debug"); err != nil {
+		t.Fatal(err)
+	}
+
+	return &buf
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		lines = append(lines, fields)
+	}
+
+	return lines
+}
+
+func newLoggedRouter() http.Handler {
+	router := mux.NewRouter()
+	routeMiddleware := RouteMiddleware{}
+	router.Use(routeMiddleware.Handle)
+	router.HandleFunc("/api/v1/events/{id}", func(w http.ResponseWriter, r *http.Request) {
+		setAccessUsername(r, "alice")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	})
+	router.HandleFunc("/api/v1/sign-in", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "token", Value: "secret-token"})
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	accessLog := AccessLogMiddleware{}
+	return accessLog.Handle(router)
+}
+
+func TestAccessLog(t *testing.T) {
+	buf := captureLog(t)
+
+	r := httptest.NewRequest(http.MethodPut, "/api/v1/events/7?token=abc", nil)
+	r.Header.Set(requestIDHeader, "client-id.1")
+	w := httptest.NewRecorder()
+	newLoggedRouter().ServeHTTP(w, r)
+
+	if w.Header().Get(requestIDHeader) != "client-id.1" {
+		t.Errorf("expected the request ID to be echoed, got %q", w.Header().Get(requestIDHeader))
+	}
+
+	lines := logLines(t, buf)
+	if len(lines) != 1 {
+		t.Fatalf("expected one log line, got %d", len(lines))
+	}
+
+	expected := map[string]interface{}{
+		"msg":        "request",
+		"level":      "info",
+		"method":     "PUT",
+		"route":      "/api/v1/events/{id}",
+		"status":     float64(http.StatusCreated),
+		"bytes":      float64(5),
+		"username":   "alice",
+		"request_id": "client-id.1",
+	}
+	for key, value := range expected {
+		if lines[0][key] != value {
+			t.Errorf("expected %s to be %v, got %v", key, value, lines[0][key])
+		}
+	}
+	if _, ok := lines[0]["latency_ms"].(float64); !ok {
+		t.Errorf("expected a latency, got %v", lines[0]["latency_ms"])
+	}
+	if strings.Contains(buf.String(), "abc") {
+		t.Errorf("query string leaked into the log: %s", buf.String())
+	}
+}
+
+func TestAccessLogLeavesOutCredentials(t *testing.T) {
+	buf := captureLog(t)
+
+	body := strings.NewReader(`{"username":"alice","password":"passw0rd!"}`)
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/sign-in", body)
+	r.AddCookie(&http.Cookie{Name: "token", Value: "old-token"})
+	w := httptest.NewRecorder()
+	newLoggedRouter().ServeHTTP(w, r)
+
+	for _, secret := range []string{"passw0rd!", "secret-token", "old-token"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("%q leaked into the log: %s", secret, buf.String())
+		}
+	}
+
+	lines := logLines(t, buf)
+	if lines[0]["level"] != "error" || lines[0]["status"] != float64(http.StatusInternalServerError) {
+		t.Errorf("expected server errors to be logged as errors, got %v", lines[0])
+	}
+}
+
+func TestAccessLogRequestID(t *testing.T) {
+	captureLog(t)
+
+	for _, header := range []string{"", "has space", strings.Repeat("a", 129), "line\nbreak"} {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/events/1", nil)
+		r.Header.Set(requestIDHeader, header)
+		w := httptest.NewRecorder()
+		newLoggedRouter().ServeHTTP(w, r)
+
+		id := w.Header().Get(requestIDHeader)
+		if id == header || len(id) != 32 {
+			t.Errorf("expected a generated ID in place of %q, got %q", header, id)
+		}
+	}
+}
+
+func TestStatusRecorderPassesThrough(t *testing.T) {
+	var w http.ResponseWriter = &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+
+	if _, ok := w.(http.Flusher); !ok {
+		t.Error("expected the recorder to support flushing")
+	}
+	if _, _, err := w.(http.Hijacker).Hijack(); err == nil {
+		t.Error("expected hijacking to fail when the underlying writer cannot")
+	}
+}
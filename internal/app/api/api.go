@@ -1,109 +1,460 @@
 package api
 
 import (
+	"context"
+	"crypto/tls"
+	"io"
 	"log"
 	"net/http"
+	"path/filepath"
+	"strings"
 	"time"
 	"workshop2/internal/app/api/controller"
+	"workshop2/internal/app/config"
+	"workshop2/internal/app/logging"
 	"workshop2/internal/app/models"
 	"workshop2/internal/app/repositories"
+	"workshop2/internal/app/repositories/cache"
 	"workshop2/internal/app/services"
 	"workshop2/internal/app/utils"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
 )
 
 type API struct {
-	port          string
-	router        *mux.Router
-	prefix        string
-	events        controller.EventController
-	notifications controller.NotificationController
-	users         controller.UserController
-	auth          controller.AuthController
+	server              config.Server
+	tls                 config.TLS
+	tlsConfig           *tls.Config
+	closing             chan struct{}
+	router              *mux.Router
+	prefix              string
+	events              controller.EventController
+	eventService        *services.EventService
+	notifications       controller.NotificationController
+	notificationService *services.NotificationService
+	dispatcher          *services.NotificationDispatcher
+	sync                controller.SyncController
+	changes             controller.ChangeFeedController
+	webhooks            controller.WebhookController
+	webhookService      *services.WebhookService
+	templates           controller.TemplateController
+	deliveries          controller.DeliveryController
+	backups             controller.BackupController
+	caches              controller.CacheController
+	admins              []string
+	store               io.Closer
+	users               controller.UserController
+	auth                controller.AuthController
 }
 
-func New() *API {
+func New(cfg config.Config) (*API, error) {
+	tlsConfig, err := newTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
 	validator := utils.NewValidator()
 
-	userRepository := &repositories.UserRepository{
-		Users:     make([]models.User, 0),
-		Validator: validator,
+	memoryStore := &repositories.MemoryStore{
+		Events:        &repositories.EventRepository{},
+		Notifications: &repositories.NotificationRepository{},
+		Users:         &repositories.UserRepository{Validator: validator},
+	}
+
+	var userRepository services.UserRepositoryInterface = memoryStore.Users
+	var eventRepository services.EventRepositoryInterface = memoryStore.Events
+	var notificationRepository services.NotificationRepositoryInterface = memoryStore.Notifications
+	var transactor services.TransactorInterface = services.TransactorFunc(func(fn func(store services.Store) error) error {
+		return memoryStore.Transaction(func(tx repositories.MemoryTx) error {
+			return fn(services.Store{Events: tx.Events, Notifications: tx.Notifications, Users: tx.Users})
+		})
+	})
+	var historyRepository services.HistoryRepositoryInterface = &repositories.HistoryRepository{}
+	var changeLog services.ChangeLogRepositoryInterface = &repositories.ChangeLogRepository{MaxEntries: 100000}
+	var dueMark services.DueMarkRepositoryInterface = &repositories.DueMarkRepository{}
+	backupService := &services.BackupService{Dir: "backups"}
+	var caches []controller.CacheInterface
+	var cacheObservers []services.ObserverInterface
+
+	var store io.Closer
+	if path := cfg.Storage.Path; path != "" {
+		boltStore, err := repositories.OpenBoltStore(path)
+		if err != nil {
+			return nil, err
+		}
+
+		boltUsers := &repositories.BoltUserRepository{Store: boltStore, Validator: validator}
+		boltEvents := &repositories.BoltEventRepository{Store: boltStore}
+		boltNotifications := &repositories.BoltNotificationRepository{Store: boltStore}
+
+		// Reads are served from a cache; writes made in transactions
+		// bypass it and invalidate it through the published changes.
+		cacheTTL := time.Duration(cfg.Storage.CacheTTL)
+		cachedEvents := cache.NewEventRepository(boltEvents, cfg.Storage.CacheEntries, cacheTTL)
+		cachedNotifications := cache.NewNotificationRepository(boltNotifications, cfg.Storage.CacheEntries, cacheTTL)
+		caches = []controller.CacheInterface{cachedEvents, cachedNotifications}
+		cacheObservers = []services.ObserverInterface{cachedEvents, cachedNotifications}
+
+		store = boltStore
+		userRepository = boltUsers
+		eventRepository = cachedEvents
+		notificationRepository = cachedNotifications
+		transactor = services.TransactorFunc(func(fn func(store services.Store) error) error {
+			return boltStore.Transaction(func(tx *bolt.Tx) error {
+				return fn(services.Store{Events: boltEvents.InTx(tx), Notifications: boltNotifications.InTx(tx), Users: boltUsers.InTx(tx)})
+			})
+		})
+		historyRepository = &repositories.BoltHistoryRepository{Store: boltStore}
+		changeLog = &repositories.BoltChangeLogRepository{Store: boltStore, MaxEntries: 100000}
+		dueMark = &repositories.BoltDueMarkRepository{Store: boltStore}
+		backupService.Store = boltStore
+		backupService.Dir = filepath.Join(filepath.Dir(path), "backups")
 	}
 
 	authService := services.NewAuth(
 		userRepository,
 		validator,
-		time.Hour*6,
-		time.Hour*24*31,
-		"keyyt",
+		time.Duration(cfg.Auth.TokenLifetime),
+		time.Duration(cfg.Auth.RefreshTokenLifetime),
+		string(cfg.Auth.SigningKey),
 		jwt.SigningMethodHS256,
 	)
+	authService.Transactor = transactor
+
+	webhookService := services.NewWebhook(
+		&repositories.WebhookRepository{
+			Webhooks:   make([]models.Webhook, 0),
+			Deliveries: make([]models.WebhookDelivery, 0),
+		},
+		validator,
+		services.WebhookTargets{AllowHTTP: cfg.Webhooks.AllowHTTP, AllowPrivate: cfg.Webhooks.AllowPrivate},
+		time.Second*10,
+		5,
+		time.Second*5,
+		10,
+		1024,
+	)
+
+	eventSync := services.NewEventSync(64)
+	changeFeed, err := services.NewChangeFeed(
+		changeLog,
+		eventRepository,
+		notificationRepository,
+		time.Hour*24*30,
+		500,
+	)
+	if err != nil {
+		if store != nil {
+			store.Close()
+		}
+		return nil, err
+	}
+
+	eventService := &services.EventService{
+		Events:         eventRepository,
+		History:        historyRepository,
+		Observers:      append(cacheObservers, webhookService, eventSync, changeFeed),
+		Transactor:     transactor,
+		Validator:      validator,
+		TrashRetention: time.Duration(cfg.Storage.TrashRetention),
+	}
+
+	mailer := services.NewSMTPMailer(services.SMTPConfig{
+		Host:     cfg.SMTP.Host,
+		Port:     cfg.SMTP.Port,
+		Username: cfg.SMTP.Username,
+		Password: string(cfg.SMTP.Password),
+		From:     cfg.SMTP.From,
+		Security: cfg.SMTP.Security,
+		Timeout:  time.Second * 10,
+	})
+
+	notificationStream := services.NewNotificationStream(256, 64, time.Second*15)
+
+	retryPolicy := services.RetryPolicy{
+		MaxAttempts:        map[string]int{"email": 6, "push": 1},
+		DefaultMaxAttempts: 3,
+		Backoff:            time.Second * 10,
+		MaxBackoff:         time.Minute * 30,
+	}
+	dispatcher := services.NewNotificationDispatcher(
+		userRepository,
+		// Queued and slow deliveries take longer than the retry delays.
+		&repositories.DeliveryRepository{Retention: retryPolicy.Horizon() + time.Hour},
+		[]services.NotificationChannelInterface{
+			&services.EmailChannel{Mailer: mailer},
+			notificationStream,
+		},
+		retryPolicy,
+		1024,
+	)
+
+	notificationService := &services.NotificationService{
+		Notifications: notificationRepository,
+		History:       historyRepository,
+		DueMark:       dueMark,
+		Observers:     append(cacheObservers, webhookService, dispatcher, changeFeed),
+		Transactor:    transactor,
+		Validator:     validator,
+	}
+	eventService.Reminders = notificationService
+
+	cookie := controller.TokenCookie{
+		Lifetime: time.Duration(cfg.Auth.CookieLifetime),
+		Secure:   cfg.TLS.Enabled(),
+	}
 
 	return &API{
-		port:   ":8002",
-		router: mux.NewRouter(),
-		prefix: "/api/v1",
+		store:               store,
+		server:              cfg.Server,
+		tls:                 cfg.TLS,
+		tlsConfig:           tlsConfig,
+		closing:             make(chan struct{}),
+		router:              mux.NewRouter(),
+		prefix:              cfg.Server.Prefix,
+		eventService:        eventService,
+		notificationService: notificationService,
+		dispatcher:          dispatcher,
+		webhookService:      webhookService,
 		events: controller.EventController{
-			Events: &services.EventService{
-				Events: &repositories.EventRepository{
-					Events: make([]models.Event, 0),
-				},
+			Events: eventService,
+			Auth:   authService,
+			InSession: func(session string) controller.EventServiceInterface {
+				return eventService.InSession(session)
 			},
+		},
+		sync: controller.SyncController{
+			Sync: eventSync,
+			Auth: authService,
+		},
+		changes: controller.ChangeFeedController{
+			Feed: changeFeed,
 			Auth: authService,
 		},
+		webhooks: controller.WebhookController{
+			Webhooks: webhookService,
+			Auth:     authService,
+		},
 		users: controller.UserController{
 			Users: &services.UserService{
-				userRepository,
+				Users:  userRepository,
+				Mailer: mailer,
 			},
-			Auth: authService,
+			Auth:   authService,
+			Cookie: cookie,
 		},
 		notifications: controller.NotificationController{
-			Notifications: &services.NotificationService{
-				Notifications: &repositories.NotificationRepository{
-					Notifications: make([]models.Notification, 0),
-				},
+			Notifications: notificationService,
+			Streamer:      notificationStream,
+			Auth:          authService,
+		},
+		templates: controller.TemplateController{
+			Templates: &services.TemplateService{
+				Templates:     &repositories.TemplateRepository{},
+				Events:        eventService.Events,
+				Users:         userRepository,
+				Notifications: notificationService,
+				Validator:     validator,
 			},
 			Auth: authService,
 		},
+		deliveries: controller.DeliveryController{
+			Deliveries: dispatcher,
+		},
+		backups: controller.BackupController{
+			Backups: backupService,
+		},
+		caches: controller.CacheController{
+			Caches: caches,
+		},
+		admins: cfg.Admins,
 		auth: controller.AuthController{
-			Auth: authService,
+			Auth:   authService,
+			Cookie: cookie,
 		},
+	}, nil
+}
+
+// Close releases the storage backend, if any.
+func (api *API) Close() error {
+	if api.store == nil {
+		return nil
 	}
+
+	return api.store.Close()
 }
 
-func (api *API) Start() error {
+// Run serves the API until ctx is cancelled, then stops accepting
+// connections and waits for in-flight requests to finish before the
+// background workers are stopped.
+func (api *API) Run(ctx context.Context) error {
 	api.configureRoutes()
-	return http.ListenAndServe(api.port, api.router)
+
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	defer api.dispatcher.Close()
+	go api.eventService.PurgeTrashPeriodically(workers, time.Hour)
+	go api.notificationService.WatchDue(workers, time.Second*15)
+	api.webhookService.Run(workers, 4)
+	api.dispatcher.Run(workers, 4)
+
+	accessLog := AccessLogMiddleware{}
+	server := api.newServer(api.server.Addr, accessLog.Handle(api.router))
+	server.TLSConfig = api.tlsConfig
+	server.RegisterOnShutdown(func() { close(api.closing) })
+	servers := []*http.Server{server}
+
+	if api.tls.RedirectAddr != "" {
+		servers = append(servers, api.newServer(api.tls.RedirectAddr, redirectToHTTPS(api.server.Addr)))
+	}
+
+	serveErr := make(chan error, len(servers))
+	for _, s := range servers {
+		go func(s *http.Server) {
+			if s.TLSConfig != nil {
+				serveErr <- s.ListenAndServeTLS("", "")
+			} else {
+				serveErr <- s.ListenAndServe()
+			}
+		}(s)
+	}
+
+	var err error
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+	}
+
+	logrus.Info("shutting down, draining in-flight requests")
+	drain, cancel := context.WithTimeout(context.Background(), time.Duration(api.server.ShutdownTimeout))
+	defer cancel()
+
+	for _, s := range servers {
+		if shutdownErr := s.Shutdown(drain); err == nil {
+			err = shutdownErr
+		}
+	}
+
+	return err
+}
+
+// newServer only speaks HTTP/1.1: HTTP/2 enforces the write timeout per
+// request, and streams could not be exempted from it.
+func (api *API) newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: time.Duration(api.server.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(api.server.ReadTimeout),
+		WriteTimeout:      time.Duration(api.server.WriteTimeout),
+		IdleTimeout:       time.Duration(api.server.IdleTimeout),
+		MaxHeaderBytes:    api.server.MaxHeaderBytes,
+		ConnContext:       withConn,
+		TLSNextProto:      map[string]func(*http.Server, *tls.Conn, http.Handler){},
+		ErrorLog:          log.New(logrus.StandardLogger().WriterLevel(logrus.WarnLevel), "", 0),
+	}
 }
 
 func (api *API) configureRoutes() {
-	authMiddleware := AuthenticationMiddleware{api.auth.Auth}
-	api.router.Use(authMiddleware.Handle)
+	routeMiddleware := RouteMiddleware{}
+	recoveryMiddleware := RecoveryMiddleware{}
+	limitMiddleware := LimitMiddleware{
+		maxBodyBytes: int64(api.server.MaxBodyBytes),
+		timeout:      time.Duration(api.server.RequestTimeout),
+		streams: map[string]bool{
+			api.prefix + "/events/live":          true,
+			api.prefix + "/notifications/stream": true,
+		},
+		closing: api.closing,
+	}
+	authMiddleware := AuthenticationMiddleware{
+		auth:        api.auth.Auth,
+		public:      []string{api.prefix + "/sign-in", api.prefix + "/sign-up"},
+		clientUsers: api.tls.ClientUsers,
+	}
+	api.router.Use(routeMiddleware.Handle, limitMiddleware.Handle, recoveryMiddleware.Handle, authMiddleware.Handle)
 
 	api.router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		_, err := w.Write([]byte("Hello! This is Workshop2 API!"))
 		if err != nil {
-			log.Fatal(err.Error())
+			logging.FromRequest(r).WithError(err).Warn("writing response")
 		}
 
 	}).Methods(http.MethodGet)
 
+	api.router.HandleFunc(api.prefix+"/sync", api.changes.Sync).Methods(http.MethodGet)
 	api.router.HandleFunc(api.prefix+"/events", api.events.GetAll).Methods(http.MethodGet)
 	api.router.HandleFunc(api.prefix+"/events", api.events.GetAll).Queries("interval", "{interval}").Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/events/live", api.sync.Connect).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/events/trash", api.events.GetTrash).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/events/trash/{id}/restore", api.events.Restore).Methods(http.MethodPost)
+	api.router.HandleFunc(api.prefix+"/events/trash/{id}", api.events.Purge).Methods(http.MethodDelete)
 	api.router.HandleFunc(api.prefix+"/events/{id}", api.events.Get).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/events/{id}/history", api.events.GetHistory).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/events/{id}/history/{version}/revert", api.events.Revert).Methods(http.MethodPost)
 	api.router.HandleFunc(api.prefix+"/events", api.events.Create).Methods(http.MethodPost)
 	api.router.HandleFunc(api.prefix+"/events/{id}", api.events.Update).Methods(http.MethodPut)
+	api.router.HandleFunc(api.prefix+"/events/{id}", api.events.Patch).Methods(http.MethodPatch)
 	api.router.HandleFunc(api.prefix+"/events/{id}", api.events.Delete).Methods(http.MethodDelete)
 
 	api.router.HandleFunc(api.prefix+"/notifications", api.notifications.GetAll).Methods(http.MethodGet)
 	api.router.HandleFunc(api.prefix+"/notifications", api.notifications.GetAll).Queries("interval", "{interval}").Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/notifications/stream", api.notifications.Stream).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/notifications/unread-count", api.notifications.UnreadCount).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/notifications/read-all", api.notifications.MarkAllRead).Methods(http.MethodPost)
+	api.router.HandleFunc(api.prefix+"/notifications/{id}", api.notifications.Get).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/notifications/{id}/history", api.notifications.GetHistory).Methods(http.MethodGet)
 	api.router.HandleFunc(api.prefix+"/notifications", api.notifications.Create).Methods(http.MethodPost)
+	api.router.HandleFunc(api.prefix+"/notifications", api.notifications.DeleteMany).Methods(http.MethodDelete)
 	api.router.HandleFunc(api.prefix+"/notifications/{id}", api.notifications.Update).Methods(http.MethodPut)
+	api.router.HandleFunc(api.prefix+"/notifications/{id}", api.notifications.Patch).Methods(http.MethodPatch)
+	api.router.HandleFunc(api.prefix+"/notifications/{id}", api.notifications.Delete).Methods(http.MethodDelete)
+	api.router.HandleFunc(api.prefix+"/notifications/{id}/read", api.notifications.MarkRead).Methods(http.MethodPost)
+	api.router.HandleFunc(api.prefix+"/notifications/{id}/unread", api.notifications.MarkUnread).Methods(http.MethodPost)
+	api.router.HandleFunc(api.prefix+"/notifications/{id}/dismiss", api.notifications.Dismiss).Methods(http.MethodPost)
+	api.router.HandleFunc(api.prefix+"/notifications/{id}/snooze", api.notifications.Snooze).Methods(http.MethodPost)
+
+	api.router.HandleFunc(api.prefix+"/templates", api.templates.GetAll).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/templates", api.templates.Create).Methods(http.MethodPost)
+	api.router.HandleFunc(api.prefix+"/templates/{id}", api.templates.Get).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/templates/{id}", api.templates.Update).Methods(http.MethodPut)
+	api.router.HandleFunc(api.prefix+"/templates/{id}", api.templates.Delete).Methods(http.MethodDelete)
+	api.router.HandleFunc(api.prefix+"/templates/{id}/preview", api.templates.Preview).Methods(http.MethodPost)
+	api.router.HandleFunc(api.prefix+"/templates/{id}/render", api.templates.Render).Methods(http.MethodPost)
+
+	admins := make(map[string]bool, len(api.admins))
+	for _, username := range api.admins {
+		if username = strings.TrimSpace(username); username != "" {
+			admins[username] = true
+		}
+	}
+	adminMiddleware := AdminMiddleware{api.auth.Auth, admins}
+	admin := api.router.PathPrefix(api.prefix + "/admin").Subrouter()
+	admin.Use(adminMiddleware.Handle)
+	admin.HandleFunc("/dead-letters", api.deliveries.GetDeadLetters).Methods(http.MethodGet)
+	admin.HandleFunc("/dead-letters/{id}", api.deliveries.GetDeadLetter).Methods(http.MethodGet)
+	admin.HandleFunc("/dead-letters/{id}", api.deliveries.DeleteDeadLetter).Methods(http.MethodDelete)
+	admin.HandleFunc("/dead-letters/{id}/redrive", api.deliveries.Redrive).Methods(http.MethodPost)
+	admin.HandleFunc("/deliveries", api.deliveries.GetAttempts).Methods(http.MethodGet)
+	admin.HandleFunc("/backup", api.backups.Create).Methods(http.MethodPost)
+	admin.HandleFunc("/cache", api.caches.Stats).Methods(http.MethodGet)
+
+	api.router.HandleFunc(api.prefix+"/webhooks", api.webhooks.GetAll).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/webhooks", api.webhooks.Create).Methods(http.MethodPost)
+	api.router.HandleFunc(api.prefix+"/webhooks/{id}", api.webhooks.Get).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/webhooks/{id}", api.webhooks.Delete).Methods(http.MethodDelete)
+	api.router.HandleFunc(api.prefix+"/webhooks/{id}/enable", api.webhooks.Enable).Methods(http.MethodPost)
+	api.router.HandleFunc(api.prefix+"/webhooks/{id}/deliveries", api.webhooks.GetDeliveries).Methods(http.MethodGet)
 
 	api.router.HandleFunc(api.prefix+"/sign-in", api.auth.SignIn).Methods(http.MethodPost)
 	api.router.HandleFunc(api.prefix+"/sign-up", api.auth.SignUp).Methods(http.MethodPost)
 
 	api.router.HandleFunc(api.prefix+"/timezone", api.users.UpdateTimezone).Methods(http.MethodPut)
+	api.router.HandleFunc(api.prefix+"/email", api.users.UpdateEmail).Methods(http.MethodPut)
+	api.router.HandleFunc(api.prefix+"/email/verify", api.users.VerifyEmail).Methods(http.MethodPost)
+	api.router.HandleFunc(api.prefix+"/preferences", api.users.GetPreferences).Methods(http.MethodGet)
+	api.router.HandleFunc(api.prefix+"/preferences", api.users.UpdatePreferences).Methods(http.MethodPut)
 }
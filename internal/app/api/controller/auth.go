@@ -14,10 +14,12 @@ type AuthServiceInterface interface {
 	VerifyToken(token string) error
 	ExtractClaims(tokenString string) (jwt.MapClaims, error)
 	GenerateTokens(username string, timezone string) ([]models.Token, error)
+	UserClaims(username string) (jwt.MapClaims, error)
 }
 
 type AuthController struct {
-	Auth AuthServiceInterface
+	Auth   AuthServiceInterface
+	Cookie TokenCookie
 }
 
 func (c *AuthController) SignIn(w http.ResponseWriter, r *http.Request) {
@@ -27,19 +29,18 @@ func (c *AuthController) SignIn(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&signin)
 	if err != nil {
-		err = errs.NewFailedRequestParsingError()
-		respondWithError(w, err, http.StatusBadRequest)
+		respondWithParsingError(w, r, err)
 		return
 	}
 
 	tokens, err := c.Auth.SignIn(signin)
 	if err != nil {
-		err = errs.NewFailedAuthenticationError(err.Error())
-		respondWithError(w, err, http.StatusUnauthorized)
+		err = errs.NewFailedAuthenticationError(err)
+		respondWithError(w, r, err, http.StatusUnauthorized)
 		return
 	}
 
-	SetTokenCookie(w, tokens)
+	c.Cookie.Set(w, tokens)
 	respond(w, tokens, http.StatusOK)
 }
 
@@ -50,18 +51,17 @@ func (c *AuthController) SignUp(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&signup)
 	if err != nil {
-		err = errs.NewFailedRequestParsingError()
-		respondWithError(w, err, http.StatusBadRequest)
+		respondWithParsingError(w, r, err)
 		return
 	}
 
 	tokens, err := c.Auth.SignUp(signup)
 	if err != nil {
-		err = errs.NewFailedSignUpError(err.Error())
-		respondWithError(w, err, http.StatusInternalServerError)
+		err = errs.NewFailedSignUpError(err)
+		respondWithError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	SetTokenCookie(w, tokens)
+	c.Cookie.Set(w, tokens)
 	respond(w, tokens, http.StatusOK)
 }
@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"net/http"
+	"workshop2/internal/app/models"
+)
+
+type BackupServiceInterface interface {
+	Create() (models.Backup, error)
+}
+
+type BackupController struct {
+	Backups BackupServiceInterface
+}
+
+func (c *BackupController) Create(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	backup, err := c.Backups.Create()
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	respond(w, backup, http.StatusCreated)
+}
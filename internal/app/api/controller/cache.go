@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"net/http"
+	"workshop2/internal/app/models"
+)
+
+type CacheInterface interface {
+	Stats() models.CacheStats
+}
+
+type CacheController struct {
+	Caches []CacheInterface
+}
+
+func (c *CacheController) Stats(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	stats := make([]models.CacheStats, 0, len(c.Caches))
+	for _, cache := range c.Caches {
+		stats = append(stats, cache.Stats())
+	}
+
+	respond(w, stats, http.StatusOK)
+}
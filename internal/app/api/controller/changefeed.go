@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+type ChangeFeedInterface interface {
+	Sync(username string, token string, timezone time.Location) (models.SyncResponse, error)
+}
+
+type ChangeFeedController struct {
+	Feed ChangeFeedInterface
+	Auth AuthServiceInterface
+}
+
+func (c *ChangeFeedController) Sync(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	loc, err := GetUserTimezone(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	response, err := c.Feed.Sync(username, r.FormValue("token"), *loc)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusBadRequest))
+		return
+	}
+
+	respond(w, response, http.StatusOK)
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+
+	"github.com/gorilla/mux"
+)
+
+type DeliveryServiceInterface interface {
+	GetDeadLetters() ([]models.DeadLetter, error)
+	GetDeadLetter(id int) (models.DeadLetter, error)
+	GetAttempts(key string) ([]models.DeliveryAttempt, error)
+	DeleteDeadLetter(id int) error
+	Redrive(id int) error
+}
+
+type DeliveryController struct {
+	Deliveries DeliveryServiceInterface
+}
+
+func (c *DeliveryController) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	letters, err := c.Deliveries.GetDeadLetters()
+	if err != nil {
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	respond(w, letters, http.StatusOK)
+}
+
+func (c *DeliveryController) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	letter, err := c.Deliveries.GetDeadLetter(id)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	attempts, err := c.Deliveries.GetAttempts(letter.Key)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	respond(w, struct {
+		models.DeadLetter
+		History []models.DeliveryAttempt `json:"history"`
+	}{letter, attempts}, http.StatusOK)
+}
+
+func (c *DeliveryController) Redrive(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	err = c.Deliveries.Redrive(id)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *DeliveryController) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	err = c.Deliveries.DeleteDeadLetter(id)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *DeliveryController) GetAttempts(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	attempts, err := c.Deliveries.GetAttempts(r.FormValue("key"))
+	if err != nil {
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	respond(w, attempts, http.StatusOK)
+}
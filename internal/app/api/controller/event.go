@@ -2,39 +2,66 @@ package controller
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
 	"workshop2/internal/app/errs"
 	"workshop2/internal/app/models"
+	"workshop2/internal/app/utils"
 
 	"github.com/gorilla/mux"
 )
 
 type EventServiceInterface interface {
-	GetAll(interval string, timezone time.Location) ([]models.Event, error)
-	Get(id int) (models.Event, error)
-	Create(event models.Event) (models.Event, error)
-	Update(id int, newEvent models.Event) (models.Event, error)
-	Delete(id int) error
+	GetAll(interval string, username string, timezone time.Location) ([]models.Event, error)
+	Get(id int, username string) (models.Event, error)
+	Create(event models.Event, username string) (models.Event, error)
+	Update(id int, newEvent models.Event, username string) (models.Event, error)
+	Patch(id int, version int, patch utils.PatchInterface, username string) (models.Event, error)
+	Delete(id int, version int, username string) error
+	GetTrash(username string, timezone time.Location) ([]models.Event, error)
+	Restore(id int, username string) (models.Event, error)
+	Purge(id int, username string) error
+	GetHistory(id int, username string) ([]models.HistoryEntry, error)
+	Revert(id int, toVersion int, version int, username string) (models.Event, error)
 }
 
 type EventController struct {
 	Events EventServiceInterface
 	Auth   AuthServiceInterface
+	// InSession binds Events to the sync session of a request, if set.
+	InSession func(session string) EventServiceInterface
+}
+
+// events returns the service to make the changes of r with, so the sync
+// session r comes from doesn't receive them back.
+func (c *EventController) events(r *http.Request) EventServiceInterface {
+	session := r.Header.Get(SyncSessionHeader)
+	if session == "" || c.InSession == nil {
+		return c.Events
+	}
+
+	return c.InSession(session)
 }
 
 func (c *EventController) GetAll(w http.ResponseWriter, r *http.Request) {
 	interval := r.FormValue("interval")
 	initHeaders(w)
 
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
 	loc, err := GetUserTimezone(r, c.Auth)
 	if err != nil {
-		respondWithError(w, err, http.StatusInternalServerError)
+		respondWithError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	events, _ := c.Events.GetAll(interval, *loc)
+	events, _ := c.Events.GetAll(interval, username, *loc)
 	respond(w, events, http.StatusOK)
 }
 
@@ -43,13 +70,25 @@ func (c *EventController) Get(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		respondWithError(w, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
 		return
 	}
 
-	event, err := c.Events.Get(id)
+	username, err := GetUsername(r, c.Auth)
 	if err != nil {
-		respondWithError(w, err, http.StatusNotFound)
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	event, err := c.Events.Get(id, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	setETag(w, event.Version)
+	if notModified(r, event.Version) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
@@ -62,11 +101,23 @@ func (c *EventController) Create(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&event)
 	if err != nil {
-		respondWithError(w, errs.NewFailedRequestParsingError(), http.StatusBadRequest)
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
 		return
 	}
 
-	event, _ = c.Events.Create(event)
+	event, err = c.events(r).Create(event, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	setETag(w, event.Version)
 	respond(w, event, http.StatusCreated)
 }
 
@@ -75,40 +126,243 @@ func (c *EventController) Update(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		respondWithError(w, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusPreconditionFailed))
 		return
 	}
 
 	var event models.Event
 	err = json.NewDecoder(r.Body).Decode(&event)
 	if err != nil {
-		respondWithError(w, errs.NewFailedRequestParsingError(), http.StatusBadRequest)
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
 		return
 	}
 
-	updatedEvent, err := c.Events.Update(id, event)
+	event.Version = version
+	updatedEvent, err := c.events(r).Update(id, event, username)
 	if err != nil {
-		respondWithError(w, err, http.StatusUnprocessableEntity)
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
 		return
 	}
 
+	setETag(w, updatedEvent.Version)
 	respond(w, updatedEvent, http.StatusOK)
 }
 
+func (c *EventController) Patch(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusPreconditionFailed))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusBadRequest))
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	patchedEvent, err := c.events(r).Patch(id, version, patch, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	setETag(w, patchedEvent.Version)
+	respond(w, patchedEvent, http.StatusOK)
+}
+
 func (c *EventController) Delete(w http.ResponseWriter, r *http.Request) {
 	initHeaders(w)
 
 	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		respondWithError(w, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
 		return
 	}
 
-	err = c.Events.Delete(id)
+	version, err := versionFromIfMatch(r)
 	if err != nil {
-		respondWithError(w, err, http.StatusUnprocessableEntity)
+		respondWithError(w, r, err, statusFromError(err, http.StatusPreconditionFailed))
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = c.events(r).Delete(id, version, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+func (c *EventController) GetTrash(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	loc, err := GetUserTimezone(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	events, err := c.Events.GetTrash(username, *loc)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	respond(w, events, http.StatusOK)
+}
+
+func (c *EventController) Restore(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	event, err := c.events(r).Restore(id, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	setETag(w, event.Version)
+	respond(w, event, http.StatusOK)
+}
+
+func (c *EventController) Purge(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = c.Events.Purge(id, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *EventController) GetHistory(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	history, err := c.Events.GetHistory(id, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	respond(w, history, http.StatusOK)
+}
+
+func (c *EventController) Revert(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	toVersion, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusPreconditionFailed))
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	event, err := c.events(r).Revert(id, toVersion, version, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	setETag(w, event.Version)
+	respond(w, event, http.StatusOK)
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gorilla/mux"
+)
+
+type fakeEvents struct {
+	EventServiceInterface
+	event models.Event
+}
+
+func (f *fakeEvents) Get(id int, username string) (models.Event, error) {
+	if id != f.event.ID {
+		return models.Event{}, &errs.EventNotFoundError{}
+	}
+
+	return f.event, nil
+}
+
+func (f *fakeEvents) Update(id int, event models.Event, username string) (models.Event, error) {
+	if id != f.event.ID {
+		return models.Event{}, &errs.EventNotFoundError{}
+	}
+	if event.Version != 0 && event.Version != f.event.Version {
+		return models.Event{}, errs.NewVersionMismatchError()
+	}
+
+	event.ID = id
+	event.Version = f.event.Version + 1
+	f.event = event
+
+	return event, nil
+}
+
+func newEventRequest(method string, body string, header string, value string) *http.Request {
+	r := httptest.NewRequest(method, "/api/v1/events/1", strings.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	if header != "" {
+		r.Header.Set(header, value)
+	}
+
+	return WithClaims(r, jwt.MapClaims{"Username": "alice"})
+}
+
+func TestEventControllerETags(t *testing.T) {
+	c := &EventController{Events: &fakeEvents{event: models.Event{ID: 1, Title: "Standup", Version: 3}}}
+
+	t.Run("emits the version as an ETag", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c.Get(w, newEventRequest(http.MethodGet, "", "", ""))
+
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+			t.Errorf("expected 200 with ETag \"3\", got %d with %q", w.Code, w.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("If-None-Match", func(t *testing.T) {
+		cases := []struct {
+			header string
+			status int
+		}{
+			{`"3"`, http.StatusNotModified},
+			{`W/"3"`, http.StatusNotModified},
+			{`"1", W/"3"`, http.StatusNotModified},
+			{`*`, http.StatusNotModified},
+			{`"2"`, http.StatusOK},
+			{`"1", W/"2"`, http.StatusOK},
+		}
+
+		for _, tc := range cases {
+			w := httptest.NewRecorder()
+			c.Get(w, newEventRequest(http.MethodGet, "", "If-None-Match", tc.header))
+
+			if w.Code != tc.status {
+				t.Errorf("If-None-Match %s: expected %d, got %d", tc.header, tc.status, w.Code)
+			}
+			if w.Header().Get("ETag") != `"3"` {
+				t.Errorf("If-None-Match %s: expected ETag \"3\", got %q", tc.header, w.Header().Get("ETag"))
+			}
+			if tc.status == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("If-None-Match %s: expected an empty body, got %q", tc.header, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("If-Match", func(t *testing.T) {
+		cases := []struct {
+			header string
+			value  string
+			status int
+		}{
+			{"", "", http.StatusPreconditionRequired},
+			{"If-Match", `"2"`, http.StatusPreconditionFailed},
+			{"If-Match", "not-a-tag", http.StatusPreconditionFailed},
+			{"If-Match", `"3"`, http.StatusOK},
+			{"If-Match", "*", http.StatusOK},
+		}
+
+		for _, tc := range cases {
+			w := httptest.NewRecorder()
+			c.Update(w, newEventRequest(http.MethodPut, `{"title":"Retro"}`, tc.header, tc.value))
+
+			if w.Code != tc.status {
+				t.Errorf("If-Match %q: expected %d, got %d", tc.value, tc.status, w.Code)
+			}
+		}
+
+		if c.Events.(*fakeEvents).event.Version != 5 {
+			t.Errorf("expected only the two matching updates to apply, got version %d", c.Events.(*fakeEvents).event.Version)
+		}
+	})
+
+	t.Run("returns the new ETag after an update", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c.Update(w, newEventRequest(http.MethodPut, `{"title":"Planning"}`, "If-Match", `"5"`))
+
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"6"` {
+			t.Errorf("expected 200 with ETag \"6\", got %d with %q", w.Code, w.Header().Get("ETag"))
+		}
+	})
+}
@@ -2,51 +2,145 @@ package controller
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
 	"workshop2/internal/app/errs"
 	"workshop2/internal/app/models"
+	"workshop2/internal/app/services"
+	"workshop2/internal/app/utils"
 
 	"github.com/gorilla/mux"
 )
 
 type NotificationServiceInterface interface {
-	GetAll(interval string, timezone time.Location) ([]models.Notification, error)
-	Create(notification models.Notification) (models.Notification, error)
-	Update(id int, notification models.Notification) (models.Notification, error)
+	GetAll(username string, interval string, state string, timezone time.Location) ([]models.Notification, error)
+	UnreadCount(username string) (int, error)
+	Get(id int, username string) (models.Notification, error)
+	Create(notification models.Notification, username string) (models.Notification, error)
+	Update(id int, notification models.Notification, username string) (models.Notification, error)
+	Patch(id int, version int, patch utils.PatchInterface, username string) (models.Notification, error)
+	GetHistory(id int, username string) ([]models.HistoryEntry, error)
+	Delete(id int, version int, username string) error
+	DeleteMany(ids []int, username string) (int, error)
+	MarkRead(id int, version int, username string) (models.Notification, error)
+	MarkUnread(id int, version int, username string) (models.Notification, error)
+	MarkAllRead(username string) (int, error)
+	Dismiss(id int, version int, username string) (models.Notification, error)
+	Snooze(id int, until time.Time, version int, username string) (models.Notification, error)
+}
+
+type NotificationStreamInterface interface {
+	Subscribe(username string, lastEventID string) (*services.StreamSubscriber, []services.StreamEvent)
+	Unsubscribe(subscriber *services.StreamSubscriber)
+	Heartbeat() time.Duration
 }
 
 type NotificationController struct {
 	Notifications NotificationServiceInterface
+	Streamer      NotificationStreamInterface
 	Auth          AuthServiceInterface
 }
 
 func (c *NotificationController) GetAll(w http.ResponseWriter, r *http.Request) {
 	interval := r.FormValue("interval")
+	state := r.FormValue("state")
 	initHeaders(w)
 
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
 	loc, err := GetUserTimezone(r, c.Auth)
 	if err != nil {
-		respondWithError(w, err, http.StatusInternalServerError)
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	notifications, err := c.Notifications.GetAll(username, interval, state, *loc)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	notifications, _ := c.Notifications.GetAll(interval, *loc)
 	respond(w, notifications, http.StatusOK)
 }
 
+func (c *NotificationController) UnreadCount(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	count, err := c.Notifications.UnreadCount(username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	respond(w, map[string]int{"count": count}, http.StatusOK)
+}
+
+func (c *NotificationController) Get(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	notification, err := c.Notifications.Get(id, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	setETag(w, notification.Version)
+	if notModified(r, notification.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	respond(w, notification, http.StatusOK)
+}
+
 func (c *NotificationController) Create(w http.ResponseWriter, r *http.Request) {
 	initHeaders(w)
 	var notification models.Notification
 
 	err := json.NewDecoder(r.Body).Decode(&notification)
 	if err != nil {
-		respondWithError(w, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
 		return
 	}
 
-	notification, _ = c.Notifications.Create(notification)
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	notification, err = c.Notifications.Create(notification, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	setETag(w, notification.Version)
 	respond(w, notification, http.StatusOK)
 }
 
@@ -55,22 +149,322 @@ func (c *NotificationController) Update(w http.ResponseWriter, r *http.Request)
 
 	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		respondWithError(w, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusPreconditionFailed))
 		return
 	}
 
 	var notification models.Notification
 	err = json.NewDecoder(r.Body).Decode(&notification)
 	if err != nil {
-		respondWithError(w, errs.NewFailedRequestParsingError(), http.StatusBadRequest)
+		respondWithParsingError(w, r, err)
 		return
 	}
 
-	updatedEvent, err := c.Notifications.Update(id, notification)
+	username, err := GetUsername(r, c.Auth)
 	if err != nil {
-		respondWithError(w, err, http.StatusUnprocessableEntity)
+		respondWithError(w, r, err, http.StatusUnauthorized)
 		return
 	}
 
+	notification.Version = version
+	updatedEvent, err := c.Notifications.Update(id, notification, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	setETag(w, updatedEvent.Version)
 	respond(w, updatedEvent, http.StatusOK)
 }
+
+func (c *NotificationController) Patch(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusPreconditionFailed))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusBadRequest))
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	patchedNotification, err := c.Notifications.Patch(id, version, patch, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	setETag(w, patchedNotification.Version)
+	respond(w, patchedNotification, http.StatusOK)
+}
+
+func (c *NotificationController) GetHistory(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	history, err := c.Notifications.GetHistory(id, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	respond(w, history, http.StatusOK)
+}
+
+func (c *NotificationController) Delete(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusPreconditionFailed))
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = c.Notifications.Delete(id, version, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *NotificationController) DeleteMany(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+	var request struct {
+		IDs []int `json:"ids"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || len(request.IDs) == 0 {
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	deleted, err := c.Notifications.DeleteMany(request.IDs, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	respond(w, map[string]int{"deleted": deleted}, http.StatusOK)
+}
+
+func (c *NotificationController) MarkRead(w http.ResponseWriter, r *http.Request) {
+	c.changeState(w, r, c.Notifications.MarkRead)
+}
+
+func (c *NotificationController) MarkUnread(w http.ResponseWriter, r *http.Request) {
+	c.changeState(w, r, c.Notifications.MarkUnread)
+}
+
+func (c *NotificationController) Dismiss(w http.ResponseWriter, r *http.Request) {
+	c.changeState(w, r, c.Notifications.Dismiss)
+}
+
+func (c *NotificationController) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	marked, err := c.Notifications.MarkAllRead(username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	respond(w, map[string]int{"marked": marked}, http.StatusOK)
+}
+
+func (c *NotificationController) Snooze(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Until    *time.Time `json:"until"`
+		Duration string     `json:"duration"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		initHeaders(w)
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	var until time.Time
+	switch {
+	case request.Until != nil:
+		until = *request.Until
+	case request.Duration != "":
+		duration, err := time.ParseDuration(request.Duration)
+		if err != nil || duration <= 0 {
+			initHeaders(w)
+			respondWithError(w, r, errs.NewBadSnoozeError(), http.StatusUnprocessableEntity)
+			return
+		}
+		until = time.Now().Add(duration)
+	default:
+		initHeaders(w)
+		respondWithError(w, r, errs.NewBadSnoozeError(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	c.changeState(w, r, func(id int, version int, username string) (models.Notification, error) {
+		return c.Notifications.Snooze(id, until, version, username)
+	})
+}
+
+func (c *NotificationController) changeState(w http.ResponseWriter, r *http.Request, change func(id int, version int, username string) (models.Notification, error)) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusPreconditionFailed))
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	notification, err := change(id, version, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	setETag(w, notification.Version)
+	respond(w, notification, http.StatusOK)
+}
+
+func (c *NotificationController) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		initHeaders(w)
+		respondWithError(w, r, errs.NewStreamingUnsupportedError(), http.StatusInternalServerError)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		initHeaders(w)
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	subscriber, missed := c.Streamer.Subscribe(username, r.Header.Get("Last-Event-ID"))
+	defer c.Streamer.Unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+		return
+	}
+
+	for _, event := range missed {
+		if err = writeStreamEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(c.Streamer.Heartbeat())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-subscriber.Events:
+			if !ok {
+				return
+			}
+
+			if err = writeStreamEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err = fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, event services.StreamEvent) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.EventID(), event.Type, event.Data)
+	return err
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+	"workshop2/internal/app/services"
+
+	"github.com/gorilla/websocket"
+)
+
+// SyncSessionHeader carries the ID of the live sync session a request is
+// made from; the session is not sent the changes of that request.
+const SyncSessionHeader = "X-Sync-Session"
+
+const (
+	syncWriteTimeout = time.Second * 10
+	syncPongTimeout  = time.Second * 60
+	syncPingInterval = time.Second * 50
+)
+
+type EventSyncInterface interface {
+	Register(username string) (*services.SyncSession, error)
+	Unregister(session *services.SyncSession)
+}
+
+type SyncController struct {
+	Sync     EventSyncInterface
+	Auth     AuthServiceInterface
+	Upgrader websocket.Upgrader
+}
+
+type syncMessage struct {
+	Type    string                `json:"type"`
+	Windows []services.TimeWindow `json:"windows"`
+}
+
+// syncHello is the first message of a session, telling the client what to
+// send in the SyncSessionHeader.
+type syncHello struct {
+	Type    string `json:"type"`
+	Session string `json:"session"`
+}
+
+func (c *SyncController) Connect(w http.ResponseWriter, r *http.Request) {
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		initHeaders(w)
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := c.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	session, err := c.Sync.Register(username)
+	if err != nil {
+		return
+	}
+	defer c.Sync.Unregister(session)
+
+	_ = conn.SetWriteDeadline(time.Now().Add(syncWriteTimeout))
+	if err = conn.WriteJSON(syncHello{Type: "session", Session: session.ID}); err != nil {
+		return
+	}
+
+	closed := make(chan struct{})
+	go c.read(conn, session, closed)
+
+	ping := time.NewTicker(syncPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case change, ok := <-session.Changes:
+			_ = conn.SetWriteDeadline(time.Now().Add(syncWriteTimeout))
+			if !ok {
+				message := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "client is too slow")
+				_ = conn.WriteMessage(websocket.CloseMessage, message)
+				return
+			}
+
+			if err = conn.WriteJSON(change); err != nil {
+				return
+			}
+		case <-ping.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(syncWriteTimeout))
+			if err = conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *SyncController) read(conn *websocket.Conn, session *services.SyncSession, closed chan struct{}) {
+	defer close(closed)
+
+	_ = conn.SetReadDeadline(time.Now().Add(syncPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(syncPongTimeout))
+	})
+
+	for {
+		var message syncMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			return
+		}
+
+		switch message.Type {
+		case "subscribe":
+			session.SetWindows(message.Windows)
+		case "unsubscribe":
+			session.SetWindows(nil)
+		}
+	}
+}
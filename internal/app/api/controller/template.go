@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+
+	"github.com/gorilla/mux"
+)
+
+type TemplateServiceInterface interface {
+	GetAll(owner string) ([]models.NotificationTemplate, error)
+	Get(id int, owner string) (models.NotificationTemplate, error)
+	Create(template models.NotificationTemplate, owner string) (models.NotificationTemplate, error)
+	Update(id int, template models.NotificationTemplate, owner string) (models.NotificationTemplate, error)
+	Delete(id int, owner string) error
+	Preview(id int, request models.TemplateRenderRequest, username string) (models.Notification, error)
+	Render(id int, request models.TemplateRenderRequest, username string) (models.Notification, error)
+}
+
+type TemplateController struct {
+	Templates TemplateServiceInterface
+	Auth      AuthServiceInterface
+}
+
+func (c *TemplateController) GetAll(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	templates, err := c.Templates.GetAll(username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	respond(w, templates, http.StatusOK)
+}
+
+func (c *TemplateController) Get(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	template, err := c.Templates.Get(id, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	respond(w, template, http.StatusOK)
+}
+
+func (c *TemplateController) Create(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+	var template models.NotificationTemplate
+
+	err := json.NewDecoder(r.Body).Decode(&template)
+	if err != nil {
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	template, err = c.Templates.Create(template, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	respond(w, template, http.StatusCreated)
+}
+
+func (c *TemplateController) Update(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	var template models.NotificationTemplate
+	err = json.NewDecoder(r.Body).Decode(&template)
+	if err != nil {
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	template, err = c.Templates.Update(id, template, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	respond(w, template, http.StatusOK)
+}
+
+func (c *TemplateController) Delete(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = c.Templates.Delete(id, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *TemplateController) Preview(w http.ResponseWriter, r *http.Request) {
+	c.render(w, r, c.Templates.Preview, http.StatusOK)
+}
+
+func (c *TemplateController) Render(w http.ResponseWriter, r *http.Request) {
+	c.render(w, r, c.Templates.Render, http.StatusCreated)
+}
+
+func (c *TemplateController) render(w http.ResponseWriter, r *http.Request, render func(id int, request models.TemplateRenderRequest, username string) (models.Notification, error), status int) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	var request models.TemplateRenderRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	loc, err := GetUserTimezone(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	notification, err := render(id, request, username)
+	if err != nil {
+		respondWithError(w, r, err, statusFromError(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	respond(w, notification.ConvertInTimezone(*loc), status)
+}
@@ -3,18 +3,22 @@ package controller
 import (
 	"encoding/json"
 	"net/http"
-	"workshop2/internal/app/errs"
 	"workshop2/internal/app/models"
 )
 
 type UserServiceInterface interface {
 	Create(user models.User) (models.User, error)
 	UpdateTimezone(username string, timezone string) error
+	UpdateEmail(username string, email string) error
+	VerifyEmail(username string, token string) error
+	GetPreferences(username string) (models.NotificationPreferences, error)
+	UpdatePreferences(username string, preferences models.NotificationPreferences) (models.NotificationPreferences, error)
 }
 
 type UserController struct {
-	Users UserServiceInterface
-	Auth  AuthServiceInterface
+	Users  UserServiceInterface
+	Auth   AuthServiceInterface
+	Cookie TokenCookie
 }
 
 func (c *UserController) Create(w http.ResponseWriter, r *http.Request) {
@@ -23,13 +27,13 @@ func (c *UserController) Create(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
-		respondWithError(w, errs.NewFailedRequestParsingError(), http.StatusBadRequest)
+		respondWithParsingError(w, r, err)
 		return
 	}
 
 	user, err = c.Users.Create(user)
 	if err != nil {
-		respondWithError(w, err, http.StatusBadRequest)
+		respondWithError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
@@ -42,7 +46,7 @@ func (c *UserController) UpdateTimezone(w http.ResponseWriter, r *http.Request)
 
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
-		respondWithError(w, errs.NewFailedRequestParsingError(), http.StatusBadRequest)
+		respondWithParsingError(w, r, err)
 		return
 	}
 
@@ -60,16 +64,111 @@ func (c *UserController) UpdateTimezone(w http.ResponseWriter, r *http.Request)
 
 	err = c.Users.UpdateTimezone(username, user.Timezone)
 	if err != nil {
-		respondWithError(w, err, http.StatusInternalServerError)
+		respondWithError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
 	tokens, err := c.Auth.GenerateTokens(username, user.Timezone)
 	if err != nil {
-		respondWithError(w, err, http.StatusInternalServerError)
+		respondWithError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	SetTokenCookie(w, tokens)
+	c.Cookie.Set(w, tokens)
 	w.WriteHeader(http.StatusOK)
 }
+
+func (c *UserController) UpdateEmail(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+	var user models.User
+
+	err := json.NewDecoder(r.Body).Decode(&user)
+	if err != nil {
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = c.Users.UpdateEmail(username, user.Email)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *UserController) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+	var request struct {
+		Token string `json:"token"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = c.Users.VerifyEmail(username, request.Token)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *UserController) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	preferences, err := c.Users.GetPreferences(username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	respond(w, preferences, http.StatusOK)
+}
+
+func (c *UserController) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+	var preferences models.NotificationPreferences
+
+	err := json.NewDecoder(r.Body).Decode(&preferences)
+	if err != nil {
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	preferences, err = c.Users.UpdatePreferences(username, preferences)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	respond(w, preferences, http.StatusOK)
+}
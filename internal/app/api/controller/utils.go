@@ -1,12 +1,17 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/golang-jwt/jwt"
-	"log"
+	"github.com/sirupsen/logrus"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 	"workshop2/internal/app/errs"
+	"workshop2/internal/app/i18n"
+	"workshop2/internal/app/logging"
 	"workshop2/internal/app/models"
 )
 
@@ -14,14 +19,20 @@ func initHeaders(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 }
 
-func SetTokenCookie(w http.ResponseWriter, tokens []models.Token) {
-	tlt := time.Hour * 6
+// TokenCookie carries the access token between requests. Secure keeps
+// browsers from sending it over plain HTTP.
+type TokenCookie struct {
+	Lifetime time.Duration
+	Secure   bool
+}
 
+func (c TokenCookie) Set(w http.ResponseWriter, tokens []models.Token) {
 	cookie := &http.Cookie{
 		Name:     "token",
 		Value:    tokens[0].Value,
 		HttpOnly: true,
-		Expires:  time.Now().Add(tlt),
+		Secure:   c.Secure,
+		Expires:  time.Now().Add(c.Lifetime),
 	}
 	http.SetCookie(w, cookie)
 }
@@ -36,7 +47,19 @@ func GetTokenCookie(r *http.Request) (string, error) {
 	return cookie.Value, nil
 }
 
+type claimsKey struct{}
+
+// WithClaims authenticates r without a token cookie, e.g. by a client
+// certificate.
+func WithClaims(r *http.Request, claims jwt.MapClaims) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims))
+}
+
 func GetClaimsFromToken(r *http.Request, auth AuthServiceInterface) (jwt.MapClaims, error) {
+	if claims, ok := r.Context().Value(claimsKey{}).(jwt.MapClaims); ok {
+		return claims, nil
+	}
+
 	tokenString, err := GetTokenCookie(r)
 	if err != nil {
 		return jwt.MapClaims{}, err
@@ -49,10 +72,24 @@ func GetClaimsFromToken(r *http.Request, auth AuthServiceInterface) (jwt.MapClai
 	return claims, nil
 }
 
+func GetUsername(r *http.Request, auth AuthServiceInterface) (string, error) {
+	claims, err := GetClaimsFromToken(r, auth)
+	if err != nil {
+		return "", errs.NewFailedAuthenticationError(err)
+	}
+
+	username, ok := claims["Username"].(string)
+	if !ok {
+		return "", errs.NewFailedAuthenticationError(errs.NewFailedTokenVerificationError())
+	}
+
+	return username, nil
+}
+
 func GetUserTimezone(r *http.Request, auth AuthServiceInterface) (*time.Location, error) {
 	claims, err := GetClaimsFromToken(r, auth)
 	if err != nil {
-		return &time.Location{}, errs.NewFailedAuthenticationError(err.Error())
+		return &time.Location{}, errs.NewFailedAuthenticationError(err)
 	}
 
 	timezone, ok := claims["Timezone"].(string)
@@ -71,14 +108,111 @@ func respond(w http.ResponseWriter, message interface{}, status int) {
 	w.WriteHeader(status)
 	err := json.NewEncoder(w).Encode(message)
 	if err != nil {
-		log.Fatal(err)
+		logrus.WithError(err).Warn("writing response")
 	}
 }
 
-func respondWithError(w http.ResponseWriter, err error, status int) {
+func respondWithError(w http.ResponseWriter, r *http.Request, err error, status int) {
+	entry := logging.FromRequest(r).WithError(err).WithField("status", status)
+	if status >= http.StatusInternalServerError {
+		entry.Error("request failed")
+	} else {
+		entry.Debug("request rejected")
+	}
+
+	trans := i18n.FromRequest(r)
+	w.Header().Set("Content-Language", trans.Locale())
 	w.WriteHeader(status)
-	encodeErr := json.NewEncoder(w).Encode(err.Error())
+	encodeErr := json.NewEncoder(w).Encode(i18n.Error(trans, err))
 	if encodeErr != nil {
-		log.Fatal(encodeErr.Error())
+		logging.FromRequest(r).WithError(encodeErr).Warn("writing error response")
+	}
+}
+
+// maxBytesErrorText is what http.MaxBytesReader fails with once a body
+// of unknown length goes over the limit.
+const maxBytesErrorText = "http: request body too large"
+
+// respondWithParsingError answers a request whose body couldn't be read,
+// with 413 when it went over the size limit and 400 otherwise.
+func respondWithParsingError(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil && strings.Contains(err.Error(), maxBytesErrorText) {
+		respondWithError(w, r, errs.NewRequestTooLargeError(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	respondWithError(w, r, errs.NewFailedRequestParsingError(), http.StatusBadRequest)
+}
+
+func statusFromError(err error, fallback int) int {
+	switch err.(type) {
+	case *errs.EventNotFoundError, *errs.NotificationNotFoundError, *errs.HistoryEntryNotFoundError, *errs.TemplateNotFoundError, *errs.DeadLetterNotFoundError:
+		return http.StatusNotFound
+	case *errs.UnsupportedMediaTypeError:
+		return http.StatusUnsupportedMediaType
+	case *errs.VersionMismatchError:
+		return http.StatusPreconditionFailed
+	case *errs.PreconditionRequiredError:
+		return http.StatusPreconditionRequired
+	case *errs.BackupUnsupportedError:
+		return http.StatusNotImplemented
+	case *errs.SyncTokenExpiredError:
+		return http.StatusGone
+	}
+
+	return fallback
+}
+
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+func parseETag(tag string) (int, error) {
+	unquoted, err := strconv.Unquote(strings.TrimSpace(tag))
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(unquoted)
+}
+
+// versionFromIfMatch returns the version required by the If-Match header,
+// or zero for "*", which accepts any version. Changes without the header
+// are refused so that clients can't overwrite each other by accident.
+func versionFromIfMatch(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, errs.NewPreconditionRequiredError()
 	}
+	if header == "*" {
+		return 0, nil
+	}
+
+	version, err := parseETag(header)
+	if err != nil {
+		return 0, errs.NewVersionMismatchError()
+	}
+
+	return version, nil
+}
+
+func notModified(r *http.Request, version int) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+
+		v, err := parseETag(strings.TrimPrefix(tag, "W/"))
+		if err == nil && v == version {
+			return true
+		}
+	}
+
+	return false
 }
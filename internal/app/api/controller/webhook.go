@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+
+	"github.com/gorilla/mux"
+)
+
+type WebhookServiceInterface interface {
+	GetAll(owner string) ([]models.Webhook, error)
+	Get(id int, owner string) (models.Webhook, error)
+	Create(webhook models.Webhook, owner string) (models.Webhook, error)
+	Delete(id int, owner string) error
+	Enable(id int, owner string) (models.Webhook, error)
+	GetDeliveries(id int, owner string) ([]models.WebhookDelivery, error)
+}
+
+type WebhookController struct {
+	Webhooks WebhookServiceInterface
+	Auth     AuthServiceInterface
+}
+
+func (c *WebhookController) GetAll(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	webhooks, err := c.Webhooks.GetAll(username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	respond(w, webhooks, http.StatusOK)
+}
+
+func (c *WebhookController) Get(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	webhook, err := c.Webhooks.Get(id, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	respond(w, webhook, http.StatusOK)
+}
+
+func (c *WebhookController) Create(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+	var webhook models.Webhook
+
+	err := json.NewDecoder(r.Body).Decode(&webhook)
+	if err != nil {
+		respondWithParsingError(w, r, err)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	webhook, err = c.Webhooks.Create(webhook, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	respond(w, webhook, http.StatusCreated)
+}
+
+func (c *WebhookController) Delete(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	err = c.Webhooks.Delete(id, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *WebhookController) Enable(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	webhook, err := c.Webhooks.Enable(id, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	respond(w, webhook, http.StatusOK)
+}
+
+func (c *WebhookController) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	initHeaders(w)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, r, errs.NewIdNotNumericError(), http.StatusBadRequest)
+		return
+	}
+
+	username, err := GetUsername(r, c.Auth)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusUnauthorized)
+		return
+	}
+
+	deliveries, err := c.Webhooks.GetDeliveries(id, username)
+	if err != nil {
+		respondWithError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	respond(w, deliveries, http.StatusOK)
+}
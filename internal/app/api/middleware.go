@@ -1,23 +1,35 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"net"
 	"net/http"
+	"runtime/debug"
+	"time"
 	"workshop2/internal/app/api/controller"
 	"workshop2/internal/app/errs"
+	"workshop2/internal/app/i18n"
+	"workshop2/internal/app/logging"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
+// AuthenticationMiddleware accepts a token cookie or, for service
+// accounts, a verified client certificate mapped in clientUsers.
 type AuthenticationMiddleware struct {
-	auth controller.AuthServiceInterface
+	auth        controller.AuthServiceInterface
+	public      []string
+	clientUsers map[string]string
 }
 
 func (mw *AuthenticationMiddleware) Handle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		notAuth := []string{"/api/v1/sign-in", "/api/v1/sign-up"}
 		requestPath := r.URL.Path
 
-		for _, value := range notAuth {
+		for _, value := range mw.public {
 
 			if value == requestPath {
 				next.ServeHTTP(w, r)
@@ -25,28 +37,164 @@ func (mw *AuthenticationMiddleware) Handle(next http.Handler) http.Handler {
 			}
 		}
 
+		if username, ok := clientCertificateUser(r, mw.clientUsers); ok {
+			claims, err := mw.auth.UserClaims(username)
+			if err != nil {
+				respondUnauthorized(w, r)
+				return
+			}
+
+			setAccessUsername(r, username)
+			next.ServeHTTP(w, controller.WithClaims(r, claims))
+			return
+		}
+
 		token, err := controller.GetTokenCookie(r)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			encodeErr := json.NewEncoder(w).Encode(errs.NewMalformedTokenError().Error())
-			if encodeErr != nil {
-				log.Fatal(encodeErr.Error())
-			}
+			respondUnauthorized(w, r)
+			return
+		}
 
+		claims, err := mw.auth.ExtractClaims(token)
+		if err != nil {
+			respondUnauthorized(w, r)
 			return
 		}
 
-		err = mw.auth.VerifyToken(token)
+		if username, ok := claims["Username"].(string); ok {
+			setAccessUsername(r, username)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminMiddleware lets only the configured administrators through.
+type AdminMiddleware struct {
+	auth   controller.AuthServiceInterface
+	admins map[string]bool
+}
+
+func (mw *AdminMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, err := controller.GetUsername(r, mw.auth)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			encodeErr := json.NewEncoder(w).Encode(errs.NewMalformedTokenError().Error())
-			if encodeErr != nil {
-				log.Fatal(encodeErr.Error())
-			}
+			respondUnauthorized(w, r)
+			return
+		}
 
+		if !mw.admins[username] {
+			respondError(w, r, errs.NewForbiddenError(), http.StatusForbidden)
 			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RecoveryMiddleware turns a panicking handler into a 500 response
+// instead of a dropped connection. It has to run after LimitMiddleware:
+// handlers run in a goroutine of their own there, and only a recover in
+// that goroutine sees the stack of the handler that failed.
+type RecoveryMiddleware struct{}
+
+func (mw *RecoveryMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			logging.FromRequest(r).WithFields(logrus.Fields{
+				"panic": fmt.Sprint(p),
+				"stack": string(debug.Stack()),
+			}).Error("handler panicked")
+			respondError(w, r, errs.NewInternalError(), http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LimitMiddleware bounds the body size and handling time of requests.
+// Streams are exempt from the timeout and from the read and write
+// deadlines of their connection, and are cancelled once the server starts
+// shutting down instead, so that they do not hold up the drain.
+type LimitMiddleware struct {
+	maxBodyBytes int64
+	timeout      time.Duration
+	streams      map[string]bool
+	closing      <-chan struct{}
+}
+
+func (mw *LimitMiddleware) Handle(next http.Handler) http.Handler {
+	timeoutBody, _ := json.Marshal(errs.NewRequestTimeoutError().Error())
+	limited := http.TimeoutHandler(next, mw.timeout, string(timeoutBody))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > mw.maxBodyBytes {
+			respondError(w, r, errs.NewRequestTooLargeError(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, mw.maxBodyBytes)
+
+		if !mw.isStream(r) {
+			limited.ServeHTTP(w, r)
+			return
+		}
+
+		if conn, ok := r.Context().Value(connContextKey{}).(net.Conn); ok {
+			if err := conn.SetDeadline(time.Time{}); err != nil {
+				logging.FromRequest(r).WithError(err).Warn("clearing stream deadlines")
+			}
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-mw.closing:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type connContextKey struct{}
+
+// withConn keeps the connection of a request in its context, so that
+// LimitMiddleware can lift the deadlines for streams.
+func withConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, conn)
+}
+
+func (mw *LimitMiddleware) isStream(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+
+	template, err := route.GetPathTemplate()
+	return err == nil && mw.streams[template]
+}
+
+func respondUnauthorized(w http.ResponseWriter, r *http.Request) {
+	respondError(w, r, errs.NewMalformedTokenError(), http.StatusUnauthorized)
+}
+
+func respondError(w http.ResponseWriter, r *http.Request, err error, status int) {
+	trans := i18n.FromRequest(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", trans.Locale())
+	w.WriteHeader(status)
+	if encodeErr := json.NewEncoder(w).Encode(i18n.Error(trans, err)); encodeErr != nil {
+		logging.FromRequest(r).WithError(encodeErr).Warn("writing error response")
+	}
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"workshop2/internal/app/api/controller"
+	"workshop2/internal/app/config"
+
+	"github.com/gorilla/mux"
+)
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic("broken handler")
+}
+
+func newLimitedRouter(timeout time.Duration) http.Handler {
+	router := mux.NewRouter()
+	routeMiddleware := RouteMiddleware{}
+	recoveryMiddleware := RecoveryMiddleware{}
+	limitMiddleware := LimitMiddleware{
+		maxBodyBytes: 16,
+		timeout:      timeout,
+		streams:      map[string]bool{"/stream": true},
+		closing:      make(chan struct{}),
+	}
+	router.Use(routeMiddleware.Handle, limitMiddleware.Handle, recoveryMiddleware.Handle)
+
+	router.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(body)
+	})
+	router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(timeout * 3)
+		w.WriteHeader(http.StatusOK)
+	})
+	router.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(timeout * 3)
+		_, _ = w.Write([]byte("event"))
+	})
+	router.HandleFunc("/panic", panickingHandler)
+	router.HandleFunc("/sign-in", (&controller.AuthController{}).SignIn)
+
+	return router
+}
+
+func TestLimitMiddlewareBodySize(t *testing.T) {
+	router := newLimitedRouter(time.Second)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(strings.Repeat("a", 17))))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a declared length over the limit, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(strings.Repeat("a", 16))))
+	if w.Code != http.StatusOK || w.Body.Len() != 16 {
+		t.Errorf("expected a body at the limit to pass, got %d with %q", w.Code, w.Body.String())
+	}
+}
+
+func TestLimitMiddlewareChunkedBody(t *testing.T) {
+	router := newLimitedRouter(time.Second)
+
+	// Without a declared length the body is cut off while it is decoded.
+	body := `{"username":"` + strings.Repeat("a", 16) + `"}`
+	r := httptest.NewRequest(http.MethodPost, "/sign-in", ioutil.NopCloser(strings.NewReader(body)))
+	r.ContentLength = -1
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a chunked body over the limit, got %d: %s", w.Code, w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/sign-in", ioutil.NopCloser(strings.NewReader("{")))
+	r.ContentLength = -1
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed body within the limit, got %d", w.Code)
+	}
+}
+
+func TestLimitMiddlewareTimeout(t *testing.T) {
+	router := newLimitedRouter(time.Millisecond * 20)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "too long") {
+		t.Errorf("expected 503 with a timeout error, got %d with %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "event" {
+		t.Errorf("expected streams not to time out, got %d with %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	buf := captureLog(t)
+
+	w := httptest.NewRecorder()
+	newLimitedRouter(time.Second).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+
+	lines := logLines(t, buf)
+	if len(lines) != 1 || lines[0]["msg"] != "handler panicked" || lines[0]["panic"] != "broken handler" {
+		t.Fatalf("expected the panic to be logged, got %v", lines)
+	}
+	if stack, _ := lines[0]["stack"].(string); !strings.Contains(stack, "panickingHandler") {
+		t.Errorf("expected the stack of the failing handler, got %s", stack)
+	}
+}
+
+func TestServerDeadlinesSpareStreams(t *testing.T) {
+	api := &API{server: config.Server{
+		ReadHeaderTimeout: config.Duration(time.Second),
+		ReadTimeout:       config.Duration(time.Millisecond * 50),
+		WriteTimeout:      config.Duration(time.Millisecond * 50),
+		IdleTimeout:       config.Duration(time.Second),
+	}}
+	ts := httptest.NewUnstartedServer(nil)
+	server := api.newServer("", newLimitedRouter(time.Millisecond*100))
+	server.ErrorLog = nil
+	ts.Config = server
+	ts.Start()
+	defer ts.Close()
+
+	// The write deadline has passed by the time the slow handler answers.
+	if resp, err := http.Get(ts.URL + "/slow"); err == nil {
+		resp.Body.Close()
+		t.Errorf("expected the connection to be cut off, got %d", resp.StatusCode)
+	}
+
+	resp, err := http.Get(ts.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, err := ioutil.ReadAll(resp.Body); err != nil || string(body) != "event" {
+		t.Errorf("expected the stream to outlive the deadlines, got %q, %v", body, err)
+	}
+}
+
+func TestRunDrainsRequests(t *testing.T) {
+	captureLog(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	cfg := config.Default()
+	cfg.Server.Addr = addr
+	api, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer api.Close()
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- api.Run(ctx) }()
+
+	// Wait for the server to listen.
+	for i := 0; ; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// A request whose body is still being sent when the shutdown starts.
+	body, send := io.Pipe()
+	responded := make(chan error)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+addr+cfg.Server.Prefix+"/sign-in", body)
+		req.ContentLength = int64(len(`{"username":"alice"}`))
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		responded <- err
+	}()
+	if _, err = send.Write([]byte(`{"username":`)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 50)
+
+	stop()
+	select {
+	case err = <-done:
+		t.Fatalf("expected Run to wait for the request, returned %v", err)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	if _, err = send.Write([]byte(`"alice"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-responded; err != nil {
+		t.Errorf("expected the in-flight request to be answered, got %v", err)
+	}
+	if err = <-done; err != nil && err != http.ErrServerClosed {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+
+	if _, err = net.Dial("tcp", addr); err == nil {
+		t.Error("expected the server to stop listening")
+	}
+}
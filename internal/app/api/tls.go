@@ -0,0 +1,154 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"workshop2/internal/app/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTLSConfig(cfg config.TLS) (*tls.Config, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	certificates, err := newCertificateReloader(cfg.CertFile, cfg.KeyFile, time.Duration(cfg.ReloadInterval))
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certificates.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", cfg.ClientCAFile)
+		}
+
+		// Users still sign in with tokens; only service accounts
+		// present certificates.
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
+
+// certificateReloader serves a certificate pair from disk and picks up
+// replaced files without a restart. A pair that fails to load, e.g. while
+// only one of the files has been replaced, is retried and the previous
+// certificate stays in use meanwhile.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	checked     time.Time
+}
+
+func newCertificateReloader(certFile, keyFile string, interval time.Duration) (*certificateReloader, error) {
+	r := &certificateReloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) >= r.interval {
+		reloaded, err := r.reload()
+		if err != nil {
+			logrus.WithError(err).Warn("keeping the current TLS certificate")
+		} else if reloaded {
+			logrus.WithField("file", r.certFile).Info("reloaded TLS certificate")
+		}
+	}
+
+	return r.certificate, nil
+}
+
+// reload loads the pair if either file changed since the last load.
+func (r *certificateReloader) reload() (bool, error) {
+	r.checked = time.Now()
+
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	if certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		return false, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	r.certificate = &certificate
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+
+	return true, nil
+}
+
+// redirectToHTTPS sends plain HTTP requests to the same path on the TLS
+// listener.
+func redirectToHTTPS(tlsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		} else {
+			host = strings.Trim(host, "[]")
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
+
+// clientCertificateUser returns the user a verified client certificate
+// is mapped to.
+func clientCertificateUser(r *http.Request, users map[string]string) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	username, ok := users[r.TLS.VerifiedChains[0][0].Subject.CommonName]
+	return username, ok
+}
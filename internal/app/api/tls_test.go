@@ -0,0 +1,173 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCertificate(t *testing.T, dir string, commonName string, modTime time.Time) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{certFile, keyFile} {
+		if err = os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return certFile, keyFile
+}
+
+func servedCommonName(t *testing.T, r *certificateReloader) string {
+	t.Helper()
+
+	certificate, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return leaf.Subject.CommonName
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "workshop2-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Now().Add(-time.Minute)
+	certFile, keyFile := writeCertificate(t, dir, "first", start)
+
+	reloader, err := newCertificateReloader(certFile, keyFile, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("serves the loaded certificate", func(t *testing.T) {
+		if name := servedCommonName(t, reloader); name != "first" {
+			t.Errorf("expected first, got %s", name)
+		}
+	})
+
+	t.Run("picks up replaced files", func(t *testing.T) {
+		writeCertificate(t, dir, "second", start.Add(time.Second))
+
+		if name := servedCommonName(t, reloader); name != "second" {
+			t.Errorf("expected second, got %s", name)
+		}
+	})
+
+	t.Run("keeps the certificate when the new pair is broken", func(t *testing.T) {
+		if err := ioutil.WriteFile(keyFile, []byte("garbage"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if name := servedCommonName(t, reloader); name != "second" {
+			t.Errorf("expected second, got %s", name)
+		}
+	})
+
+	t.Run("fails to start without a valid pair", func(t *testing.T) {
+		if _, err := newCertificateReloader(certFile, keyFile, 0); err == nil {
+			t.Error("expected an error for a broken key")
+		}
+	})
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		addr     string
+		host     string
+		expected string
+	}{
+		{":443", "example.com:80", "https://example.com/api/v1/events?from=1"},
+		{":8443", "example.com", "https://example.com:8443/api/v1/events?from=1"},
+		{":8443", "[::1]:8080", "https://[::1]:8443/api/v1/events?from=1"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/events?from=1", nil)
+		r.Host = tt.host
+		w := httptest.NewRecorder()
+
+		redirectToHTTPS(tt.addr).ServeHTTP(w, r)
+
+		if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != tt.expected {
+			t.Errorf("expected redirect to %s, got %d %s", tt.expected, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestClientCertificateUser(t *testing.T) {
+	users := map[string]string{"billing": "billing-bot"}
+	chain := func(commonName string) *tls.ConnectionState {
+		leaf := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := clientCertificateUser(r, users); ok {
+		t.Error("expected no user without TLS")
+	}
+
+	r.TLS = chain("billing")
+	if username, ok := clientCertificateUser(r, users); !ok || username != "billing-bot" {
+		t.Errorf("expected billing-bot, got %q", username)
+	}
+
+	r.TLS = chain("unknown")
+	if _, ok := clientCertificateUser(r, users); ok {
+		t.Error("expected no user for an unmapped certificate")
+	}
+
+	r.TLS = &tls.ConnectionState{}
+	if _, ok := clientCertificateUser(r, users); ok {
+		t.Error("expected no user for an unverified connection")
+	}
+}
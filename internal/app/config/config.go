@@ -0,0 +1,294 @@
+// Package config assembles the server settings. Every setting has a
+// default which can be overridden, in increasing order of precedence, by a
+// YAML file, a WORKSHOP2_* environment variable and a command-line flag.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"workshop2/internal/app/logging"
+	"workshop2/internal/app/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "WORKSHOP2_"
+
+type Config struct {
+	Server   Server   `yaml:"server"`
+	TLS      TLS      `yaml:"tls"`
+	Auth     Auth     `yaml:"auth"`
+	Storage  Storage  `yaml:"storage"`
+	SMTP     SMTP     `yaml:"smtp"`
+	Webhooks Webhooks `yaml:"webhooks"`
+	Log      Log      `yaml:"log"`
+	Admins   []string `yaml:"admins"`
+}
+
+// Server.RequestTimeout, ReadTimeout and WriteTimeout bound ordinary
+// requests only; event and notification streams stay open until the
+// client leaves or the server shuts down. WriteTimeout must leave time to
+// send the response of a request that ran into RequestTimeout.
+type Server struct {
+	Addr              string   `yaml:"addr"`
+	Prefix            string   `yaml:"prefix"`
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout"`
+	ReadTimeout       Duration `yaml:"read_timeout"`
+	WriteTimeout      Duration `yaml:"write_timeout"`
+	RequestTimeout    Duration `yaml:"request_timeout"`
+	IdleTimeout       Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   Duration `yaml:"shutdown_timeout"`
+	MaxHeaderBytes    int      `yaml:"max_header_bytes"`
+	MaxBodyBytes      int      `yaml:"max_body_bytes"`
+}
+
+// TLS is enabled by a certificate and key, which are reloaded when the
+// files change. ClientUsers maps the common name of a client certificate
+// signed by ClientCAFile to the user it authenticates as.
+type TLS struct {
+	CertFile       string            `yaml:"cert_file"`
+	KeyFile        string            `yaml:"key_file"`
+	ReloadInterval Duration          `yaml:"reload_interval"`
+	ClientCAFile   string            `yaml:"client_ca_file"`
+	ClientUsers    map[string]string `yaml:"client_users"`
+	RedirectAddr   string            `yaml:"redirect_addr"`
+}
+
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+type Auth struct {
+	SigningKey           Secret   `yaml:"signing_key"`
+	TokenLifetime        Duration `yaml:"token_lifetime"`
+	RefreshTokenLifetime Duration `yaml:"refresh_token_lifetime"`
+	CookieLifetime       Duration `yaml:"cookie_lifetime"`
+}
+
+// Storage.Path is the bolt database file; when it is empty everything is
+// kept in memory. A TrashRetention of 0 keeps deleted events until they
+// are purged by hand.
+type Storage struct {
+	Path           string   `yaml:"path"`
+	CacheEntries   int      `yaml:"cache_entries"`
+	CacheTTL       Duration `yaml:"cache_ttl"`
+	TrashRetention Duration `yaml:"trash_retention"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password Secret `yaml:"password"`
+	From     string `yaml:"from"`
+	Security string `yaml:"security"`
+}
+
+// Webhooks may only call public HTTPS addresses unless these development
+// switches are turned on.
+type Webhooks struct {
+	AllowHTTP    bool `yaml:"allow_http"`
+	AllowPrivate bool `yaml:"allow_private"`
+}
+
+type Log struct {
+	Level string `yaml:"level"`
+}
+
+// DefaultSigningKey is only fit for development.
+const DefaultSigningKey = "keyyt"
+
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:              ":8002",
+			Prefix:            "/api/v1",
+			ReadHeaderTimeout: Duration(time.Second * 10),
+			ReadTimeout:       Duration(time.Second * 30),
+			WriteTimeout:      Duration(time.Second * 45),
+			RequestTimeout:    Duration(time.Second * 30),
+			IdleTimeout:       Duration(time.Minute * 2),
+			ShutdownTimeout:   Duration(time.Second * 15),
+			MaxHeaderBytes:    64 << 10,
+			MaxBodyBytes:      1 << 20,
+		},
+		TLS: TLS{
+			ReloadInterval: Duration(time.Second * 10),
+		},
+		Auth: Auth{
+			SigningKey:           DefaultSigningKey,
+			TokenLifetime:        Duration(time.Hour * 6),
+			RefreshTokenLifetime: Duration(time.Hour * 24 * 31),
+			CookieLifetime:       Duration(time.Hour * 6),
+		},
+		Storage: Storage{
+			CacheEntries:   1000,
+			CacheTTL:       Duration(time.Second * 30),
+			TrashRetention: Duration(time.Hour * 24 * 30),
+		},
+		SMTP: SMTP{
+			Host:     "localhost",
+			Port:     1025,
+			From:     "workshop2@localhost",
+			Security: models.SMTPSecurityNone,
+		},
+		Log: Log{
+			Level: "info",
+		},
+	}
+}
+
+// Options are the settings of a single run that are not part of the
+// configuration itself.
+type Options struct {
+	File        string
+	PrintConfig bool
+}
+
+// Load builds the configuration from the defaults, the file named by
+// -config or WORKSHOP2_CONFIG, the environment and args, and validates
+// the result.
+func Load(name string, args []string, lookupEnv func(string) (string, bool)) (Config, Options, error) {
+	var opts Options
+
+	// The first pass only finds the config file and rejects bad flags
+	// early; the values it parses are thrown away.
+	scratch := Default()
+	if err := newFlagSet(name, &scratch, &opts).Parse(args); err != nil {
+		return Config{}, opts, err
+	}
+	if opts.File == "" {
+		opts.File, _ = lookupEnv(envPrefix + "CONFIG")
+	}
+
+	cfg := Default()
+	if opts.File != "" {
+		if err := cfg.readFile(opts.File); err != nil {
+			return Config{}, opts, err
+		}
+	}
+
+	for _, o := range cfg.options() {
+		value, ok := lookupEnv(o.env())
+		if !ok {
+			continue
+		}
+		if err := o.value.Set(value); err != nil {
+			return Config{}, opts, fmt.Errorf("%s: %v", o.env(), err)
+		}
+	}
+
+	var parsed Options
+	if err := newFlagSet(name, &cfg, &parsed).Parse(args); err != nil {
+		return Config{}, opts, err
+	}
+
+	cfg.Admins = cleanList(cfg.Admins)
+
+	return cfg, opts, cfg.Validate()
+}
+
+func (c *Config) readFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err = decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	return nil
+}
+
+// Print writes the effective configuration as YAML with secrets redacted.
+func (c Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+type option struct {
+	key   string
+	flag  string
+	usage string
+	value flag.Value
+}
+
+func (o option) env() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(o.flag, "-", "_"))
+}
+
+func (c *Config) options() []option {
+	return []option{
+		{"server.addr", "addr", "listen address", (*stringValue)(&c.Server.Addr)},
+		{"server.prefix", "prefix", "path prefix of the API routes", (*stringValue)(&c.Server.Prefix)},
+		{"server.read_header_timeout", "read-header-timeout", "time allowed to read request headers", &c.Server.ReadHeaderTimeout},
+		{"server.read_timeout", "read-timeout", "time allowed to read a whole request", &c.Server.ReadTimeout},
+		{"server.write_timeout", "write-timeout", "time allowed to handle a request and write the response", &c.Server.WriteTimeout},
+		{"server.request_timeout", "request-timeout", "time allowed to handle a request", &c.Server.RequestTimeout},
+		{"server.idle_timeout", "idle-timeout", "how long idle keep-alive connections stay open", &c.Server.IdleTimeout},
+		{"server.shutdown_timeout", "shutdown-timeout", "how long to drain requests on shutdown", &c.Server.ShutdownTimeout},
+		{"server.max_header_bytes", "max-header-bytes", "maximum size of request headers", (*intValue)(&c.Server.MaxHeaderBytes)},
+		{"server.max_body_bytes", "max-body-bytes", "maximum size of request bodies", (*intValue)(&c.Server.MaxBodyBytes)},
+		{"tls.cert_file", "tls-cert", "TLS certificate file; enables HTTPS", (*stringValue)(&c.TLS.CertFile)},
+		{"tls.key_file", "tls-key", "TLS private key file", (*stringValue)(&c.TLS.KeyFile)},
+		{"tls.reload_interval", "tls-reload-interval", "how often to check the certificate files for changes", &c.TLS.ReloadInterval},
+		{"tls.client_ca_file", "tls-client-ca", "CA bundle that signs client certificates; enables mutual TLS", (*stringValue)(&c.TLS.ClientCAFile)},
+		{"tls.client_users", "tls-client-users", "comma-separated common-name=username pairs for client certificates", (*mapValue)(&c.TLS.ClientUsers)},
+		{"tls.redirect_addr", "tls-redirect-addr", "plain HTTP address that redirects to HTTPS", (*stringValue)(&c.TLS.RedirectAddr)},
+		{"auth.signing_key", "signing-key", "key the tokens are signed with", &c.Auth.SigningKey},
+		{"auth.token_lifetime", "token-lifetime", "lifetime of access tokens", &c.Auth.TokenLifetime},
+		{"auth.refresh_token_lifetime", "refresh-token-lifetime", "lifetime of refresh tokens", &c.Auth.RefreshTokenLifetime},
+		{"auth.cookie_lifetime", "cookie-lifetime", "lifetime of the token cookie", &c.Auth.CookieLifetime},
+		{"storage.path", "db", "bolt database file; empty keeps data in memory", (*stringValue)(&c.Storage.Path)},
+		{"storage.cache_entries", "cache-entries", "cached queries per repository; 0 disables the cache", (*intValue)(&c.Storage.CacheEntries)},
+		{"storage.cache_ttl", "cache-ttl", "lifetime of cached queries", &c.Storage.CacheTTL},
+		{"storage.trash_retention", "trash-retention", "how long deleted events stay in the trash", &c.Storage.TrashRetention},
+		{"smtp.host", "smtp-host", "SMTP server host", (*stringValue)(&c.SMTP.Host)},
+		{"smtp.port", "smtp-port", "SMTP server port", (*intValue)(&c.SMTP.Port)},
+		{"smtp.username", "smtp-username", "SMTP username", (*stringValue)(&c.SMTP.Username)},
+		{"smtp.password", "smtp-password", "SMTP password", &c.SMTP.Password},
+		{"smtp.from", "smtp-from", "sender address of emails", (*stringValue)(&c.SMTP.From)},
+		{"smtp.security", "smtp-security", "none, starttls or tls", (*stringValue)(&c.SMTP.Security)},
+		{"webhooks.allow_http", "webhook-allow-http", "let webhooks call plain HTTP addresses", (*boolValue)(&c.Webhooks.AllowHTTP)},
+		{"webhooks.allow_private", "webhook-allow-private", "let webhooks call loopback, link-local and private addresses", (*boolValue)(&c.Webhooks.AllowPrivate)},
+		{"log.level", "log-level", "one of " + strings.Join(logging.Levels, ", "), (*stringValue)(&c.Log.Level)},
+		{"admins", "admins", "comma-separated administrator usernames", (*listValue)(&c.Admins)},
+	}
+}
+
+func newFlagSet(name string, c *Config, opts *Options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&opts.File, "config", "", "YAML configuration file (env "+envPrefix+"CONFIG)")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration and exit")
+
+	for _, o := range c.options() {
+		fs.Var(o.value, o.flag, fmt.Sprintf("%s (env %s)", o.usage, o.env()))
+	}
+
+	return fs
+}
+
+func cleanList(values []string) []string {
+	cleaned := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			cleaned = append(cleaned, value)
+		}
+	}
+
+	return cleaned
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "workshop2-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.yaml")
+	if err = ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, opts, err := Load("test", nil, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Server.Addr != ":8002" || cfg.Server.Prefix != "/api/v1" {
+		t.Errorf("unexpected server settings %+v", cfg.Server)
+	}
+	if time.Duration(cfg.Auth.TokenLifetime) != time.Hour*6 {
+		t.Errorf("expected 6h token lifetime, got %s", cfg.Auth.TokenLifetime)
+	}
+	if opts.File != "" || opts.PrintConfig {
+		t.Errorf("unexpected options %+v", opts)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+server:
+  addr: ":9000"
+  prefix: /file
+auth:
+  token_lifetime: 1h
+smtp:
+  port: 2525
+`)
+
+	cfg, _, err := Load("test", []string{"-config", path, "-prefix", "/flag"}, env(map[string]string{
+		"WORKSHOP2_PREFIX":         "/env",
+		"WORKSHOP2_TOKEN_LIFETIME": "2h",
+		"WORKSHOP2_ADMINS":         " alice, ,bob ",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Server.Addr != ":9000" {
+		t.Errorf("expected the file to override the default addr, got %q", cfg.Server.Addr)
+	}
+	if time.Duration(cfg.Auth.TokenLifetime) != time.Hour*2 {
+		t.Errorf("expected the environment to override the file, got %s", cfg.Auth.TokenLifetime)
+	}
+	if cfg.Server.Prefix != "/flag" {
+		t.Errorf("expected the flag to override the environment, got %q", cfg.Server.Prefix)
+	}
+	if cfg.SMTP.Port != 2525 || cfg.SMTP.Host != "localhost" {
+		t.Errorf("expected the file to merge with the defaults, got %+v", cfg.SMTP)
+	}
+	if strings.Join(cfg.Admins, ",") != "alice,bob" {
+		t.Errorf("expected cleaned admins, got %q", cfg.Admins)
+	}
+}
+
+func TestLoadConfigFromEnvironment(t *testing.T) {
+	path := writeFile(t, "storage:\n  path: /tmp/workshop2.db\n")
+
+	cfg, opts, err := Load("test", nil, env(map[string]string{"WORKSHOP2_CONFIG": path}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.File != path || cfg.Storage.Path != "/tmp/workshop2.db" {
+		t.Errorf("expected the file named by WORKSHOP2_CONFIG to be read, got %+v", cfg.Storage)
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	path := writeFile(t, "server:\n  port: 9000\n")
+
+	_, _, err := Load("test", []string{"-config", path}, env(nil))
+	if err == nil || !strings.Contains(err.Error(), "port") {
+		t.Errorf("expected an unknown field error, got %v", err)
+	}
+}
+
+func TestLoadRejectsBadEnvironmentValue(t *testing.T) {
+	_, _, err := Load("test", nil, env(map[string]string{"WORKSHOP2_CACHE_TTL": "soon"}))
+	if err == nil || !strings.Contains(err.Error(), "WORKSHOP2_CACHE_TTL") {
+		t.Errorf("expected the variable to be named in the error, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	_, _, err := Load("test", []string{
+		"-addr", "8002",
+		"-prefix", "/api/",
+		"-write-timeout", "30s",
+		"-signing-key", "",
+		"-token-lifetime", "48h",
+		"-refresh-token-lifetime", "24h",
+		"-smtp-security", "ssl",
+		"-log-level", "verbose",
+	}, env(nil))
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	expected := []string{
+		"server.addr (-addr, WORKSHOP2_ADDR)",
+		"server.prefix",
+		"server.write_timeout",
+		"auth.signing_key",
+		"auth.refresh_token_lifetime",
+		"smtp.security",
+		"log.level",
+	}
+	if len(validationErr.Problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %q", len(expected), validationErr.Problems)
+	}
+	for i, problem := range validationErr.Problems {
+		if !strings.HasPrefix(problem, expected[i]) {
+			t.Errorf("expected problem %d to start with %q, got %q", i, expected[i], problem)
+		}
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg, opts, err := Load("test", []string{"-print-config", "-signing-key", "s3cret", "-smtp-password", "hunter2"}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opts.PrintConfig {
+		t.Error("expected print-config to be set")
+	}
+
+	var out bytes.Buffer
+	if err = cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(out.String(), "s3cret") || strings.Contains(out.String(), "hunter2") {
+		t.Errorf("secrets leaked:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "signing_key: '[redacted]'") || !strings.Contains(out.String(), "token_lifetime: 6h0m0s") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+	if string(cfg.Auth.SigningKey) != "s3cret" {
+		t.Errorf("expected the raw key to stay usable, got %q", string(cfg.Auth.SigningKey))
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	_, _, err := Load("test", []string{"-tls-cert", "cert.pem", "-tls-client-users", "billing=billing-bot"}, env(nil))
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if len(validationErr.Problems) != 2 ||
+		!strings.HasPrefix(validationErr.Problems[0], "tls.cert_file") ||
+		!strings.HasPrefix(validationErr.Problems[1], "tls.client_users") {
+		t.Errorf("unexpected problems %q", validationErr.Problems)
+	}
+
+	_, _, err = Load("test", []string{"-tls-redirect-addr", ":8080"}, env(nil))
+	if err == nil || !strings.Contains(err.Error(), "is required by the other tls settings") {
+		t.Errorf("expected TLS settings to require a certificate, got %v", err)
+	}
+
+	cfg, _, err := Load("test", []string{"-tls-cert", "cert.pem", "-tls-key", "key.pem", "-tls-client-ca", "ca.pem"}, env(map[string]string{
+		"WORKSHOP2_TLS_CLIENT_USERS": "billing = billing-bot, reports=reports-bot",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.TLS.ClientUsers) != 2 || cfg.TLS.ClientUsers["billing"] != "billing-bot" {
+		t.Errorf("unexpected client users %v", cfg.TLS.ClientUsers)
+	}
+
+	_, _, err = Load("test", []string{"-tls-client-users", "billing"}, env(nil))
+	if err == nil || !strings.Contains(err.Error(), "key=value") {
+		t.Errorf("expected a malformed pair error, got %v", err)
+	}
+}
+
+func TestLoadWebhookSwitches(t *testing.T) {
+	cfg, _, err := Load("test", nil, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Webhooks.AllowHTTP || cfg.Webhooks.AllowPrivate {
+		t.Errorf("expected webhooks to be restricted by default, got %+v", cfg.Webhooks)
+	}
+
+	cfg, _, err = Load("test", []string{"-webhook-allow-http"}, env(map[string]string{"WORKSHOP2_WEBHOOK_ALLOW_PRIVATE": "true"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Webhooks.AllowHTTP || !cfg.Webhooks.AllowPrivate {
+		t.Errorf("expected both switches to be on, got %+v", cfg.Webhooks)
+	}
+
+	_, _, err = Load("test", nil, env(map[string]string{"WORKSHOP2_WEBHOOK_ALLOW_HTTP": "sometimes"}))
+	if err == nil || !strings.Contains(err.Error(), "WORKSHOP2_WEBHOOK_ALLOW_HTTP") {
+		t.Errorf("expected the variable to be named in the error, got %v", err)
+	}
+}
+
+func TestValidateTrashRetention(t *testing.T) {
+	cfg, _, err := Load("test", []string{"-trash-retention", "0s"}, env(nil))
+	if err != nil {
+		t.Fatalf("expected 0 to keep deleted events, got %v", err)
+	}
+	if cfg.Storage.TrashRetention != 0 {
+		t.Errorf("expected no retention, got %s", cfg.Storage.TrashRetention)
+	}
+
+	_, _, err = Load("test", []string{"-trash-retention", "-1h"}, env(nil))
+	if err == nil || !strings.Contains(err.Error(), "storage.trash_retention") {
+		t.Errorf("expected a negative retention to be rejected, got %v", err)
+	}
+}
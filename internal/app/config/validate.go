@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"workshop2/internal/app/logging"
+	"workshop2/internal/app/models"
+)
+
+// ValidationError lists every invalid setting at once, each with the flag
+// and environment variable that set it.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+func (c Config) Validate() error {
+	v := validation{options: c.options()}
+
+	if _, port, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		v.fail("server.addr", "%q is not a host:port address", c.Server.Addr)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		v.fail("server.addr", "%q has an invalid port", c.Server.Addr)
+	}
+
+	if c.Server.Prefix != "" && (!strings.HasPrefix(c.Server.Prefix, "/") || strings.HasSuffix(c.Server.Prefix, "/")) {
+		v.fail("server.prefix", "%q must start with a slash and must not end with one", c.Server.Prefix)
+	}
+
+	v.positive("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	v.positive("server.read_timeout", c.Server.ReadTimeout)
+	v.positive("server.request_timeout", c.Server.RequestTimeout)
+	if c.Server.WriteTimeout <= c.Server.RequestTimeout {
+		v.fail("server.write_timeout", "%s must be longer than server.request_timeout", c.Server.WriteTimeout)
+	}
+	v.positive("server.idle_timeout", c.Server.IdleTimeout)
+	v.positive("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	if c.Server.MaxHeaderBytes < 1024 {
+		v.fail("server.max_header_bytes", "%d is less than 1024", c.Server.MaxHeaderBytes)
+	}
+	if c.Server.MaxBodyBytes < 1024 {
+		v.fail("server.max_body_bytes", "%d is less than 1024", c.Server.MaxBodyBytes)
+	}
+
+	if c.TLS.Enabled() {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			v.fail("tls.cert_file", "and tls.key_file must be set together")
+		}
+		v.positive("tls.reload_interval", c.TLS.ReloadInterval)
+		if len(c.TLS.ClientUsers) > 0 && c.TLS.ClientCAFile == "" {
+			v.fail("tls.client_users", "needs tls.client_ca_file to verify client certificates")
+		}
+		for name, username := range c.TLS.ClientUsers {
+			if name == "" || username == "" {
+				v.fail("tls.client_users", "%q=%q needs both a common name and a username", name, username)
+			}
+		}
+		if c.TLS.RedirectAddr != "" {
+			if _, _, err := net.SplitHostPort(c.TLS.RedirectAddr); err != nil {
+				v.fail("tls.redirect_addr", "%q is not a host:port address", c.TLS.RedirectAddr)
+			}
+		}
+	} else if c.TLS.ClientCAFile != "" || len(c.TLS.ClientUsers) > 0 || c.TLS.RedirectAddr != "" {
+		v.fail("tls.cert_file", "is required by the other tls settings")
+	}
+
+	if c.Auth.SigningKey == "" {
+		v.fail("auth.signing_key", "is required")
+	}
+
+	v.positive("auth.token_lifetime", c.Auth.TokenLifetime)
+	v.positive("auth.refresh_token_lifetime", c.Auth.RefreshTokenLifetime)
+	v.positive("auth.cookie_lifetime", c.Auth.CookieLifetime)
+	if c.Auth.RefreshTokenLifetime < c.Auth.TokenLifetime {
+		v.fail("auth.refresh_token_lifetime", "%s is shorter than auth.token_lifetime (%s)", c.Auth.RefreshTokenLifetime, c.Auth.TokenLifetime)
+	}
+
+	if c.Storage.CacheEntries < 0 {
+		v.fail("storage.cache_entries", "%d is negative; use 0 to disable the cache", c.Storage.CacheEntries)
+	}
+	v.positive("storage.cache_ttl", c.Storage.CacheTTL)
+	if c.Storage.TrashRetention < 0 {
+		v.fail("storage.trash_retention", "%s is negative; use 0 to keep deleted events", c.Storage.TrashRetention)
+	}
+
+	if c.SMTP.Host == "" {
+		v.fail("smtp.host", "is required")
+	}
+	if c.SMTP.Port < 1 || c.SMTP.Port > 65535 {
+		v.fail("smtp.port", "%d is not between 1 and 65535", c.SMTP.Port)
+	}
+	if c.SMTP.From == "" {
+		v.fail("smtp.from", "is required")
+	}
+	switch c.SMTP.Security {
+	case models.SMTPSecurityNone, models.SMTPSecurityStartTLS, models.SMTPSecurityTLS:
+	default:
+		v.fail("smtp.security", "%q is not one of none, starttls or tls", c.SMTP.Security)
+	}
+
+	if !contains(logging.Levels, c.Log.Level) {
+		v.fail("log.level", "%q is not one of %s", c.Log.Level, strings.Join(logging.Levels, ", "))
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+
+	return nil
+}
+
+type validation struct {
+	options  []option
+	problems []string
+}
+
+func (v *validation) fail(key string, format string, args ...interface{}) {
+	source := key
+	for _, o := range v.options {
+		if o.key == key {
+			source = fmt.Sprintf("%s (-%s, %s)", key, o.flag, o.env())
+		}
+	}
+
+	v.problems = append(v.problems, source+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validation) positive(key string, d Duration) {
+	if d <= 0 {
+		v.fail(key, "%s must be positive", d)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[redacted]"
+
+// Secret is a string that is never printed.
+type Secret string
+
+func (s *Secret) Set(value string) error {
+	*s = Secret(value)
+	return nil
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// Duration is written as "90s" or "6h" rather than in nanoseconds.
+type Duration time.Duration
+
+func (d *Duration) Set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+
+	return d.Set(value)
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+type stringValue string
+
+func (v *stringValue) Set(value string) error {
+	*v = stringValue(value)
+	return nil
+}
+
+func (v *stringValue) String() string {
+	if v == nil {
+		return ""
+	}
+
+	return string(*v)
+}
+
+type intValue int
+
+func (v *intValue) Set(value string) error {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+
+	*v = intValue(parsed)
+	return nil
+}
+
+func (v *intValue) String() string {
+	if v == nil {
+		return "0"
+	}
+
+	return strconv.Itoa(int(*v))
+}
+
+type boolValue bool
+
+func (v *boolValue) Set(value string) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+
+	*v = boolValue(parsed)
+	return nil
+}
+
+func (v *boolValue) String() string {
+	if v == nil {
+		return "false"
+	}
+
+	return strconv.FormatBool(bool(*v))
+}
+
+func (v *boolValue) IsBoolFlag() bool {
+	return true
+}
+
+type listValue []string
+
+func (v *listValue) Set(value string) error {
+	*v = cleanList(strings.Split(value, ","))
+	return nil
+}
+
+func (v *listValue) String() string {
+	if v == nil {
+		return ""
+	}
+
+	return strings.Join(*v, ",")
+}
+
+type mapValue map[string]string
+
+func (v *mapValue) Set(value string) error {
+	parsed := make(map[string]string)
+	for _, pair := range cleanList(strings.Split(value, ",")) {
+		i := strings.Index(pair, "=")
+		if i <= 0 {
+			return fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		parsed[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	}
+
+	*v = parsed
+	return nil
+}
+
+func (v *mapValue) String() string {
+	if v == nil {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(*v))
+	for key, value := range *v {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
@@ -1,39 +1,69 @@
 package errs
 
+import (
+	"workshop2/internal/app/i18n"
+
+	ut "github.com/go-playground/universal-translator"
+)
+
 type AuthValidationError struct {
-	Message string
+	Err error
 }
 
 func (e *AuthValidationError) Error() string {
-	return e.Message
+	return e.Err.Error()
 }
 
-func NewAuthValidationError(message string) error {
-	return &AuthValidationError{Message: message}
+func (e *AuthValidationError) Translate(trans ut.Translator) string {
+	return i18n.Error(trans, e.Err)
+}
+
+func (e *AuthValidationError) Unwrap() error {
+	return e.Err
+}
+
+func NewAuthValidationError(err error) error {
+	return &AuthValidationError{Err: err}
 }
 
 type FailedAuthenticationError struct {
-	Message string
+	Err error
 }
 
 func (e *FailedAuthenticationError) Error() string {
-	return e.Message
+	return e.Err.Error()
+}
+
+func (e *FailedAuthenticationError) Translate(trans ut.Translator) string {
+	return i18n.Error(trans, e.Err)
+}
+
+func (e *FailedAuthenticationError) Unwrap() error {
+	return e.Err
 }
 
-func NewFailedAuthenticationError(message string) error {
-	return &FailedAuthenticationError{Message: message}
+func NewFailedAuthenticationError(err error) error {
+	return &FailedAuthenticationError{Err: err}
 }
 
 type FailedSignUpError struct {
-	Message string
+	Err error
 }
 
 func (e *FailedSignUpError) Error() string {
-	return e.Message
+	return e.Err.Error()
+}
+
+func (e *FailedSignUpError) Translate(trans ut.Translator) string {
+	return i18n.Error(trans, e.Err)
+}
+
+func (e *FailedSignUpError) Unwrap() error {
+	return e.Err
 }
 
-func NewFailedSignUpError(message string) error {
-	return &FailedSignUpError{Message: message}
+func NewFailedSignUpError(err error) error {
+	return &FailedSignUpError{Err: err}
 }
 
 type FailedTokenVerificationError struct{}
@@ -42,6 +72,10 @@ func (e *FailedTokenVerificationError) Error() string {
 	return "Failed token verification."
 }
 
+func (e *FailedTokenVerificationError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "failed_token_verification", e.Error())
+}
+
 func NewFailedTokenVerificationError() error {
 	return &FailedTokenVerificationError{}
 }
@@ -52,6 +86,10 @@ func (e *MalformedAuthTokenError) Error() string {
 	return "Your token is malformed."
 }
 
+func (e *MalformedAuthTokenError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "malformed_token", e.Error())
+}
+
 func NewMalformedTokenError() error {
 	return &MalformedAuthTokenError{}
 }
@@ -1,23 +1,81 @@
 package errs
 
+import (
+	"workshop2/internal/app/i18n"
+
+	ut "github.com/go-playground/universal-translator"
+)
+
 type EventNotFoundError struct{}
 
 func (e *EventNotFoundError) Error() string {
 	return "Event with that ID does not exists in database."
 }
 
+func (e *EventNotFoundError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "event_not_found", e.Error())
+}
+
 type NotificationNotFoundError struct{}
 
 func (e *NotificationNotFoundError) Error() string {
 	return "Notification with that ID does not exists in database."
 }
 
+func (e *NotificationNotFoundError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "notification_not_found", e.Error())
+}
+
+type EventValidationError struct {
+	Err error
+}
+
+func (e *EventValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *EventValidationError) Translate(trans ut.Translator) string {
+	return i18n.Error(trans, e.Err)
+}
+
+func (e *EventValidationError) Unwrap() error {
+	return e.Err
+}
+
+func NewEventValidationError(err error) error {
+	return &EventValidationError{Err: err}
+}
+
+type NotificationValidationError struct {
+	Err error
+}
+
+func (e *NotificationValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NotificationValidationError) Translate(trans ut.Translator) string {
+	return i18n.Error(trans, e.Err)
+}
+
+func (e *NotificationValidationError) Unwrap() error {
+	return e.Err
+}
+
+func NewNotificationValidationError(err error) error {
+	return &NotificationValidationError{Err: err}
+}
+
 type IdNotNumericError struct{}
 
 func (e *IdNotNumericError) Error() string {
 	return "ID should be numeric."
 }
 
+func (e *IdNotNumericError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "id_not_numeric", e.Error())
+}
+
 func NewIdNotNumericError() error {
 	return &IdNotNumericError{}
 }
@@ -28,6 +86,10 @@ func (e *FailedRequestParsingError) Error() string {
 	return "Provided info is invalid."
 }
 
+func (e *FailedRequestParsingError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "failed_request_parsing", e.Error())
+}
+
 func NewFailedRequestParsingError() error {
 	return &FailedRequestParsingError{}
 }
@@ -38,6 +100,336 @@ func (e *BadTimezoneError) Error() string {
 	return "Provided timezone isn't correct. Please use the example: \"America/New_York\"."
 }
 
+func (e *BadTimezoneError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "bad_timezone", e.Error())
+}
+
 func NewBadTimezoneError() error {
 	return &BadTimezoneError{}
 }
+
+type InvalidPatchError struct {
+	Reason string
+}
+
+func (e *InvalidPatchError) Error() string {
+	return "Provided patch can't be applied: " + e.Reason
+}
+
+func (e *InvalidPatchError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "invalid_patch", e.Error(), e.Reason)
+}
+
+func NewInvalidPatchError(reason string) error {
+	return &InvalidPatchError{Reason: reason}
+}
+
+type UnsupportedMediaTypeError struct{}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return "Patch content type should be \"application/merge-patch+json\" or \"application/json-patch+json\"."
+}
+
+func (e *UnsupportedMediaTypeError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "unsupported_media_type", e.Error())
+}
+
+func NewUnsupportedMediaTypeError() error {
+	return &UnsupportedMediaTypeError{}
+}
+
+type VersionMismatchError struct{}
+
+func (e *VersionMismatchError) Error() string {
+	return "Resource was modified by someone else. Please reload it and try again."
+}
+
+func (e *VersionMismatchError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "version_mismatch", e.Error())
+}
+
+func NewVersionMismatchError() error {
+	return &VersionMismatchError{}
+}
+
+type PreconditionRequiredError struct{}
+
+func (e *PreconditionRequiredError) Error() string {
+	return "Send the ETag of the version you are changing in the If-Match header."
+}
+
+func (e *PreconditionRequiredError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "precondition_required", e.Error())
+}
+
+func NewPreconditionRequiredError() error {
+	return &PreconditionRequiredError{}
+}
+
+type HistoryEntryNotFoundError struct{}
+
+func (e *HistoryEntryNotFoundError) Error() string {
+	return "There is no such version in the history."
+}
+
+func (e *HistoryEntryNotFoundError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "history_entry_not_found", e.Error())
+}
+
+func NewHistoryEntryNotFoundError() error {
+	return &HistoryEntryNotFoundError{}
+}
+
+type WebhookNotFoundError struct{}
+
+func (e *WebhookNotFoundError) Error() string {
+	return "Webhook with that ID does not exists in database."
+}
+
+func (e *WebhookNotFoundError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "webhook_not_found", e.Error())
+}
+
+func NewWebhookNotFoundError() error {
+	return &WebhookNotFoundError{}
+}
+
+type WebhookValidationError struct {
+	Err error
+}
+
+func (e *WebhookValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *WebhookValidationError) Translate(trans ut.Translator) string {
+	return i18n.Error(trans, e.Err)
+}
+
+func (e *WebhookValidationError) Unwrap() error {
+	return e.Err
+}
+
+func NewWebhookValidationError(err error) error {
+	return &WebhookValidationError{Err: err}
+}
+
+type WebhookTargetError struct {
+	URL string
+}
+
+func (e *WebhookTargetError) Error() string {
+	return "Webhooks can only call public HTTPS addresses."
+}
+
+func (e *WebhookTargetError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "webhook_target_refused", e.Error())
+}
+
+func NewWebhookTargetError(url string) error {
+	return &WebhookTargetError{URL: url}
+}
+
+type StreamingUnsupportedError struct{}
+
+func (e *StreamingUnsupportedError) Error() string {
+	return "Streaming is not supported by the server."
+}
+
+func (e *StreamingUnsupportedError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "streaming_unsupported", e.Error())
+}
+
+func NewStreamingUnsupportedError() error {
+	return &StreamingUnsupportedError{}
+}
+
+type BadNotificationStateError struct{}
+
+func (e *BadNotificationStateError) Error() string {
+	return "Notification state should be one of: all, active, unread, read, dismissed, snoozed."
+}
+
+func (e *BadNotificationStateError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "bad_notification_state", e.Error())
+}
+
+func NewBadNotificationStateError() error {
+	return &BadNotificationStateError{}
+}
+
+type BadSnoozeError struct{}
+
+func (e *BadSnoozeError) Error() string {
+	return "Snooze requires a future \"until\" time or a positive \"duration\"."
+}
+
+func (e *BadSnoozeError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "bad_snooze", e.Error())
+}
+
+func NewBadSnoozeError() error {
+	return &BadSnoozeError{}
+}
+
+type TemplateNotFoundError struct{}
+
+func (e *TemplateNotFoundError) Error() string {
+	return "Template with that ID does not exists in database."
+}
+
+func (e *TemplateNotFoundError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "template_not_found", e.Error())
+}
+
+func NewTemplateNotFoundError() error {
+	return &TemplateNotFoundError{}
+}
+
+type TemplateValidationError struct {
+	Err error
+}
+
+func (e *TemplateValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TemplateValidationError) Translate(trans ut.Translator) string {
+	return i18n.Error(trans, e.Err)
+}
+
+func (e *TemplateValidationError) Unwrap() error {
+	return e.Err
+}
+
+func NewTemplateValidationError(err error) error {
+	return &TemplateValidationError{Err: err}
+}
+
+type BadTemplateError struct {
+	Reason string
+}
+
+func (e *BadTemplateError) Error() string {
+	return "Template can't be rendered: " + e.Reason
+}
+
+func (e *BadTemplateError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "bad_template", e.Error(), e.Reason)
+}
+
+func NewBadTemplateError(reason string) error {
+	return &BadTemplateError{Reason: reason}
+}
+
+type DeadLetterNotFoundError struct{}
+
+func (e *DeadLetterNotFoundError) Error() string {
+	return "Dead letter with that ID does not exists in database."
+}
+
+func (e *DeadLetterNotFoundError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "dead_letter_not_found", e.Error())
+}
+
+func NewDeadLetterNotFoundError() error {
+	return &DeadLetterNotFoundError{}
+}
+
+type ForbiddenError struct{}
+
+func (e *ForbiddenError) Error() string {
+	return "You are not allowed to perform this action."
+}
+
+func (e *ForbiddenError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "forbidden", e.Error())
+}
+
+func NewForbiddenError() error {
+	return &ForbiddenError{}
+}
+
+type BackupUnsupportedError struct{}
+
+func (e *BackupUnsupportedError) Error() string {
+	return "Backups are only available with a persistent storage backend."
+}
+
+func (e *BackupUnsupportedError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "backup_unsupported", e.Error())
+}
+
+func NewBackupUnsupportedError() error {
+	return &BackupUnsupportedError{}
+}
+
+type BadSyncTokenError struct{}
+
+func (e *BadSyncTokenError) Error() string {
+	return "Sync token is malformed."
+}
+
+func (e *BadSyncTokenError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "bad_sync_token", e.Error())
+}
+
+func NewBadSyncTokenError() error {
+	return &BadSyncTokenError{}
+}
+
+type SyncTokenExpiredError struct{}
+
+func (e *SyncTokenExpiredError) Error() string {
+	return "Sync token is too old. Sync again without a token."
+}
+
+func (e *SyncTokenExpiredError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "sync_token_expired", e.Error())
+}
+
+func NewSyncTokenExpiredError() error {
+	return &SyncTokenExpiredError{}
+}
+
+type RequestTooLargeError struct{}
+
+func (e *RequestTooLargeError) Error() string {
+	return "Request body is too large."
+}
+
+func (e *RequestTooLargeError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "request_too_large", e.Error())
+}
+
+func NewRequestTooLargeError() error {
+	return &RequestTooLargeError{}
+}
+
+type RequestTimeoutError struct{}
+
+func (e *RequestTimeoutError) Error() string {
+	return "Request took too long to process."
+}
+
+func (e *RequestTimeoutError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "request_timeout", e.Error())
+}
+
+func NewRequestTimeoutError() error {
+	return &RequestTimeoutError{}
+}
+
+type InternalError struct{}
+
+func (e *InternalError) Error() string {
+	return "Something went wrong on our side."
+}
+
+func (e *InternalError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "internal_error", e.Error())
+}
+
+func NewInternalError() error {
+	return &InternalError{}
+}
@@ -1,11 +1,21 @@
 package errs
 
+import (
+	"workshop2/internal/app/i18n"
+
+	ut "github.com/go-playground/universal-translator"
+)
+
 type UserNotFoundError struct{}
 
 func (e *UserNotFoundError) Error() string {
 	return "User with that username does not exists in database."
 }
 
+func (e *UserNotFoundError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "user_not_found", e.Error())
+}
+
 func NewUserNotFoundError() error {
 	return &UserNotFoundError{}
 }
@@ -16,20 +26,32 @@ func (e *UserAlreadyExistsError) Error() string {
 	return "User with that username already exists in database."
 }
 
+func (e *UserAlreadyExistsError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "user_already_exists", e.Error())
+}
+
 func NewUserAlreadyExistsError() error {
 	return &UserAlreadyExistsError{}
 }
 
 type UserValidationError struct {
-	Message string
+	Err error
 }
 
 func (e *UserValidationError) Error() string {
-	return e.Message
+	return e.Err.Error()
 }
 
-func NewUserValidationError(message string) error {
-	return &UserValidationError{Message: message}
+func (e *UserValidationError) Translate(trans ut.Translator) string {
+	return i18n.Error(trans, e.Err)
+}
+
+func (e *UserValidationError) Unwrap() error {
+	return e.Err
+}
+
+func NewUserValidationError(err error) error {
+	return &UserValidationError{Err: err}
 }
 
 type BadUsernameLengthError struct{}
@@ -38,6 +60,38 @@ func (e *BadUsernameLengthError) Error() string {
 	return "Username shoul be between 3 - 40 characters."
 }
 
+func (e *BadUsernameLengthError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "bad_username_length", e.Error())
+}
+
 func NewBadUsernameLengthError() error {
 	return &BadUsernameLengthError{}
 }
+
+type BadVerificationTokenError struct{}
+
+func (e *BadVerificationTokenError) Error() string {
+	return "Provided verification token is invalid."
+}
+
+func (e *BadVerificationTokenError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "bad_verification_token", e.Error())
+}
+
+func NewBadVerificationTokenError() error {
+	return &BadVerificationTokenError{}
+}
+
+type BadQuietHoursError struct{}
+
+func (e *BadQuietHoursError) Error() string {
+	return "Quiet hours should be given as \"HH:MM\" times."
+}
+
+func (e *BadQuietHoursError) Translate(trans ut.Translator) string {
+	return i18n.T(trans, "bad_quiet_hours", e.Error())
+}
+
+func NewBadQuietHoursError() error {
+	return &BadQuietHoursError{}
+}
@@ -0,0 +1,101 @@
+package i18n
+
+var catalog = map[string]map[string]string{
+	"en": {
+		"validation.required":    "{0} is a required field",
+		"validation.max":         "{0} must be maximum of {1} in length",
+		"validation.min":         "{0} must be minimum of {1} in length",
+		"validation.alphanum":    "{0} must containt only alphanumeric characters",
+		"validation.containsany": "{0} must containt at least one of {1} characters",
+		"validation.url":         "{0} must be a valid URL",
+		"validation.email":       "{0} must be a valid email address",
+		"validation.oneof":       "{0} must be one of [{1}]",
+		"validation.default":     "something wrong on {0}; {1}",
+	},
+	"uk": {
+		"event_not_found":           "Подію з таким ID не знайдено в базі даних.",
+		"notification_not_found":    "Сповіщення з таким ID не знайдено в базі даних.",
+		"id_not_numeric":            "ID має бути числом.",
+		"failed_request_parsing":    "Надані дані некоректні.",
+		"bad_timezone":              "Вказаний часовий пояс некоректний. Використовуйте формат: \"Europe/Kyiv\".",
+		"user_not_found":            "Користувача з таким іменем не знайдено в базі даних.",
+		"user_already_exists":       "Користувач з таким іменем уже існує в базі даних.",
+		"bad_username_length":       "Ім'я користувача має містити від 3 до 40 символів.",
+		"failed_token_verification": "Не вдалося перевірити токен.",
+		"malformed_token":           "Ваш токен пошкоджений.",
+		"invalid_patch":             "Неможливо застосувати патч: {0}",
+		"unsupported_media_type":    "Тип вмісту патча має бути \"application/merge-patch+json\" або \"application/json-patch+json\".",
+		"version_mismatch":          "Ресурс було змінено кимось іншим. Оновіть його та спробуйте ще раз.",
+		"precondition_required":     "Передайте ETag версії, яку ви змінюєте, у заголовку If-Match.",
+		"history_entry_not_found":   "Такої версії немає в історії.",
+		"webhook_not_found":         "Вебхук з таким ID не знайдено в базі даних.",
+		"webhook_target_refused":    "Вебхуки можуть звертатися лише до публічних HTTPS-адрес.",
+		"streaming_unsupported":     "Сервер не підтримує потокову передачу.",
+		"bad_verification_token":    "Наданий код підтвердження недійсний.",
+		"bad_notification_state":    "Стан сповіщення має бути одним із: all, active, unread, read, dismissed, snoozed.",
+		"bad_snooze":                "Для відкладення потрібен майбутній час \"until\" або додатна тривалість \"duration\".",
+		"bad_quiet_hours":           "Тихі години мають бути вказані у форматі \"ГГ:ХХ\".",
+		"template_not_found":        "Шаблон з таким ID не знайдено в базі даних.",
+		"bad_template":              "Неможливо обробити шаблон: {0}",
+		"dead_letter_not_found":     "Недоставленого повідомлення з таким ID не знайдено в базі даних.",
+		"forbidden":                 "Вам не дозволено виконувати цю дію.",
+		"backup_unsupported":        "Резервні копії доступні лише з постійним сховищем даних.",
+		"bad_sync_token":            "Токен синхронізації має неправильний формат.",
+		"sync_token_expired":        "Токен синхронізації застарів. Синхронізуйтеся знову без токена.",
+		"request_too_large":         "Тіло запиту завелике.",
+		"request_timeout":           "Обробка запиту тривала надто довго.",
+		"internal_error":            "Щось пішло не так на нашому боці.",
+		"validation.required":       "{0} є обов'язковим полем",
+		"validation.max":            "{0} має містити не більше {1} символів",
+		"validation.min":            "{0} має містити щонайменше {1} символів",
+		"validation.alphanum":       "{0} має містити лише літери та цифри",
+		"validation.containsany":    "{0} має містити хоча б один із символів {1}",
+		"validation.url":            "{0} має бути коректною URL-адресою",
+		"validation.email":          "{0} має бути коректною адресою електронної пошти",
+		"validation.oneof":          "{0} має бути одним із [{1}]",
+		"validation.default":        "помилка в полі {0}; {1}",
+	},
+	"de": {
+		"event_not_found":           "Es gibt kein Ereignis mit dieser ID in der Datenbank.",
+		"notification_not_found":    "Es gibt keine Benachrichtigung mit dieser ID in der Datenbank.",
+		"id_not_numeric":            "Die ID muss numerisch sein.",
+		"failed_request_parsing":    "Die übermittelten Daten sind ungültig.",
+		"bad_timezone":              "Die angegebene Zeitzone ist ungültig. Bitte verwenden Sie das Format: \"Europe/Berlin\".",
+		"user_not_found":            "Es gibt keinen Benutzer mit diesem Namen in der Datenbank.",
+		"user_already_exists":       "Ein Benutzer mit diesem Namen existiert bereits in der Datenbank.",
+		"bad_username_length":       "Der Benutzername muss zwischen 3 und 40 Zeichen lang sein.",
+		"failed_token_verification": "Die Token-Überprüfung ist fehlgeschlagen.",
+		"malformed_token":           "Ihr Token ist fehlerhaft.",
+		"invalid_patch":             "Der Patch kann nicht angewendet werden: {0}",
+		"unsupported_media_type":    "Der Inhaltstyp des Patches muss \"application/merge-patch+json\" oder \"application/json-patch+json\" sein.",
+		"version_mismatch":          "Die Ressource wurde von jemand anderem geändert. Bitte laden Sie sie neu und versuchen Sie es erneut.",
+		"precondition_required":     "Senden Sie das ETag der Version, die Sie ändern, im If-Match-Header.",
+		"history_entry_not_found":   "Diese Version ist in der Historie nicht vorhanden.",
+		"webhook_not_found":         "Es gibt keinen Webhook mit dieser ID in der Datenbank.",
+		"webhook_target_refused":    "Webhooks können nur öffentliche HTTPS-Adressen aufrufen.",
+		"streaming_unsupported":     "Streaming wird vom Server nicht unterstützt.",
+		"bad_verification_token":    "Der angegebene Bestätigungscode ist ungültig.",
+		"bad_notification_state":    "Der Benachrichtigungsstatus muss einer der folgenden sein: all, active, unread, read, dismissed, snoozed.",
+		"bad_snooze":                "Zum Zurückstellen wird eine zukünftige Zeit \"until\" oder eine positive Dauer \"duration\" benötigt.",
+		"bad_quiet_hours":           "Ruhezeiten müssen im Format \"HH:MM\" angegeben werden.",
+		"template_not_found":        "Es gibt keine Vorlage mit dieser ID in der Datenbank.",
+		"bad_template":              "Die Vorlage kann nicht verarbeitet werden: {0}",
+		"dead_letter_not_found":     "Es gibt keine unzustellbare Nachricht mit dieser ID in der Datenbank.",
+		"forbidden":                 "Sie dürfen diese Aktion nicht ausführen.",
+		"backup_unsupported":        "Sicherungen sind nur mit einem persistenten Speicher verfügbar.",
+		"bad_sync_token":            "Das Sync-Token ist fehlerhaft.",
+		"sync_token_expired":        "Das Sync-Token ist zu alt. Synchronisieren Sie erneut ohne Token.",
+		"request_too_large":         "Der Anfragetext ist zu groß.",
+		"request_timeout":           "Die Bearbeitung der Anfrage hat zu lange gedauert.",
+		"internal_error":            "Bei uns ist etwas schiefgelaufen.",
+		"validation.required":       "{0} ist ein Pflichtfeld",
+		"validation.max":            "{0} darf höchstens {1} Zeichen lang sein",
+		"validation.min":            "{0} muss mindestens {1} Zeichen lang sein",
+		"validation.alphanum":       "{0} darf nur alphanumerische Zeichen enthalten",
+		"validation.containsany":    "{0} muss mindestens eines der Zeichen {1} enthalten",
+		"validation.url":            "{0} muss eine gültige URL sein",
+		"validation.email":          "{0} muss eine gültige E-Mail-Adresse sein",
+		"validation.oneof":          "{0} muss einer der Werte [{1}] sein",
+		"validation.default":        "Fehler im Feld {0}; {1}",
+	},
+}
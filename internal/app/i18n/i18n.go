@@ -0,0 +1,128 @@
+package i18n
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/uk"
+	ut "github.com/go-playground/universal-translator"
+)
+
+// Translatable is implemented by errors that can render their message
+// in the language of the given translator.
+type Translatable interface {
+	Translate(trans ut.Translator) string
+}
+
+var universal = newUniversalTranslator()
+
+func newUniversalTranslator() *ut.UniversalTranslator {
+	fallback := en.New()
+	uni := ut.New(fallback, fallback, uk.New(), de.New())
+
+	for locale, messages := range catalog {
+		trans, _ := uni.GetTranslator(locale)
+		for key, text := range messages {
+			if err := trans.Add(key, text, false); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	return uni
+}
+
+func Default() ut.Translator {
+	return universal.GetFallback()
+}
+
+func Get(locale string) ut.Translator {
+	trans, _ := universal.FindTranslator(candidates(locale)...)
+	return trans
+}
+
+func FromRequest(r *http.Request) ut.Translator {
+	return FromAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+func FromAcceptLanguage(header string) ut.Translator {
+	trans, _ := universal.FindTranslator(parseAcceptLanguage(header)...)
+	return trans
+}
+
+func T(trans ut.Translator, key string, fallback string, params ...string) string {
+	message, err := trans.T(key, params...)
+	if err != nil {
+		return fallback
+	}
+
+	return message
+}
+
+func Error(trans ut.Translator, err error) string {
+	if t, ok := err.(Translatable); ok {
+		return t.Translate(trans)
+	}
+
+	return err.Error()
+}
+
+type weightedLanguage struct {
+	tag    string
+	weight float64
+}
+
+func parseAcceptLanguage(header string) []string {
+	var languages []weightedLanguage
+
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil {
+				weight = q
+			}
+		}
+
+		if weight > 0 {
+			languages = append(languages, weightedLanguage{tag: tag, weight: weight})
+		}
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].weight > languages[j].weight
+	})
+
+	var locales []string
+	for _, l := range languages {
+		locales = append(locales, candidates(l.tag)...)
+	}
+
+	return locales
+}
+
+func candidates(tag string) []string {
+	locale := strings.ReplaceAll(tag, "-", "_")
+	base := strings.SplitN(locale, "_", 2)[0]
+
+	if base == locale {
+		return []string{locale}
+	}
+
+	return []string{locale, base}
+}
@@ -0,0 +1,45 @@
+package i18n
+
+import (
+	"testing"
+)
+
+func TestFromAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		locale string
+	}{
+		{"", "en"},
+		{"uk", "uk"},
+		{"uk-UA,uk;q=0.9,en;q=0.8", "uk"},
+		{"fr-FR,de;q=0.7,en;q=0.5", "de"},
+		{"en;q=0.3,de;q=0.9", "de"},
+		{"de;q=0,uk;q=0.1", "uk"},
+		{"fr", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			locale := FromAcceptLanguage(tt.header).Locale()
+			if locale != tt.locale {
+				t.Errorf("expected %s, got %s", tt.locale, locale)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	t.Run("translates known key", func(t *testing.T) {
+		message := T(Get("uk"), "validation.required", "fallback", "username")
+		if message != "username є обов'язковим полем" {
+			t.Errorf("unexpected message %q", message)
+		}
+	})
+
+	t.Run("returns fallback for unknown key", func(t *testing.T) {
+		message := T(Get("de"), "unknown", "fallback")
+		if message != "fallback" {
+			t.Errorf("unexpected message %q", message)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+// Package logging writes JSON log lines and carries the request ID of a
+// request to everything that logs on its behalf.
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Levels are the accepted values of the log level setting.
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// Configure switches the logger to JSON lines on out at the given level.
+// Output of the standard library logger, used by net/http among others,
+// is routed through it as well.
+func Configure(out io.Writer, level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	logrus.SetOutput(out)
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+	logrus.SetLevel(parsed)
+
+	log.SetFlags(0)
+	log.SetOutput(logrus.StandardLogger().WriterLevel(logrus.InfoLevel))
+
+	return nil
+}
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromRequest returns a logger tagged with the request ID of r.
+func FromRequest(r *http.Request) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	if id := RequestID(r.Context()); id != "" {
+		entry = entry.WithField("request_id", id)
+	}
+
+	return entry
+}
@@ -0,0 +1,11 @@
+package models
+
+import (
+	"time"
+)
+
+type Backup struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
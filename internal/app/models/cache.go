@@ -0,0 +1,12 @@
+package models
+
+type CacheStats struct {
+	Name          string  `json:"name"`
+	Entries       int     `json:"entries"`
+	MaxEntries    int     `json:"max_entries"`
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	Evictions     uint64  `json:"evictions"`
+	Invalidations uint64  `json:"invalidations"`
+	HitRatio      float64 `json:"hit_ratio"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+type Change struct {
+	Type      string      `json:"type"`
+	Actor     string      `json:"actor"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+	// Session is the live sync session the change was made from, if any.
+	Session string `json:"-"`
+}
+
+const ChangeEventCreated = "event.created"
+const ChangeEventUpdated = "event.updated"
+const ChangeEventDeleted = "event.deleted"
+const ChangeEventRestored = "event.restored"
+const ChangeNotificationCreated = "notification.created"
+const ChangeNotificationUpdated = "notification.updated"
+const ChangeNotificationDeleted = "notification.deleted"
+const ChangeNotificationDue = "notification.due"
+const ChangeNotificationDigest = "notification.digest"
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+type DeliveryAttempt struct {
+	ID              int       `json:"id"`
+	Key             string    `json:"key"`
+	Channel         string    `json:"channel"`
+	Recipient       string    `json:"recipient"`
+	NotificationIDs []int     `json:"notification_ids"`
+	Attempt         int       `json:"attempt"`
+	Success         bool      `json:"success"`
+	Skipped         bool      `json:"skipped,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// DeadLetter keeps a delivery that ran out of attempts so it can be
+// inspected and re-driven later.
+type DeadLetter struct {
+	ID            int            `json:"id"`
+	Key           string         `json:"key"`
+	Channel       string         `json:"channel"`
+	Recipient     string         `json:"recipient"`
+	Notifications []Notification `json:"notifications"`
+	Digest        bool           `json:"digest"`
+	Created       bool           `json:"created"`
+	Attempts      int            `json:"attempts"`
+	LastError     string         `json:"last_error"`
+	FailedAt      time.Time      `json:"failed_at"`
+}
@@ -4,15 +4,46 @@ import (
 	"time"
 )
 
+// Event belongs to the user who created it; only the owner can see,
+// change, delete or restore it.
 type Event struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	TimeUTC     time.Time `json:"time_utc"`
-	Time        time.Time `json:"time"`
-	Description string    `json:"description"`
+	ID          int        `json:"id"`
+	Owner       string     `json:"owner"`
+	Title       string     `json:"title" validate:"required,max=255"`
+	TimeUTC     time.Time  `json:"time_utc"`
+	Time        time.Time  `json:"time" validate:"required"`
+	Description string     `json:"description" validate:"max=4096"`
+	Version     int        `json:"version"`
+	Reminders   []int      `json:"reminders,omitempty" validate:"max=10,dive,min=0,max=40320"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy   string     `json:"deleted_by,omitempty"`
+}
+
+func (e *Event) IsTrashed() bool {
+	return e.DeletedAt != nil
 }
 
 func (e *Event) ConvertInTimezone(loc time.Location) Event {
 	e.Time = e.TimeUTC.In(&loc)
 	return *e
 }
+
+// ReminderTime returns when a reminder set the given number of minutes
+// before the event fires.
+func (e *Event) ReminderTime(minutes int) time.Time {
+	return e.TimeUTC.Add(-time.Duration(minutes) * time.Minute)
+}
+
+// Clone returns a copy that shares no slices or pointers with e.
+func (e Event) Clone() Event {
+	if e.Reminders != nil {
+		e.Reminders = append([]int(nil), e.Reminders...)
+	}
+
+	if e.DeletedAt != nil {
+		deletedAt := *e.DeletedAt
+		e.DeletedAt = &deletedAt
+	}
+
+	return e
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type HistoryEntry struct {
+	ID           int             `json:"id"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   int             `json:"resource_id"`
+	Version      int             `json:"version"`
+	Action       string          `json:"action"`
+	Actor        string          `json:"actor"`
+	Timestamp    time.Time       `json:"timestamp"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	Changes      []FieldChange   `json:"changes"`
+}
+
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+const HistoryResourceEvent = "event"
+const HistoryResourceNotification = "notification"
+
+const HistoryActionCreated = "created"
+const HistoryActionUpdated = "updated"
+const HistoryActionDeleted = "deleted"
+const HistoryActionRestored = "restored"
+const HistoryActionPurged = "purged"
+const HistoryActionReverted = "reverted"
+
+const HistoryActorSystem = "system"
@@ -4,15 +4,84 @@ import (
 	"time"
 )
 
+const (
+	NotificationStateAll       = "all"
+	NotificationStateActive    = "active"
+	NotificationStateUnread    = "unread"
+	NotificationStateRead      = "read"
+	NotificationStateDismissed = "dismissed"
+	NotificationStateSnoozed   = "snoozed"
+)
+
 type Notification struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	TimeUTC     time.Time `json:"time_utc"`
-	Time        time.Time `json:"time"`
-	Description string    `json:"description"`
+	ID              int        `json:"id"`
+	Title           string     `json:"title" validate:"required,max=255"`
+	TimeUTC         time.Time  `json:"time_utc"`
+	Time            time.Time  `json:"time" validate:"required"`
+	Description     string     `json:"description" validate:"max=4096"`
+	Version         int        `json:"version"`
+	Recipient       string     `json:"recipient"`
+	EventID         int        `json:"event_id,omitempty"`
+	ReminderMinutes int        `json:"reminder_minutes,omitempty"`
+	Read            bool       `json:"read"`
+	Dismissed       bool       `json:"dismissed"`
+	SnoozedUntil    *time.Time `json:"snoozed_until,omitempty"`
+}
+
+func (n *Notification) IsSnoozed(now time.Time) bool {
+	return n.SnoozedUntil != nil && n.SnoozedUntil.After(now)
+}
+
+// InState reports whether the notification matches one of the
+// NotificationState* filters at the given moment.
+func (n *Notification) InState(state string, now time.Time) bool {
+	switch state {
+	case NotificationStateAll:
+		return true
+	case NotificationStateActive:
+		return !n.Dismissed && !n.IsSnoozed(now)
+	case NotificationStateUnread:
+		return !n.Dismissed && !n.IsSnoozed(now) && !n.Read
+	case NotificationStateRead:
+		return !n.Dismissed && !n.IsSnoozed(now) && n.Read
+	case NotificationStateDismissed:
+		return n.Dismissed
+	case NotificationStateSnoozed:
+		return !n.Dismissed && n.IsSnoozed(now)
+	}
+
+	return false
+}
+
+func (n *Notification) Type() string {
+	if n.EventID != 0 {
+		return NotificationTypeReminder
+	}
+
+	return NotificationTypeGeneral
+}
+
+func IsNotificationState(state string) bool {
+	switch state {
+	case NotificationStateAll, NotificationStateActive, NotificationStateUnread,
+		NotificationStateRead, NotificationStateDismissed, NotificationStateSnoozed:
+		return true
+	}
+
+	return false
 }
 
 func (n *Notification) ConvertInTimezone(loc time.Location) Notification {
 	n.Time = n.TimeUTC.In(&loc)
 	return *n
 }
+
+// Clone returns a copy that shares no pointers with n.
+func (n Notification) Clone() Notification {
+	if n.SnoozedUntil != nil {
+		snoozedUntil := *n.SnoozedUntil
+		n.SnoozedUntil = &snoozedUntil
+	}
+
+	return n
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	NotificationTypeGeneral  = "general"
+	NotificationTypeReminder = "reminder"
+)
+
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+type NotificationPreferences struct {
+	// Channels lists the enabled delivery channels per notification type;
+	// a type that isn't listed is delivered through every channel.
+	Channels      map[string][]string `json:"channels,omitempty" validate:"dive,keys,oneof=general reminder,endkeys,dive,oneof=email push"`
+	QuietHours    *QuietHours         `json:"quiet_hours,omitempty"`
+	Digest        string              `json:"digest,omitempty" validate:"omitempty,oneof=off daily weekly"`
+	DigestHour    int                 `json:"digest_hour" validate:"min=0,max=23"`
+	DigestWeekday time.Weekday        `json:"digest_weekday" validate:"min=0,max=6"`
+}
+
+// QuietHours is a daily do-not-disturb window given as "15:04" wall clock
+// times in the user's timezone. The window may span midnight.
+type QuietHours struct {
+	Start string `json:"start" validate:"required"`
+	End   string `json:"end" validate:"required"`
+}
+
+func (p *NotificationPreferences) ChannelEnabled(notificationType string, channel string) bool {
+	channels, ok := p.Channels[notificationType]
+	if !ok {
+		return true
+	}
+
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *NotificationPreferences) IsDigest() bool {
+	return p.Digest == DigestDaily || p.Digest == DigestWeekly
+}
+
+// LastDigest returns the latest scheduled digest moment not after t.
+func (p *NotificationPreferences) LastDigest(t time.Time) time.Time {
+	scheduled := time.Date(t.Year(), t.Month(), t.Day(), p.DigestHour, 0, 0, 0, t.Location())
+	if scheduled.After(t) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+
+	if p.Digest == DigestWeekly {
+		for scheduled.Weekday() != p.DigestWeekday {
+			scheduled = scheduled.AddDate(0, 0, -1)
+		}
+	}
+
+	return scheduled
+}
+
+func (q *QuietHours) Parse() (time.Time, time.Time, error) {
+	start, err := time.Parse("15:04", q.Start)
+	if err != nil {
+		return start, start, err
+	}
+
+	end, err := time.Parse("15:04", q.End)
+
+	return start, end, err
+}
+
+// Contains reports whether the wall clock time of t falls into the window.
+func (q *QuietHours) Contains(t time.Time) bool {
+	start, end, err := q.Parse()
+	if err != nil {
+		return false
+	}
+
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+	now := t.Hour()*60 + t.Minute()
+
+	if from <= to {
+		return from <= now && now < to
+	}
+
+	return now >= from || now < to
+}
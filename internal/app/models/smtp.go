@@ -0,0 +1,8 @@
+package models
+
+// How the connection to the SMTP server is secured.
+const (
+	SMTPSecurityNone     = "none"
+	SMTPSecurityStartTLS = "starttls"
+	SMTPSecurityTLS      = "tls"
+)
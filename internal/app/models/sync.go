@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// SyncChange is one entry of the change log offline clients sync from.
+// Deleted entries are tombstones and carry no data.
+type SyncChange struct {
+	Seq       int64       `json:"seq"`
+	Owner     string      `json:"-"`
+	Type      string      `json:"type"`
+	Resource  string      `json:"resource"`
+	ID        int         `json:"id"`
+	Deleted   bool        `json:"deleted"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+type SyncResponse struct {
+	Changes []SyncChange `json:"changes"`
+	Token   string       `json:"token"`
+	// More is set when the page is full; sync again with Token right away.
+	More bool `json:"more"`
+}
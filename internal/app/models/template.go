@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+type NotificationTemplate struct {
+	ID          int       `json:"id"`
+	Owner       string    `json:"owner"`
+	Name        string    `json:"name" validate:"required,max=100"`
+	Title       string    `json:"title" validate:"required,max=1024"`
+	Description string    `json:"description" validate:"max=8192"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type TemplateRenderRequest struct {
+	EventID int       `json:"event_id"`
+	Time    time.Time `json:"time" validate:"required"`
+}
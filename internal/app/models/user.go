@@ -1,7 +1,44 @@
 package models
 
+import (
+	"time"
+)
+
 type User struct {
 	Username string `json:"username" validate:"required,min=3,max=40,alphanum,nefield=Password"`
 	Password string `json:"password" validate:"required,min=8"`
 	Timezone string `json:"timezone" validate:"required"`
+
+	Email                  string `json:"email" validate:"omitempty,email,max=254"`
+	EmailVerified          bool   `json:"email_verified"`
+	EmailVerificationToken string `json:"-"`
+
+	Preferences NotificationPreferences `json:"preferences"`
+}
+
+func (u *User) Location() *time.Location {
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// Clone returns a copy that shares no maps or pointers with u.
+func (u User) Clone() User {
+	if u.Preferences.Channels != nil {
+		channels := make(map[string][]string, len(u.Preferences.Channels))
+		for k, v := range u.Preferences.Channels {
+			channels[k] = append([]string(nil), v...)
+		}
+		u.Preferences.Channels = channels
+	}
+
+	if u.Preferences.QuietHours != nil {
+		quietHours := *u.Preferences.QuietHours
+		u.Preferences.QuietHours = &quietHours
+	}
+
+	return u
 }
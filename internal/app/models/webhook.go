@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Webhook struct {
+	ID                  int       `json:"id"`
+	Owner               string    `json:"owner"`
+	URL                 string    `json:"url" validate:"required,url,max=2048"`
+	Secret              string    `json:"secret,omitempty" validate:"max=256"`
+	EventTypes          []string  `json:"event_types" validate:"required,min=1,dive,oneof=event.created event.updated event.deleted event.restored notification.created notification.updated notification.deleted notification.due"`
+	Active              bool      `json:"active"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+func (w *Webhook) IsSubscribed(eventType string) bool {
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type WebhookDelivery struct {
+	ID         int             `json:"id"`
+	WebhookID  int             `json:"webhook_id"`
+	DeliveryID string          `json:"delivery_id"`
+	EventType  string          `json:"event_type"`
+	Attempt    int             `json:"attempt"`
+	StatusCode int             `json:"status_code,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Success    bool            `json:"success"`
+	Timestamp  time.Time       `json:"timestamp"`
+	DurationMs int64           `json:"duration_ms"`
+	Payload    json.RawMessage `json:"payload"`
+}
@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	eventsBucket                   = []byte("events")
+	eventsByTimeBucket             = []byte("events_by_time")
+	eventsByOwnerBucket            = []byte("events_by_owner")
+	notificationsBucket            = []byte("notifications")
+	notificationsByTimeBucket      = []byte("notifications_by_time")
+	notificationsByRecipientBucket = []byte("notifications_by_recipient")
+	notificationsByEventBucket     = []byte("notifications_by_event")
+	usersBucket                    = []byte("users")
+	historyBucket                  = []byte("history")
+	historyByResourceBucket        = []byte("history_by_resource")
+	changesBucket                  = []byte("changes")
+	metaBucket                     = []byte("meta")
+)
+
+var (
+	changeLogEpochKey = []byte("changes_epoch")
+	dueMarkKey        = []byte("due_mark")
+)
+
+// BoltStore is a single-file embedded database shared by the bbolt
+// backed repositories.
+type BoltStore struct {
+	DB *bolt.DB
+}
+
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second * 5})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		buckets := [][]byte{
+			eventsBucket,
+			eventsByTimeBucket,
+			eventsByOwnerBucket,
+			notificationsBucket,
+			notificationsByTimeBucket,
+			notificationsByRecipientBucket,
+			notificationsByEventBucket,
+			usersBucket,
+			historyBucket,
+			historyByResourceBucket,
+			changesBucket,
+			metaBucket,
+		}
+
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		return indexEventOwners(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{DB: db}, nil
+}
+
+// Transaction runs fn in a single read-write transaction; the repositories
+// bound to it with InTx commit or roll back together.
+func (s *BoltStore) Transaction(fn func(tx *bolt.Tx) error) error {
+	return s.DB.Update(fn)
+}
+
+// view and update run fn in tx when a repository is bound to a
+// transaction and in a transaction of their own otherwise.
+func (s *BoltStore) view(tx *bolt.Tx, fn func(tx *bolt.Tx) error) error {
+	if tx != nil {
+		return fn(tx)
+	}
+
+	return s.DB.View(fn)
+}
+
+func (s *BoltStore) update(tx *bolt.Tx, fn func(tx *bolt.Tx) error) error {
+	if tx != nil {
+		return fn(tx)
+	}
+
+	return s.DB.Update(fn)
+}
+
+func (s *BoltStore) Close() error {
+	return s.DB.Close()
+}
+
+// Backup writes a consistent copy of the database to path while the
+// store stays available for reads and writes.
+func (s *BoltStore) Backup(path string) (int64, error) {
+	var size int64
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		size = tx.Size()
+		return tx.CopyFile(path, 0600)
+	})
+	if err != nil {
+		os.Remove(path)
+	}
+
+	return size, err
+}
+
+func itob(id int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+
+	return b
+}
+
+func btoi(b []byte) int {
+	return int(binary.BigEndian.Uint64(b))
+}
+
+// timeKey sorts by time first; flipping the sign bit keeps times before
+// 1970 in order.
+func timeKey(t time.Time, id int) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano())^(1<<63))
+	copy(key[8:], itob(id))
+
+	return key
+}
+
+func timePrefix(t time.Time) []byte {
+	return timeKey(t, 0)[:8]
+}
+
+func ownerKey(owner string, id int) []byte {
+	return append(append([]byte(owner), 0), itob(id)...)
+}
+
+func ownerPrefix(owner string) []byte {
+	return append([]byte(owner), 0)
+}
+
+func put(bucket *bolt.Bucket, id int, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put(itob(id), data)
+}
+
+// scanPrefix calls fn with the ID stored at the end of every index key
+// starting with prefix.
+func scanPrefix(bucket *bolt.Bucket, prefix []byte, fn func(id int) error) error {
+	c := bucket.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		if err := fn(btoi(k[len(k)-8:])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanTime calls fn for every ID in a time index with from <= t < to.
+func scanTime(bucket *bolt.Bucket, from time.Time, to time.Time, fn func(id int) error) error {
+	end := timePrefix(to)
+	c := bucket.Cursor()
+	for k, _ := c.Seek(timePrefix(from)); k != nil && bytes.Compare(k[:8], end) < 0; k, _ = c.Next() {
+		if err := fn(btoi(k[8:])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"encoding/json"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltChangeLogRepository keeps the change log and its sequence in the
+// database, so sync tokens stay valid across restarts. Only the newest
+// MaxEntries are kept; zero keeps everything.
+type BoltChangeLogRepository struct {
+	Store      *BoltStore
+	MaxEntries int
+}
+
+// boltChange is how a change is stored: the owner is kept, unlike in API
+// responses, and the data is decoded by resource type.
+type boltChange struct {
+	models.SyncChange
+	Owner string          `json:"owner"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+func encodeChange(change models.SyncChange) ([]byte, error) {
+	stored := boltChange{SyncChange: change, Owner: change.Owner}
+	if change.Data != nil {
+		data, err := json.Marshal(change.Data)
+		if err != nil {
+			return nil, err
+		}
+		stored.Data = data
+	}
+
+	return json.Marshal(stored)
+}
+
+func decodeChange(data []byte) (models.SyncChange, error) {
+	var stored boltChange
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return models.SyncChange{}, err
+	}
+
+	change := stored.SyncChange
+	change.Owner = stored.Owner
+	if len(stored.Data) == 0 {
+		return change, nil
+	}
+
+	switch change.Resource {
+	case models.HistoryResourceEvent:
+		var event models.Event
+		if err := json.Unmarshal(stored.Data, &event); err != nil {
+			return change, err
+		}
+		change.Data = event
+	case models.HistoryResourceNotification:
+		var notification models.Notification
+		if err := json.Unmarshal(stored.Data, &notification); err != nil {
+			return change, err
+		}
+		change.Data = notification
+	}
+
+	return change, nil
+}
+
+func (r *BoltChangeLogRepository) Append(change models.SyncChange) (models.SyncChange, error) {
+	err := r.Store.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(changesBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		change.Seq = int64(seq)
+		data, err := encodeChange(change)
+		if err != nil {
+			return err
+		}
+
+		if err = bucket.Put(itob(int(change.Seq)), data); err != nil {
+			return err
+		}
+
+		if r.MaxEntries <= 0 {
+			return nil
+		}
+
+		return deleteChangesWhile(bucket, func(seq int64, _ []byte) bool {
+			return seq <= change.Seq-int64(r.MaxEntries)
+		})
+	})
+
+	return change, err
+}
+
+// Since returns up to limit changes after seq that belong to owner or to
+// everyone, together with the sequence number of the newest change. It
+// fails when changes after seq have already been dropped.
+func (r *BoltChangeLogRepository) Since(seq int64, owner string, limit int) ([]models.SyncChange, int64, error) {
+	changes := make([]models.SyncChange, 0)
+	var last int64
+	err := r.Store.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(changesBucket)
+		last = int64(bucket.Sequence())
+
+		first := last + 1
+		if k, _ := bucket.Cursor().First(); k != nil {
+			first = int64(btoi(k))
+		}
+
+		if seq > last || seq < first-1 {
+			return errs.NewSyncTokenExpiredError()
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(itob(int(seq + 1))); k != nil; k, v = c.Next() {
+			if limit > 0 && len(changes) == limit {
+				break
+			}
+
+			change, err := decodeChange(v)
+			if err != nil {
+				return err
+			}
+
+			if change.Owner == "" || change.Owner == owner {
+				changes = append(changes, change)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, last, err
+	}
+
+	return changes, last, nil
+}
+
+func (r *BoltChangeLogRepository) Last() (int64, error) {
+	var last int64
+	err := r.Store.DB.View(func(tx *bolt.Tx) error {
+		last = int64(tx.Bucket(changesBucket).Sequence())
+		return nil
+	})
+
+	return last, err
+}
+
+// PruneBefore drops the changes older than t.
+func (r *BoltChangeLogRepository) PruneBefore(t time.Time) error {
+	return r.Store.DB.Update(func(tx *bolt.Tx) error {
+		return deleteChangesWhile(tx.Bucket(changesBucket), func(_ int64, v []byte) bool {
+			change, err := decodeChange(v)
+			return err == nil && change.Timestamp.Before(t)
+		})
+	})
+}
+
+// Epoch is chosen when the log is first used and stored with it.
+func (r *BoltChangeLogRepository) Epoch() (int64, error) {
+	var epoch int64
+	err := r.Store.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(metaBucket)
+		if data := bucket.Get(changeLogEpochKey); data != nil {
+			epoch = int64(btoi(data))
+			return nil
+		}
+
+		epoch = time.Now().UnixNano()
+		return bucket.Put(changeLogEpochKey, itob(int(epoch)))
+	})
+
+	return epoch, err
+}
+
+// deleteChangesWhile drops changes from the oldest on for as long as drop
+// reports true.
+func deleteChangesWhile(bucket *bolt.Bucket, drop func(seq int64, v []byte) bool) error {
+	var keys [][]byte
+	c := bucket.Cursor()
+	for k, v := c.First(); k != nil && drop(int64(btoi(k)), v); k, v = c.Next() {
+		keys = append(keys, k)
+	}
+
+	for _, k := range keys {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltDueMarkRepository keeps the due mark in the database, so due
+// notifications missed while the server was down are still published.
+type BoltDueMarkRepository struct {
+	Store *BoltStore
+}
+
+// Get returns the zero time until a mark is set.
+func (r *BoltDueMarkRepository) Get() (time.Time, error) {
+	var mark time.Time
+	err := r.Store.DB.View(func(tx *bolt.Tx) error {
+		if data := tx.Bucket(metaBucket).Get(dueMarkKey); data != nil {
+			mark = time.Unix(0, int64(btoi(data))).UTC()
+		}
+
+		return nil
+	})
+
+	return mark, err
+}
+
+func (r *BoltDueMarkRepository) Set(mark time.Time) error {
+	return r.Store.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(dueMarkKey, itob(int(mark.UnixNano())))
+	})
+}
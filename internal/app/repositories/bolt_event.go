@@ -0,0 +1,329 @@
+package repositories
+
+import (
+	"encoding/json"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+type BoltEventRepository struct {
+	Store *BoltStore
+	tx    *bolt.Tx
+}
+
+// InTx returns a copy of the repository that works inside tx.
+func (r *BoltEventRepository) InTx(tx *bolt.Tx) *BoltEventRepository {
+	return &BoltEventRepository{Store: r.Store, tx: tx}
+}
+
+func getEvent(tx *bolt.Tx, id int) (models.Event, error) {
+	var event models.Event
+	data := tx.Bucket(eventsBucket).Get(itob(id))
+	if data == nil {
+		return event, &errs.EventNotFoundError{}
+	}
+
+	return event, json.Unmarshal(data, &event)
+}
+
+func putEvent(tx *bolt.Tx, event models.Event, previous *models.Event) error {
+	index := tx.Bucket(eventsByTimeBucket)
+	if previous != nil && !previous.TimeUTC.Equal(event.TimeUTC) {
+		if err := index.Delete(timeKey(previous.TimeUTC, previous.ID)); err != nil {
+			return err
+		}
+	}
+
+	if err := index.Put(timeKey(event.TimeUTC, event.ID), nil); err != nil {
+		return err
+	}
+
+	owners := tx.Bucket(eventsByOwnerBucket)
+	if previous != nil && previous.Owner != event.Owner {
+		if err := owners.Delete(ownerKey(previous.Owner, previous.ID)); err != nil {
+			return err
+		}
+	}
+
+	if err := owners.Put(ownerKey(event.Owner, event.ID), nil); err != nil {
+		return err
+	}
+
+	return put(tx.Bucket(eventsBucket), event.ID, event)
+}
+
+func deleteEvent(tx *bolt.Tx, event models.Event) error {
+	if err := tx.Bucket(eventsByTimeBucket).Delete(timeKey(event.TimeUTC, event.ID)); err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(eventsByOwnerBucket).Delete(ownerKey(event.Owner, event.ID)); err != nil {
+		return err
+	}
+
+	return tx.Bucket(eventsBucket).Delete(itob(event.ID))
+}
+
+// indexEventOwners fills an empty owner index from the events, so that
+// databases written before the index existed can be queried by owner.
+func indexEventOwners(tx *bolt.Tx) error {
+	owners := tx.Bucket(eventsByOwnerBucket)
+	if k, _ := owners.Cursor().First(); k != nil {
+		return nil
+	}
+
+	return tx.Bucket(eventsBucket).ForEach(func(k, v []byte) error {
+		var e models.Event
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+
+		return owners.Put(ownerKey(e.Owner, e.ID), nil)
+	})
+}
+
+// owned returns the events of owner that pass keep, ordered by ID.
+func (r *BoltEventRepository) owned(owner string, keep func(e models.Event) bool) ([]models.Event, error) {
+	events := make([]models.Event, 0)
+	err := r.Store.view(r.tx, func(tx *bolt.Tx) error {
+		return scanPrefix(tx.Bucket(eventsByOwnerBucket), ownerPrefix(owner), func(id int) error {
+			e, err := getEvent(tx, id)
+			if err != nil {
+				return err
+			}
+
+			if keep(e) {
+				events = append(events, e)
+			}
+
+			return nil
+		})
+	})
+
+	return events, err
+}
+
+func (r *BoltEventRepository) all(keep func(e models.Event) bool) ([]models.Event, error) {
+	events := make([]models.Event, 0)
+	err := r.Store.view(r.tx, func(tx *bolt.Tx) error {
+		return tx.Bucket(eventsBucket).ForEach(func(k, v []byte) error {
+			var e models.Event
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+
+			if keep(e) {
+				events = append(events, e)
+			}
+
+			return nil
+		})
+	})
+
+	return events, err
+}
+
+func (r *BoltEventRepository) GetAll() ([]models.Event, error) {
+	return r.all(func(e models.Event) bool {
+		return !e.IsTrashed()
+	})
+}
+
+func (r *BoltEventRepository) GetBetween(from time.Time, to time.Time) ([]models.Event, error) {
+	events := make([]models.Event, 0)
+	err := r.Store.view(r.tx, func(tx *bolt.Tx) error {
+		return scanTime(tx.Bucket(eventsByTimeBucket), from, to, func(id int) error {
+			e, err := getEvent(tx, id)
+			if err != nil {
+				return err
+			}
+
+			if !e.IsTrashed() {
+				events = append(events, e)
+			}
+
+			return nil
+		})
+	})
+
+	return events, err
+}
+
+func (r *BoltEventRepository) GetByOwner(owner string) ([]models.Event, error) {
+	return r.owned(owner, func(e models.Event) bool {
+		return !e.IsTrashed()
+	})
+}
+
+func (r *BoltEventRepository) Get(id int) (models.Event, error) {
+	var event models.Event
+	err := r.Store.view(r.tx, func(tx *bolt.Tx) error {
+		var err error
+		event, err = getEvent(tx, id)
+		if err == nil && event.IsTrashed() {
+			return &errs.EventNotFoundError{}
+		}
+
+		return err
+	})
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	return event, nil
+}
+
+func (r *BoltEventRepository) Create(event models.Event) (models.Event, error) {
+	err := r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		id, err := tx.Bucket(eventsBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+
+		event.ID = int(id)
+		event.Version = 1
+		event.DeletedAt = nil
+		event.DeletedBy = ""
+
+		return putEvent(tx, event, nil)
+	})
+
+	return event, err
+}
+
+func (r *BoltEventRepository) Update(id int, newEvent models.Event) (models.Event, error) {
+	newEvent.ID = id
+	newEvent.DeletedAt = nil
+	newEvent.DeletedBy = ""
+
+	var current models.Event
+	err := r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		var err error
+		current, err = getEvent(tx, id)
+		if err != nil || current.IsTrashed() {
+			return &errs.EventNotFoundError{}
+		}
+
+		if newEvent.Version != 0 && newEvent.Version != current.Version {
+			return errs.NewVersionMismatchError()
+		}
+
+		newEvent.Owner = current.Owner
+		newEvent.Version = current.Version + 1
+
+		return putEvent(tx, newEvent, &current)
+	})
+	if _, ok := err.(*errs.VersionMismatchError); ok {
+		return current, err
+	}
+
+	return newEvent, err
+}
+
+func (r *BoltEventRepository) Trash(id int, version int, username string, deletedAt time.Time) (models.Event, error) {
+	var event models.Event
+	err := r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		var err error
+		event, err = getEvent(tx, id)
+		if err != nil || event.IsTrashed() {
+			return &errs.EventNotFoundError{}
+		}
+
+		if version != 0 && version != event.Version {
+			return errs.NewVersionMismatchError()
+		}
+
+		previous := event
+		event.Version++
+		event.DeletedAt = &deletedAt
+		event.DeletedBy = username
+
+		return putEvent(tx, event, &previous)
+	})
+	if err != nil {
+		if _, ok := err.(*errs.VersionMismatchError); ok {
+			return event, err
+		}
+
+		return models.Event{}, err
+	}
+
+	return event, nil
+}
+
+func (r *BoltEventRepository) GetTrash(username string) ([]models.Event, error) {
+	return r.owned(username, func(e models.Event) bool {
+		return e.IsTrashed()
+	})
+}
+
+func (r *BoltEventRepository) Restore(id int, username string) (models.Event, error) {
+	var event models.Event
+	err := r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		var err error
+		event, err = getEvent(tx, id)
+		if err != nil || !event.IsTrashed() || event.Owner != username {
+			return &errs.EventNotFoundError{}
+		}
+
+		previous := event
+		event.Version++
+		event.DeletedAt = nil
+		event.DeletedBy = ""
+
+		return putEvent(tx, event, &previous)
+	})
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	return event, nil
+}
+
+func (r *BoltEventRepository) Purge(id int, username string) error {
+	return r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		event, err := getEvent(tx, id)
+		if err != nil || !event.IsTrashed() || event.Owner != username {
+			return &errs.EventNotFoundError{}
+		}
+
+		return deleteEvent(tx, event)
+	})
+}
+
+func (r *BoltEventRepository) PurgeDeletedBefore(limit time.Time) ([]models.Event, error) {
+	purged := make([]models.Event, 0)
+	err := r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		err := tx.Bucket(eventsBucket).ForEach(func(k, v []byte) error {
+			var e models.Event
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+
+			if e.IsTrashed() && e.DeletedAt.Before(limit) {
+				purged = append(purged, e)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, e := range purged {
+			if err = deleteEvent(tx, e); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"encoding/json"
+	"workshop2/internal/app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltHistoryRepository keeps the change history next to the data it
+// describes, so history and revert snapshots survive a restart.
+type BoltHistoryRepository struct {
+	Store *BoltStore
+}
+
+func resourceKey(resourceType string, resourceID int, id int) []byte {
+	return append(resourcePrefix(resourceType, resourceID), itob(id)...)
+}
+
+func resourcePrefix(resourceType string, resourceID int) []byte {
+	return append(append([]byte(resourceType), 0), itob(resourceID)...)
+}
+
+func (r *BoltHistoryRepository) Create(entry models.HistoryEntry) (models.HistoryEntry, error) {
+	err := r.Store.DB.Update(func(tx *bolt.Tx) error {
+		id, err := tx.Bucket(historyBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+
+		entry.ID = int(id)
+		if err = tx.Bucket(historyByResourceBucket).Put(resourceKey(entry.ResourceType, entry.ResourceID, entry.ID), nil); err != nil {
+			return err
+		}
+
+		return put(tx.Bucket(historyBucket), entry.ID, entry)
+	})
+
+	return entry, err
+}
+
+func (r *BoltHistoryRepository) GetByResource(resourceType string, resourceID int) ([]models.HistoryEntry, error) {
+	entries := make([]models.HistoryEntry, 0)
+	err := r.Store.DB.View(func(tx *bolt.Tx) error {
+		history := tx.Bucket(historyBucket)
+		return scanPrefix(tx.Bucket(historyByResourceBucket), resourcePrefix(resourceType, resourceID), func(id int) error {
+			var entry models.HistoryEntry
+			if err := json.Unmarshal(history.Get(itob(id)), &entry); err != nil {
+				return err
+			}
+
+			entries = append(entries, entry)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,245 @@
+package repositories
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+type BoltNotificationRepository struct {
+	Store *BoltStore
+	tx    *bolt.Tx
+}
+
+// InTx returns a copy of the repository that works inside tx.
+func (r *BoltNotificationRepository) InTx(tx *bolt.Tx) *BoltNotificationRepository {
+	return &BoltNotificationRepository{Store: r.Store, tx: tx}
+}
+
+func getNotification(tx *bolt.Tx, id int) (models.Notification, error) {
+	var notification models.Notification
+	data := tx.Bucket(notificationsBucket).Get(itob(id))
+	if data == nil {
+		return notification, &errs.NotificationNotFoundError{}
+	}
+
+	return notification, json.Unmarshal(data, &notification)
+}
+
+func notificationIndexKeys(n models.Notification) [][2][]byte {
+	keys := [][2][]byte{
+		{notificationsByTimeBucket, timeKey(n.TimeUTC, n.ID)},
+		{notificationsByRecipientBucket, ownerKey(n.Recipient, n.ID)},
+	}
+
+	if n.EventID != 0 {
+		keys = append(keys, [2][]byte{notificationsByEventBucket, ownerKey(strconv.Itoa(n.EventID), n.ID)})
+	}
+
+	return keys
+}
+
+func putNotification(tx *bolt.Tx, n models.Notification, previous *models.Notification) error {
+	if previous != nil {
+		if err := deleteNotificationIndexes(tx, *previous); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range notificationIndexKeys(n) {
+		if err := tx.Bucket(key[0]).Put(key[1], nil); err != nil {
+			return err
+		}
+	}
+
+	return put(tx.Bucket(notificationsBucket), n.ID, n)
+}
+
+func deleteNotificationIndexes(tx *bolt.Tx, n models.Notification) error {
+	for _, key := range notificationIndexKeys(n) {
+		if err := tx.Bucket(key[0]).Delete(key[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteNotification(tx *bolt.Tx, n models.Notification) error {
+	if err := deleteNotificationIndexes(tx, n); err != nil {
+		return err
+	}
+
+	return tx.Bucket(notificationsBucket).Delete(itob(n.ID))
+}
+
+// byIndex loads the notifications whose IDs are produced by scan.
+func (r *BoltNotificationRepository) byIndex(scan func(tx *bolt.Tx, fn func(id int) error) error) ([]models.Notification, error) {
+	notifications := make([]models.Notification, 0)
+	err := r.Store.view(r.tx, func(tx *bolt.Tx) error {
+		return scan(tx, func(id int) error {
+			n, err := getNotification(tx, id)
+			if err != nil {
+				return err
+			}
+
+			notifications = append(notifications, n)
+
+			return nil
+		})
+	})
+
+	return notifications, err
+}
+
+func (r *BoltNotificationRepository) GetAll() ([]models.Notification, error) {
+	notifications := make([]models.Notification, 0)
+	err := r.Store.view(r.tx, func(tx *bolt.Tx) error {
+		return tx.Bucket(notificationsBucket).ForEach(func(k, v []byte) error {
+			var n models.Notification
+			if err := json.Unmarshal(v, &n); err != nil {
+				return err
+			}
+
+			notifications = append(notifications, n)
+
+			return nil
+		})
+	})
+
+	return notifications, err
+}
+
+func (r *BoltNotificationRepository) GetBetween(from time.Time, to time.Time) ([]models.Notification, error) {
+	return r.byIndex(func(tx *bolt.Tx, fn func(id int) error) error {
+		return scanTime(tx.Bucket(notificationsByTimeBucket), from, to, fn)
+	})
+}
+
+func (r *BoltNotificationRepository) GetByRecipient(recipient string) ([]models.Notification, error) {
+	return r.byIndex(func(tx *bolt.Tx, fn func(id int) error) error {
+		return scanPrefix(tx.Bucket(notificationsByRecipientBucket), ownerPrefix(recipient), fn)
+	})
+}
+
+func (r *BoltNotificationRepository) GetByEvent(eventID int) ([]models.Notification, error) {
+	return r.byIndex(func(tx *bolt.Tx, fn func(id int) error) error {
+		return scanPrefix(tx.Bucket(notificationsByEventBucket), ownerPrefix(strconv.Itoa(eventID)), fn)
+	})
+}
+
+func (r *BoltNotificationRepository) Get(id int) (models.Notification, error) {
+	var notification models.Notification
+	err := r.Store.view(r.tx, func(tx *bolt.Tx) error {
+		var err error
+		notification, err = getNotification(tx, id)
+		return err
+	})
+	if err != nil {
+		return models.Notification{}, err
+	}
+
+	return notification, nil
+}
+
+func (r *BoltNotificationRepository) Create(notification models.Notification) (models.Notification, error) {
+	err := r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		id, err := tx.Bucket(notificationsBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+
+		notification.ID = int(id)
+		notification.Version = 1
+
+		return putNotification(tx, notification, nil)
+	})
+
+	return notification, err
+}
+
+func (r *BoltNotificationRepository) Update(id int, newNotification models.Notification) (models.Notification, error) {
+	newNotification.ID = id
+
+	var current models.Notification
+	err := r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		var err error
+		current, err = getNotification(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if newNotification.Version != 0 && newNotification.Version != current.Version {
+			return errs.NewVersionMismatchError()
+		}
+
+		newNotification.Version = current.Version + 1
+
+		return putNotification(tx, newNotification, &current)
+	})
+	if _, ok := err.(*errs.VersionMismatchError); ok {
+		return current, err
+	}
+
+	return newNotification, err
+}
+
+func (r *BoltNotificationRepository) Delete(id int, version int) (models.Notification, error) {
+	var notification models.Notification
+	err := r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		var err error
+		notification, err = getNotification(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if version != 0 && version != notification.Version {
+			return errs.NewVersionMismatchError()
+		}
+
+		return deleteNotification(tx, notification)
+	})
+	if err != nil {
+		if _, ok := err.(*errs.VersionMismatchError); ok {
+			return notification, err
+		}
+
+		return models.Notification{}, err
+	}
+
+	return notification, nil
+}
+
+func (r *BoltNotificationRepository) DeleteMany(ids []int) ([]models.Notification, error) {
+	deleted := make([]models.Notification, 0, len(ids))
+	err := r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		seen := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			n, err := getNotification(tx, id)
+			if err != nil {
+				return err
+			}
+
+			if err = deleteNotification(tx, n); err != nil {
+				return err
+			}
+			deleted = append(deleted, n)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
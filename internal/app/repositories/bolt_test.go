@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/utils"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestStore(t *testing.T, path string) *BoltStore {
+	t.Helper()
+
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestBoltIndexesFollowUpdates(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "test.db"))
+	notifications := &BoltNotificationRepository{Store: store}
+	base := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	n, err := notifications.Create(models.Notification{Title: "a", TimeUTC: base, Recipient: "alice", EventID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n.TimeUTC = base.Add(time.Hour * 48)
+	n.Recipient = "bob"
+	n.EventID = 0
+	if _, err = notifications.Update(n.ID, n); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, _ := notifications.GetBetween(base, base.Add(time.Hour)); len(found) != 0 {
+		t.Errorf("old time index entry should be gone, got %+v", found)
+	}
+	if found, _ := notifications.GetBetween(base.Add(time.Hour*47), base.Add(time.Hour*49)); len(found) != 1 {
+		t.Errorf("new time index entry missing")
+	}
+	if found, _ := notifications.GetByRecipient("alice"); len(found) != 0 {
+		t.Errorf("old recipient index entry should be gone")
+	}
+	if found, _ := notifications.GetByRecipient("bob"); len(found) != 1 {
+		t.Errorf("new recipient index entry missing")
+	}
+	if found, _ := notifications.GetByEvent(3); len(found) != 0 {
+		t.Errorf("event index entry should be gone")
+	}
+}
+
+func TestBoltTimeIndexBeforeEpoch(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "test.db"))
+	events := &BoltEventRepository{Store: store}
+
+	for _, year := range []int{2001, 1960, 1999} {
+		if _, err := events.Create(models.Event{Title: "e", TimeUTC: time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, _ := events.GetBetween(time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	if len(found) != 2 || found[0].TimeUTC.Year() != 1960 || found[1].TimeUTC.Year() != 1999 {
+		t.Errorf("expected the 1960 and 1999 events in order, got %+v", found)
+	}
+}
+
+func TestBoltPersistenceAndBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.db")
+
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := &BoltUserRepository{Store: store, Validator: utils.NewValidator()}
+	user := models.User{Username: "alice", Password: "passw0rd!", Timezone: "UTC", EmailVerificationToken: "secret"}
+	if _, err = users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.Backup(filepath.Join(dir, "backup.db")); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	for _, p := range []string{path, filepath.Join(dir, "backup.db")} {
+		reopened := openTestStore(t, p)
+		stored, err := (&BoltUserRepository{Store: reopened}).Get("alice")
+		if err != nil {
+			t.Fatalf("%s: %s", p, err)
+		}
+
+		if stored.EmailVerificationToken != "secret" {
+			t.Errorf("%s: hidden fields should be persisted", p)
+		}
+	}
+}
+
+func TestBoltChangeLogPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := &BoltChangeLogRepository{Store: store}
+	epoch, err := log.Epoch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = log.Append(models.SyncChange{Owner: "bob", Resource: models.HistoryResourceNotification, ID: 1, Data: models.Notification{ID: 1, Title: "Bob's"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = log.Append(models.SyncChange{Resource: models.HistoryResourceEvent, ID: 1, Data: models.Event{ID: 1, Title: "Standup"}}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	log = &BoltChangeLogRepository{Store: openTestStore(t, path)}
+	if reopened, _ := log.Epoch(); reopened != epoch {
+		t.Errorf("expected the epoch %d to be kept, got %d", epoch, reopened)
+	}
+
+	changes, last, err := log.Since(0, "alice", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != 2 || len(changes) != 1 {
+		t.Fatalf("expected only the event change up to 2, got %+v up to %d", changes, last)
+	}
+	if event, ok := changes[0].Data.(models.Event); !ok || event.Title != "Standup" {
+		t.Errorf("expected the event to be decoded, got %#v", changes[0].Data)
+	}
+
+	change, _ := log.Append(models.SyncChange{Owner: "bob", Resource: models.HistoryResourceEvent, ID: 2, Deleted: true})
+	if change.Seq != 3 {
+		t.Errorf("expected the sequence to continue at 3, got %d", change.Seq)
+	}
+	if changes, _, _ = log.Since(2, "bob", 0); len(changes) != 1 || changes[0].Owner != "bob" || changes[0].Data != nil {
+		t.Errorf("expected bob's deletion, got %+v", changes)
+	}
+}
+
+func TestBoltChangeLogPruning(t *testing.T) {
+	log := &BoltChangeLogRepository{Store: openTestStore(t, filepath.Join(t.TempDir(), "test.db")), MaxEntries: 3}
+	now := time.Now().UTC()
+	for i := 1; i <= 5; i++ {
+		log.Append(models.SyncChange{ID: i, Timestamp: now.Add(time.Duration(i) * time.Minute)})
+	}
+
+	if _, _, err := log.Since(1, "", 0); err == nil {
+		t.Error("expected changes beyond MaxEntries to be dropped")
+	}
+	if changes, _, err := log.Since(2, "", 0); err != nil || len(changes) != 3 {
+		t.Errorf("expected the newest 3 changes, got %+v, %v", changes, err)
+	}
+
+	if err := log.PruneBefore(now.Add(time.Minute * 4)); err != nil {
+		t.Fatal(err)
+	}
+	changes, last, err := log.Since(3, "", 0)
+	if err != nil || last != 5 || len(changes) != 2 || changes[0].ID != 4 {
+		t.Errorf("expected changes 4 and 5, got %+v up to %d, %v", changes, last, err)
+	}
+
+	if err = log.PruneBefore(now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if changes, _, err = log.Since(5, "", 0); err != nil || len(changes) != 0 {
+		t.Errorf("expected an empty log to accept the last sequence, got %+v, %v", changes, err)
+	}
+}
+
+func TestBoltDueMarkPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marks := &BoltDueMarkRepository{Store: store}
+	if mark, err := marks.Get(); err != nil || !mark.IsZero() {
+		t.Fatalf("expected no mark yet, got %s, %v", mark, err)
+	}
+
+	mark := time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC)
+	if err = marks.Set(mark); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	marks = &BoltDueMarkRepository{Store: openTestStore(t, path)}
+	if reopened, _ := marks.Get(); !reopened.Equal(mark) {
+		t.Errorf("expected the mark %s to be kept, got %s", mark, reopened)
+	}
+}
+
+func TestBoltOwnerIndexBackfill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := &BoltEventRepository{Store: store}
+	created, err := events.Create(models.Event{Title: "Standup", Owner: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Databases written before the owner index have an empty bucket.
+	err = store.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(eventsByOwnerBucket).Delete(ownerKey("alice", created.ID))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	events = &BoltEventRepository{Store: openTestStore(t, path)}
+	if owned, _ := events.GetByOwner("alice"); len(owned) != 1 || owned[0].ID != created.ID {
+		t.Errorf("expected the event to be indexed on open, got %+v", owned)
+	}
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"encoding/json"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/utils"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+type BoltUserRepository struct {
+	Store     *BoltStore
+	Validator utils.ValidatorInterface
+	tx        *bolt.Tx
+}
+
+// InTx returns a copy of the repository that works inside tx.
+func (r *BoltUserRepository) InTx(tx *bolt.Tx) *BoltUserRepository {
+	return &BoltUserRepository{Store: r.Store, Validator: r.Validator, tx: tx}
+}
+
+func (r *BoltUserRepository) GetAll() ([]models.User, error) {
+	users := make([]models.User, 0)
+	err := r.Store.view(r.tx, func(tx *bolt.Tx) error {
+		return tx.Bucket(usersBucket).ForEach(func(k, v []byte) error {
+			u, err := decodeUser(v)
+			if err != nil {
+				return err
+			}
+
+			users = append(users, u)
+
+			return nil
+		})
+	})
+
+	return users, err
+}
+
+func (r *BoltUserRepository) Get(username string) (models.User, error) {
+	var user models.User
+	err := r.Store.view(r.tx, func(tx *bolt.Tx) error {
+		data := tx.Bucket(usersBucket).Get([]byte(username))
+		if data == nil {
+			return errs.NewUserNotFoundError()
+		}
+
+		var err error
+		user, err = decodeUser(data)
+
+		return err
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (r *BoltUserRepository) Create(user models.User) (models.User, error) {
+	err := r.Validator.Struct(user)
+	if err != nil {
+		return user, errs.NewUserValidationError(err)
+	}
+
+	err = r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+		if bucket.Get([]byte(user.Username)) != nil {
+			return errs.NewUserAlreadyExistsError()
+		}
+
+		return putUser(bucket, user)
+	})
+
+	return user, err
+}
+
+func (r *BoltUserRepository) Update(user models.User) error {
+	err := r.Validator.Struct(user)
+	if err != nil {
+		return errs.NewUserValidationError(err)
+	}
+
+	return r.Store.update(r.tx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+		if bucket.Get([]byte(user.Username)) == nil {
+			return errs.NewUserNotFoundError()
+		}
+
+		return putUser(bucket, user)
+	})
+}
+
+// storedUser also keeps the fields hidden from the API.
+type storedUser struct {
+	models.User
+	EmailVerificationToken string `json:"email_verification_token"`
+}
+
+func putUser(bucket *bolt.Bucket, user models.User) error {
+	data, err := json.Marshal(storedUser{user, user.EmailVerificationToken})
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(user.Username), data)
+}
+
+func decodeUser(data []byte) (models.User, error) {
+	var stored storedUser
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return models.User{}, err
+	}
+
+	stored.User.EmailVerificationToken = stored.EmailVerificationToken
+
+	return stored.User, nil
+}
@@ -0,0 +1,149 @@
+// Package cache puts read-through query caches in front of slower
+// repositories.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+type entry struct {
+	key     string
+	value   interface{}
+	tags    []string
+	expires time.Time
+}
+
+// queryCache is a size bounded LRU cache whose entries expire after ttl.
+// Entries carry tags so a write can drop every query it may have changed.
+type queryCache struct {
+	name       string
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	tags    map[string]map[*list.Element]struct{}
+	// version changes on every invalidation. Loads that started before it
+	// changed may have read stale data and are not stored.
+	version uint64
+
+	hits          uint64
+	misses        uint64
+	evictions     uint64
+	invalidations uint64
+}
+
+func newQueryCache(name string, maxEntries int, ttl time.Duration) *queryCache {
+	return &queryCache{
+		name:       name,
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		tags:       make(map[string]map[*list.Element]struct{}),
+	}
+}
+
+// get returns the cached value for key, or false together with the version
+// to hand to put once the value has been loaded.
+func (c *queryCache) get(key string) (interface{}, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		e := element.Value.(*entry)
+		if c.now().Before(e.expires) {
+			c.lru.MoveToFront(element)
+			c.hits++
+
+			return e.value, c.version, true
+		}
+
+		c.remove(element)
+	}
+
+	c.misses++
+
+	return nil, c.version, false
+}
+
+func (c *queryCache) put(key string, value interface{}, tags []string, version uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if version != c.version || c.maxEntries <= 0 {
+		return
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	element := c.lru.PushFront(&entry{key: key, value: value, tags: tags, expires: c.now().Add(c.ttl)})
+	c.entries[key] = element
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[*list.Element]struct{})
+		}
+		c.tags[tag][element] = struct{}{}
+	}
+
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+		c.evictions++
+	}
+}
+
+// invalidate drops every entry carrying one of the tags.
+func (c *queryCache) invalidate(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	c.invalidations++
+	for _, tag := range tags {
+		for element := range c.tags[tag] {
+			c.remove(element)
+		}
+	}
+}
+
+func (c *queryCache) remove(element *list.Element) {
+	e := element.Value.(*entry)
+	for _, tag := range e.tags {
+		delete(c.tags[tag], element)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+
+	delete(c.entries, e.key)
+	c.lru.Remove(element)
+}
+
+func (c *queryCache) Stats() models.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := models.CacheStats{
+		Name:          c.name,
+		Entries:       c.lru.Len(),
+		MaxEntries:    c.maxEntries,
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Evictions:     c.evictions,
+		Invalidations: c.invalidations,
+	}
+
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRatio = float64(c.hits) / float64(total)
+	}
+
+	return stats
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"testing"
+	"time"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/repositories"
+)
+
+var base = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestQueryCacheBounds(t *testing.T) {
+	c := newQueryCache("test", 2, time.Minute)
+	now := base
+	c.now = func() time.Time { return now }
+
+	for _, key := range []string{"a", "b"} {
+		_, version, _ := c.get(key)
+		c.put(key, key, nil, version)
+	}
+
+	c.get("a")
+	_, version, _ := c.get("c")
+	c.put("c", "c", nil, version)
+
+	if _, _, ok := c.get("b"); ok {
+		t.Errorf("least recently used entry should have been evicted")
+	}
+	if _, _, ok := c.get("a"); !ok {
+		t.Errorf("recently used entry should be kept")
+	}
+
+	now = now.Add(time.Minute)
+	if _, _, ok := c.get("a"); ok {
+		t.Errorf("entry should expire after the ttl")
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 5 || stats.Evictions != 1 || stats.Entries != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.HitRatio != 2.0/7.0 {
+		t.Errorf("unexpected hit ratio %f", stats.HitRatio)
+	}
+}
+
+func TestQueryCacheSkipsStaleLoads(t *testing.T) {
+	c := newQueryCache("test", 10, time.Minute)
+
+	_, version, _ := c.get("a")
+	c.invalidate("a")
+	c.put("a", "loaded before the write", []string{"a"}, version)
+
+	if _, _, ok := c.get("a"); ok {
+		t.Errorf("a value loaded before an invalidation must not be cached")
+	}
+}
+
+func TestNotificationCachePerRecipient(t *testing.T) {
+	inner := &repositories.NotificationRepository{}
+	r := NewNotificationRepository(inner, 100, time.Minute)
+
+	r.Create(models.Notification{Title: "a", TimeUTC: base, Recipient: "alice"})
+	r.GetByRecipient("alice")
+	r.GetByRecipient("bob")
+
+	bobs, _ := r.Create(models.Notification{Title: "b", TimeUTC: base, Recipient: "bob"})
+	if mine, _ := r.GetByRecipient("bob"); len(mine) != 1 {
+		t.Errorf("bob's query should be invalidated by his new notification")
+	}
+	r.GetByRecipient("alice")
+
+	if stats := r.Stats(); stats.Hits != 1 {
+		t.Errorf("alice's query should survive bob's write, got %+v", stats)
+	}
+
+	// A write that bypasses the cache, e.g. in a transaction, is picked
+	// up once the change is published.
+	bobs.Read = true
+	updated, _ := inner.Update(bobs.ID, bobs)
+	if mine, _ := r.GetByRecipient("bob"); mine[0].Read {
+		t.Fatalf("expected the cached, unread copy before the change is published")
+	}
+
+	r.Notify(models.Change{Type: models.ChangeNotificationUpdated, Data: updated})
+	if mine, _ := r.GetByRecipient("bob"); !mine[0].Read {
+		t.Errorf("published change should invalidate the cached query")
+	}
+}
+
+func TestEventCacheInvalidation(t *testing.T) {
+	r := NewEventRepository(&repositories.EventRepository{}, 100, time.Minute)
+
+	event, _ := r.Create(models.Event{Title: "e", Owner: "alice", TimeUTC: base})
+	r.GetAll()
+	r.GetBetween(base, base.Add(time.Hour))
+	r.Get(event.ID)
+
+	r.Trash(event.ID, 0, "alice", base)
+	if all, _ := r.GetAll(); len(all) != 0 {
+		t.Errorf("trashed event still cached in GetAll")
+	}
+	if between, _ := r.GetBetween(base, base.Add(time.Hour)); len(between) != 0 {
+		t.Errorf("trashed event still cached in GetBetween")
+	}
+	if _, err := r.Get(event.ID); err == nil {
+		t.Errorf("trashed event still cached in Get")
+	}
+	if trash, _ := r.GetTrash("alice"); len(trash) != 1 {
+		t.Errorf("expected the event in alice's trash")
+	}
+
+	r.Restore(event.ID, "alice")
+	if trash, _ := r.GetTrash("alice"); len(trash) != 0 {
+		t.Errorf("restored event still cached in the trash")
+	}
+}
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/services"
+)
+
+const (
+	tagAll     = "all"
+	tagBetween = "between"
+)
+
+func idTag(id int) string {
+	return "id:" + strconv.Itoa(id)
+}
+
+// ownerTag marks the queries of one owner's events and trash.
+func ownerTag(owner string) string {
+	return "owner:" + owner
+}
+
+// EventRepository caches the queries of an event repository until a write
+// may have changed their result. Writes made elsewhere, e.g. inside a
+// transaction, are picked up through Notify.
+type EventRepository struct {
+	Events services.EventRepositoryInterface
+	cache  *queryCache
+}
+
+func NewEventRepository(events services.EventRepositoryInterface, maxEntries int, ttl time.Duration) *EventRepository {
+	return &EventRepository{
+		Events: events,
+		cache:  newQueryCache("events", maxEntries, ttl),
+	}
+}
+
+func cloneEvents(events []models.Event) []models.Event {
+	clones := make([]models.Event, len(events))
+	for i, e := range events {
+		clones[i] = e.Clone()
+	}
+
+	return clones
+}
+
+func (r *EventRepository) list(key string, tags []string, load func() ([]models.Event, error)) ([]models.Event, error) {
+	cached, version, ok := r.cache.get(key)
+	if ok {
+		return cloneEvents(cached.([]models.Event)), nil
+	}
+
+	events, err := load()
+	if err != nil {
+		return events, err
+	}
+
+	r.cache.put(key, cloneEvents(events), tags, version)
+
+	return events, nil
+}
+
+func (r *EventRepository) Stats() models.CacheStats {
+	return r.cache.Stats()
+}
+
+func (r *EventRepository) GetAll() ([]models.Event, error) {
+	return r.list("all", []string{tagAll}, r.Events.GetAll)
+}
+
+func (r *EventRepository) GetBetween(from time.Time, to time.Time) ([]models.Event, error) {
+	key := fmt.Sprintf("between:%d:%d", from.UnixNano(), to.UnixNano())
+	return r.list(key, []string{tagBetween}, func() ([]models.Event, error) {
+		return r.Events.GetBetween(from, to)
+	})
+}
+
+func (r *EventRepository) GetByOwner(owner string) ([]models.Event, error) {
+	return r.list("owned:"+owner, []string{ownerTag(owner)}, func() ([]models.Event, error) {
+		return r.Events.GetByOwner(owner)
+	})
+}
+
+func (r *EventRepository) Get(id int) (models.Event, error) {
+	key := idTag(id)
+	cached, version, ok := r.cache.get(key)
+	if ok {
+		return cached.(models.Event).Clone(), nil
+	}
+
+	event, err := r.Events.Get(id)
+	if err != nil {
+		return event, err
+	}
+
+	r.cache.put(key, event.Clone(), []string{key}, version)
+
+	return event, nil
+}
+
+func (r *EventRepository) GetTrash(username string) ([]models.Event, error) {
+	return r.list("trash:"+username, []string{ownerTag(username)}, func() ([]models.Event, error) {
+		return r.Events.GetTrash(username)
+	})
+}
+
+func (r *EventRepository) Create(event models.Event) (models.Event, error) {
+	event, err := r.Events.Create(event)
+	r.cache.invalidate(tagAll, tagBetween, ownerTag(event.Owner))
+
+	return event, err
+}
+
+func (r *EventRepository) Update(id int, newEvent models.Event) (models.Event, error) {
+	event, err := r.Events.Update(id, newEvent)
+	r.cache.invalidate(tagAll, tagBetween, idTag(id), ownerTag(event.Owner))
+
+	return event, err
+}
+
+func (r *EventRepository) Trash(id int, version int, username string, deletedAt time.Time) (models.Event, error) {
+	event, err := r.Events.Trash(id, version, username, deletedAt)
+	r.cache.invalidate(tagAll, tagBetween, idTag(id), ownerTag(event.Owner))
+
+	return event, err
+}
+
+func (r *EventRepository) Restore(id int, username string) (models.Event, error) {
+	event, err := r.Events.Restore(id, username)
+	r.cache.invalidate(tagAll, tagBetween, idTag(id), ownerTag(username))
+
+	return event, err
+}
+
+func (r *EventRepository) Purge(id int, username string) error {
+	err := r.Events.Purge(id, username)
+	r.cache.invalidate(idTag(id), ownerTag(username))
+
+	return err
+}
+
+func (r *EventRepository) PurgeDeletedBefore(limit time.Time) ([]models.Event, error) {
+	events, err := r.Events.PurgeDeletedBefore(limit)
+
+	tags := make([]string, 0, len(events)*2)
+	for _, e := range events {
+		tags = append(tags, idTag(e.ID), ownerTag(e.Owner))
+	}
+	r.cache.invalidate(tags...)
+
+	return events, err
+}
+
+// Notify drops the queries a published event change may have changed.
+func (r *EventRepository) Notify(change models.Change) {
+	if event, ok := change.Data.(models.Event); ok {
+		r.cache.invalidate(tagAll, tagBetween, idTag(event.ID), ownerTag(event.Owner))
+	}
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/services"
+)
+
+func recipientTag(recipient string) string {
+	return "recipient:" + recipient
+}
+
+func eventTag(eventID int) string {
+	return "event:" + strconv.Itoa(eventID)
+}
+
+// notificationTags lists the queries a change to n may affect.
+func notificationTags(notifications ...models.Notification) []string {
+	tags := []string{tagAll, tagBetween}
+	for _, n := range notifications {
+		tags = append(tags, idTag(n.ID), recipientTag(n.Recipient), eventTag(n.EventID))
+	}
+
+	return tags
+}
+
+// NotificationRepository caches the queries of a notification repository,
+// per recipient for GetByRecipient, until a write may have changed them.
+// Writes made elsewhere, e.g. inside a transaction, are picked up through
+// Notify.
+type NotificationRepository struct {
+	Notifications services.NotificationRepositoryInterface
+	cache         *queryCache
+}
+
+func NewNotificationRepository(notifications services.NotificationRepositoryInterface, maxEntries int, ttl time.Duration) *NotificationRepository {
+	return &NotificationRepository{
+		Notifications: notifications,
+		cache:         newQueryCache("notifications", maxEntries, ttl),
+	}
+}
+
+func cloneNotifications(notifications []models.Notification) []models.Notification {
+	clones := make([]models.Notification, len(notifications))
+	for i, n := range notifications {
+		clones[i] = n.Clone()
+	}
+
+	return clones
+}
+
+func (r *NotificationRepository) list(key string, tags []string, load func() ([]models.Notification, error)) ([]models.Notification, error) {
+	cached, version, ok := r.cache.get(key)
+	if ok {
+		return cloneNotifications(cached.([]models.Notification)), nil
+	}
+
+	notifications, err := load()
+	if err != nil {
+		return notifications, err
+	}
+
+	r.cache.put(key, cloneNotifications(notifications), tags, version)
+
+	return notifications, nil
+}
+
+func (r *NotificationRepository) Stats() models.CacheStats {
+	return r.cache.Stats()
+}
+
+func (r *NotificationRepository) GetAll() ([]models.Notification, error) {
+	return r.list("all", []string{tagAll}, r.Notifications.GetAll)
+}
+
+func (r *NotificationRepository) GetBetween(from time.Time, to time.Time) ([]models.Notification, error) {
+	key := fmt.Sprintf("between:%d:%d", from.UnixNano(), to.UnixNano())
+	return r.list(key, []string{tagBetween}, func() ([]models.Notification, error) {
+		return r.Notifications.GetBetween(from, to)
+	})
+}
+
+func (r *NotificationRepository) GetByRecipient(recipient string) ([]models.Notification, error) {
+	key := recipientTag(recipient)
+	return r.list(key, []string{key}, func() ([]models.Notification, error) {
+		return r.Notifications.GetByRecipient(recipient)
+	})
+}
+
+func (r *NotificationRepository) GetByEvent(eventID int) ([]models.Notification, error) {
+	key := eventTag(eventID)
+	return r.list(key, []string{key}, func() ([]models.Notification, error) {
+		return r.Notifications.GetByEvent(eventID)
+	})
+}
+
+func (r *NotificationRepository) Get(id int) (models.Notification, error) {
+	key := idTag(id)
+	cached, version, ok := r.cache.get(key)
+	if ok {
+		return cached.(models.Notification).Clone(), nil
+	}
+
+	notification, err := r.Notifications.Get(id)
+	if err != nil {
+		return notification, err
+	}
+
+	r.cache.put(key, notification.Clone(), []string{key}, version)
+
+	return notification, nil
+}
+
+func (r *NotificationRepository) Create(notification models.Notification) (models.Notification, error) {
+	notification, err := r.Notifications.Create(notification)
+	if err == nil {
+		r.cache.invalidate(notificationTags(notification)...)
+	}
+
+	return notification, err
+}
+
+func (r *NotificationRepository) Update(id int, notification models.Notification) (models.Notification, error) {
+	// The recipient or event may change, so the queries of the old
+	// version have to go as well.
+	before, _ := r.Notifications.Get(id)
+	updated, err := r.Notifications.Update(id, notification)
+	r.cache.invalidate(notificationTags(before, updated)...)
+
+	return updated, err
+}
+
+func (r *NotificationRepository) Delete(id int, version int) (models.Notification, error) {
+	notification, err := r.Notifications.Delete(id, version)
+	if err == nil {
+		r.cache.invalidate(notificationTags(notification)...)
+	}
+
+	return notification, err
+}
+
+func (r *NotificationRepository) DeleteMany(ids []int) ([]models.Notification, error) {
+	notifications, err := r.Notifications.DeleteMany(ids)
+	if err == nil {
+		r.cache.invalidate(notificationTags(notifications...)...)
+	}
+
+	return notifications, err
+}
+
+// Notify drops the queries a published notification change may have
+// changed.
+func (r *NotificationRepository) Notify(change models.Change) {
+	if n, ok := change.Data.(models.Notification); ok {
+		r.cache.invalidate(notificationTags(n)...)
+	}
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+)
+
+// ChangeLogRepository is an append-only log of changes that lives as long
+// as the process. Only the newest MaxEntries are kept; zero keeps
+// everything.
+type ChangeLogRepository struct {
+	MaxEntries int
+	changes    []models.SyncChange
+	lastSeq    int64
+	epoch      int64
+	sync.RWMutex
+}
+
+func (r *ChangeLogRepository) Append(change models.SyncChange) (models.SyncChange, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastSeq++
+	change.Seq = r.lastSeq
+	r.changes = append(r.changes, change)
+
+	if r.MaxEntries > 0 && len(r.changes) > r.MaxEntries {
+		r.changes = append(r.changes[:0:0], r.changes[len(r.changes)-r.MaxEntries:]...)
+	}
+
+	return change, nil
+}
+
+// Since returns up to limit changes after seq that belong to owner or to
+// everyone, together with the sequence number of the newest change. It
+// fails when changes after seq have already been dropped.
+func (r *ChangeLogRepository) Since(seq int64, owner string, limit int) ([]models.SyncChange, int64, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if seq > r.lastSeq || seq < r.firstSeq()-1 {
+		return nil, r.lastSeq, errs.NewSyncTokenExpiredError()
+	}
+
+	start := sort.Search(len(r.changes), func(i int) bool {
+		return r.changes[i].Seq > seq
+	})
+
+	changes := make([]models.SyncChange, 0)
+	for _, c := range r.changes[start:] {
+		if limit > 0 && len(changes) == limit {
+			break
+		}
+
+		if c.Owner == "" || c.Owner == owner {
+			changes = append(changes, c)
+		}
+	}
+
+	return changes, r.lastSeq, nil
+}
+
+func (r *ChangeLogRepository) Last() (int64, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.lastSeq, nil
+}
+
+// PruneBefore drops the changes older than t.
+func (r *ChangeLogRepository) PruneBefore(t time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+
+	keep := sort.Search(len(r.changes), func(i int) bool {
+		return !r.changes[i].Timestamp.Before(t)
+	})
+	if keep > 0 {
+		r.changes = append(r.changes[:0:0], r.changes[keep:]...)
+	}
+
+	return nil
+}
+
+// Epoch is chosen when the log is first used, so a restarted process never
+// accepts the tokens of the log it lost.
+func (r *ChangeLogRepository) Epoch() (int64, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.epoch == 0 {
+		r.epoch = time.Now().UnixNano()
+	}
+
+	return r.epoch, nil
+}
+
+// firstSeq is the sequence number of the oldest change still kept.
+func (r *ChangeLogRepository) firstSeq() int64 {
+	if len(r.changes) == 0 {
+		return r.lastSeq + 1
+	}
+
+	return r.changes[0].Seq
+}
@@ -0,0 +1,124 @@
+package repositories_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+	"workshop2/internal/app/repositories"
+	"workshop2/internal/app/repositories/cache"
+	"workshop2/internal/app/repositories/repotest"
+	"workshop2/internal/app/services"
+	"workshop2/internal/app/utils"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func newBoltStore(t *testing.T) *repositories.BoltStore {
+	t.Helper()
+
+	store, err := repositories.OpenBoltStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestEventRepositoryConformance(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestEventRepository(t, func(t *testing.T) services.EventRepositoryInterface {
+			return &repositories.EventRepository{}
+		})
+	})
+	t.Run("Bolt", func(t *testing.T) {
+		repotest.TestEventRepository(t, func(t *testing.T) services.EventRepositoryInterface {
+			return &repositories.BoltEventRepository{Store: newBoltStore(t)}
+		})
+	})
+	t.Run("Cached", func(t *testing.T) {
+		repotest.TestEventRepository(t, func(t *testing.T) services.EventRepositoryInterface {
+			return cache.NewEventRepository(&repositories.BoltEventRepository{Store: newBoltStore(t)}, 100, time.Minute)
+		})
+	})
+}
+
+func TestNotificationRepositoryConformance(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestNotificationRepository(t, func(t *testing.T) services.NotificationRepositoryInterface {
+			return &repositories.NotificationRepository{}
+		})
+	})
+	t.Run("Bolt", func(t *testing.T) {
+		repotest.TestNotificationRepository(t, func(t *testing.T) services.NotificationRepositoryInterface {
+			return &repositories.BoltNotificationRepository{Store: newBoltStore(t)}
+		})
+	})
+	t.Run("Cached", func(t *testing.T) {
+		repotest.TestNotificationRepository(t, func(t *testing.T) services.NotificationRepositoryInterface {
+			return cache.NewNotificationRepository(&repositories.BoltNotificationRepository{Store: newBoltStore(t)}, 100, time.Minute)
+		})
+	})
+}
+
+func TestUserRepositoryConformance(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestUserRepository(t, func(t *testing.T) services.UserRepositoryInterface {
+			return &repositories.UserRepository{Validator: utils.NewValidator()}
+		})
+	})
+	t.Run("Bolt", func(t *testing.T) {
+		repotest.TestUserRepository(t, func(t *testing.T) services.UserRepositoryInterface {
+			return &repositories.BoltUserRepository{Store: newBoltStore(t), Validator: utils.NewValidator()}
+		})
+	})
+}
+
+func TestHistoryRepositoryConformance(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestHistoryRepository(t, func(t *testing.T) services.HistoryRepositoryInterface {
+			return &repositories.HistoryRepository{}
+		})
+	})
+	t.Run("Bolt", func(t *testing.T) {
+		repotest.TestHistoryRepository(t, func(t *testing.T) services.HistoryRepositoryInterface {
+			return &repositories.BoltHistoryRepository{Store: newBoltStore(t)}
+		})
+	})
+}
+
+func TestTransactorConformance(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestTransactor(t, func(t *testing.T) (services.TransactorInterface, services.Store) {
+			store := &repositories.MemoryStore{
+				Events:        &repositories.EventRepository{},
+				Notifications: &repositories.NotificationRepository{},
+				Users:         &repositories.UserRepository{Validator: utils.NewValidator()},
+			}
+
+			transactor := services.TransactorFunc(func(fn func(store services.Store) error) error {
+				return store.Transaction(func(tx repositories.MemoryTx) error {
+					return fn(services.Store{Events: tx.Events, Notifications: tx.Notifications, Users: tx.Users})
+				})
+			})
+
+			return transactor, services.Store{Events: store.Events, Notifications: store.Notifications, Users: store.Users}
+		})
+	})
+	t.Run("Bolt", func(t *testing.T) {
+		repotest.TestTransactor(t, func(t *testing.T) (services.TransactorInterface, services.Store) {
+			boltStore := newBoltStore(t)
+			events := &repositories.BoltEventRepository{Store: boltStore}
+			notifications := &repositories.BoltNotificationRepository{Store: boltStore}
+			users := &repositories.BoltUserRepository{Store: boltStore, Validator: utils.NewValidator()}
+
+			transactor := services.TransactorFunc(func(fn func(store services.Store) error) error {
+				return boltStore.Transaction(func(tx *bolt.Tx) error {
+					return fn(services.Store{Events: events.InTx(tx), Notifications: notifications.InTx(tx), Users: users.InTx(tx)})
+				})
+			})
+
+			return transactor, services.Store{Events: events, Notifications: notifications, Users: users}
+		})
+	})
+}
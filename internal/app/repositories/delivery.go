@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"sync"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+)
+
+const maxDeliveryAttempts = 10000
+
+// DeliveryRepository keeps the newest maxDeliveryAttempts attempts in a
+// ring. Delivered keys are forgotten once they are older than Retention,
+// which should cover every retry of a delivery; zero keeps them forever.
+type DeliveryRepository struct {
+	Retention   time.Duration
+	DeadLetters []models.DeadLetter
+	attempts    []models.DeliveryAttempt
+	oldest      int
+	lastAttempt int
+	delivered   map[string]time.Time
+	pruned      time.Time
+	claimed     map[string]bool
+	lastLetter  int
+	sync.RWMutex
+}
+
+func (r *DeliveryRepository) AddAttempt(attempt models.DeliveryAttempt) (models.DeliveryAttempt, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastAttempt++
+	attempt.ID = r.lastAttempt
+
+	if len(r.attempts) < maxDeliveryAttempts {
+		r.attempts = append(r.attempts, attempt)
+	} else {
+		r.attempts[r.oldest] = attempt
+		r.oldest = (r.oldest + 1) % len(r.attempts)
+	}
+
+	return attempt, nil
+}
+
+func (r *DeliveryRepository) GetAttempts(key string) ([]models.DeliveryAttempt, error) {
+	r.RLock()
+	defer r.RUnlock()
+	attempts := make([]models.DeliveryAttempt, 0)
+	for i := range r.attempts {
+		a := r.attempts[(r.oldest+i)%len(r.attempts)]
+		if a.Key == key {
+			attempts = append(attempts, a)
+		}
+	}
+
+	return attempts, nil
+}
+
+// Claim marks the key as in flight and reports false when it is already
+// in flight or delivered.
+func (r *DeliveryRepository) Claim(key string) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.delivered[key]; ok || r.claimed[key] {
+		return false, nil
+	}
+
+	if r.claimed == nil {
+		r.claimed = make(map[string]bool)
+	}
+	r.claimed[key] = true
+
+	return true, nil
+}
+
+func (r *DeliveryRepository) Release(key string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.claimed, key)
+
+	return nil
+}
+
+// MarkDelivered records a successful delivery, releases its claim and
+// reports false when the key had already been delivered.
+func (r *DeliveryRepository) MarkDelivered(key string, at time.Time) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.claimed, key)
+	if r.delivered == nil {
+		r.delivered = make(map[string]time.Time)
+	}
+	r.prune(at)
+
+	if _, ok := r.delivered[key]; ok {
+		return false, nil
+	}
+	r.delivered[key] = at
+
+	return true, nil
+}
+
+// prune forgets the deliveries older than Retention, at most once per
+// Retention so that recording a delivery stays cheap.
+func (r *DeliveryRepository) prune(now time.Time) {
+	if r.Retention <= 0 || now.Sub(r.pruned) < r.Retention {
+		return
+	}
+
+	for key, at := range r.delivered {
+		if now.Sub(at) > r.Retention {
+			delete(r.delivered, key)
+		}
+	}
+	r.pruned = now
+}
+
+func (r *DeliveryRepository) AddDeadLetter(letter models.DeadLetter) (models.DeadLetter, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastLetter++
+	letter.ID = r.lastLetter
+	r.DeadLetters = append(r.DeadLetters, letter)
+
+	return letter, nil
+}
+
+func (r *DeliveryRepository) GetDeadLetters() ([]models.DeadLetter, error) {
+	r.RLock()
+	defer r.RUnlock()
+	letters := make([]models.DeadLetter, len(r.DeadLetters))
+	copy(letters, r.DeadLetters)
+
+	return letters, nil
+}
+
+func (r *DeliveryRepository) GetDeadLetter(id int) (models.DeadLetter, error) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, l := range r.DeadLetters {
+		if l.ID == id {
+			return l, nil
+		}
+	}
+
+	return models.DeadLetter{}, errs.NewDeadLetterNotFoundError()
+}
+
+func (r *DeliveryRepository) DeleteDeadLetter(id int) (models.DeadLetter, error) {
+	r.Lock()
+	defer r.Unlock()
+	for i, l := range r.DeadLetters {
+		if l.ID == id {
+			r.DeadLetters = append(r.DeadLetters[:i], r.DeadLetters[i+1:]...)
+
+			return l, nil
+		}
+	}
+
+	return models.DeadLetter{}, errs.NewDeadLetterNotFoundError()
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+func TestDeliveryAttemptsRing(t *testing.T) {
+	r := &DeliveryRepository{}
+	for i := 0; i < maxDeliveryAttempts+2; i++ {
+		key := "other"
+		if i < 3 || i >= maxDeliveryAttempts {
+			key = "watched"
+		}
+
+		if _, err := r.AddAttempt(models.DeliveryAttempt{Key: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(r.attempts) != maxDeliveryAttempts {
+		t.Fatalf("expected %d attempts to be kept, got %d", maxDeliveryAttempts, len(r.attempts))
+	}
+
+	attempts, _ := r.GetAttempts("watched")
+	ids := make([]int, 0, len(attempts))
+	for _, a := range attempts {
+		ids = append(ids, a.ID)
+	}
+	if len(ids) != 3 || ids[0] != 3 || ids[1] != maxDeliveryAttempts+1 || ids[2] != maxDeliveryAttempts+2 {
+		t.Errorf("expected the two oldest attempts to be dropped and IDs to keep counting, got %v", ids)
+	}
+}
+
+func TestDeliveredKeysExpire(t *testing.T) {
+	r := &DeliveryRepository{Retention: time.Hour}
+	start := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+
+	if ok, _ := r.MarkDelivered("old", start); !ok {
+		t.Fatal("expected the first delivery to be recorded")
+	}
+	if ok, _ := r.MarkDelivered("old", start.Add(time.Minute)); ok {
+		t.Error("expected a repeated delivery to be reported")
+	}
+
+	if _, err := r.MarkDelivered("new", start.Add(time.Hour*2)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.delivered["old"]; ok {
+		t.Error("expected the delivery past the retention to be forgotten")
+	}
+	if claimed, _ := r.Claim("new"); claimed {
+		t.Error("expected the recent delivery to be kept")
+	}
+}
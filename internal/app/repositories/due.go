@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"sync"
+	"time"
+)
+
+// DueMarkRepository remembers up to when due notifications were published
+// for as long as the process runs.
+type DueMarkRepository struct {
+	mark time.Time
+	sync.Mutex
+}
+
+func (r *DueMarkRepository) Get() (time.Time, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.mark, nil
+}
+
+func (r *DueMarkRepository) Set(mark time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.mark = mark
+	return nil
+}
@@ -2,28 +2,104 @@ package repositories
 
 import (
 	"sync"
+	"time"
 	"workshop2/internal/app/errs"
 	"workshop2/internal/app/models"
 )
 
+// EventRepository keeps events in memory. Events are stored and handed out
+// as deep copies, so callers can modify what they get back without
+// touching shared state. The zero value is ready to use.
 type EventRepository struct {
-	Events []models.Event
+	events  map[int]models.Event
+	ids     idList
+	byTime  timeIndex
+	byOwner map[string]idList
+	lastID  int
 	sync.RWMutex
 }
 
+// collect copies the events with the given IDs that pass keep.
+func (r *EventRepository) collect(ids []int, keep func(e models.Event) bool) []models.Event {
+	events := make([]models.Event, 0)
+	for _, id := range ids {
+		if e := r.events[id]; keep(e) {
+			events = append(events, e.Clone())
+		}
+	}
+
+	return events
+}
+
+// store saves event and moves its time index entry if the time changed.
+func (r *EventRepository) store(event models.Event) {
+	if previous, ok := r.events[event.ID]; ok {
+		r.byTime.remove(previous.TimeUTC, previous.ID)
+	}
+
+	r.byTime.add(event.TimeUTC, event.ID)
+	r.events[event.ID] = event.Clone()
+}
+
+// add stores a new event and indexes it by owner; owners don't change.
+func (r *EventRepository) add(event models.Event) {
+	if r.byOwner == nil {
+		r.byOwner = make(map[string]idList)
+	}
+
+	owned := r.byOwner[event.Owner]
+	owned.add(event.ID)
+	r.byOwner[event.Owner] = owned
+	r.ids.add(event.ID)
+	r.store(event)
+}
+
+func (r *EventRepository) remove(event models.Event) {
+	owned := r.byOwner[event.Owner]
+	owned.remove(event.ID)
+	if len(owned) == 0 {
+		delete(r.byOwner, event.Owner)
+	} else {
+		r.byOwner[event.Owner] = owned
+	}
+
+	r.byTime.remove(event.TimeUTC, event.ID)
+	r.ids.remove(event.ID)
+	delete(r.events, event.ID)
+}
+
 func (r *EventRepository) GetAll() ([]models.Event, error) {
 	r.RLock()
 	defer r.RUnlock()
-	return r.Events, nil
+
+	return r.collect(r.ids, func(e models.Event) bool {
+		return !e.IsTrashed()
+	}), nil
+}
+
+func (r *EventRepository) GetBetween(from time.Time, to time.Time) ([]models.Event, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.collect(r.byTime.between(from, to), func(e models.Event) bool {
+		return !e.IsTrashed()
+	}), nil
+}
+
+func (r *EventRepository) GetByOwner(owner string) ([]models.Event, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.collect(r.byOwner[owner], func(e models.Event) bool {
+		return !e.IsTrashed()
+	}), nil
 }
 
 func (r *EventRepository) Get(id int) (models.Event, error) {
 	r.RLock()
 	defer r.RUnlock()
-	for _, e := range r.Events {
-		if e.ID == id {
-			return e, nil
-		}
+	if e, ok := r.events[id]; ok && !e.IsTrashed() {
+		return e.Clone(), nil
 	}
 
 	return models.Event{}, &errs.EventNotFoundError{}
@@ -32,44 +108,113 @@ func (r *EventRepository) Get(id int) (models.Event, error) {
 func (r *EventRepository) Create(event models.Event) (models.Event, error) {
 	r.Lock()
 	defer r.Unlock()
-
-	id := 1
-	if len(r.Events) > 0 {
-		id = (r.Events[len(r.Events)-1]).ID + 1
+	if r.events == nil {
+		r.events = make(map[int]models.Event)
 	}
-	event.ID = id
 
-	r.Events = append(r.Events, event)
+	r.lastID++
+	event.ID = r.lastID
+	event.Version = 1
+	event.DeletedAt = nil
+	event.DeletedBy = ""
 
-	return event, nil
+	r.add(event)
+
+	return event.Clone(), nil
 }
 
 func (r *EventRepository) Update(id int, newEvent models.Event) (models.Event, error) {
 
 	newEvent.ID = id
+	newEvent.DeletedAt = nil
+	newEvent.DeletedBy = ""
+	r.Lock()
+	defer r.Unlock()
+	e, ok := r.events[id]
+	if !ok || e.IsTrashed() {
+		return newEvent, &errs.EventNotFoundError{}
+	}
+
+	newEvent.Owner = e.Owner
+
+	if newEvent.Version != 0 && newEvent.Version != e.Version {
+		return e.Clone(), errs.NewVersionMismatchError()
+	}
+
+	newEvent.Version = e.Version + 1
+	r.store(newEvent)
+
+	return newEvent.Clone(), nil
+}
+
+func (r *EventRepository) Trash(id int, version int, username string, deletedAt time.Time) (models.Event, error) {
 	r.Lock()
 	defer r.Unlock()
-	for i, e := range r.Events {
-		if e.ID == newEvent.ID {
-			r.Events[i] = newEvent
+	e, ok := r.events[id]
+	if !ok || e.IsTrashed() {
+		return models.Event{}, &errs.EventNotFoundError{}
+	}
 
-			return newEvent, nil
-		}
+	if version != 0 && version != e.Version {
+		return e.Clone(), errs.NewVersionMismatchError()
+	}
+
+	e.Version++
+	e.DeletedAt = &deletedAt
+	e.DeletedBy = username
+	r.store(e)
+
+	return e.Clone(), nil
+}
+
+func (r *EventRepository) GetTrash(username string) ([]models.Event, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.collect(r.byOwner[username], func(e models.Event) bool {
+		return e.IsTrashed()
+	}), nil
+}
+
+func (r *EventRepository) Restore(id int, username string) (models.Event, error) {
+	r.Lock()
+	defer r.Unlock()
+	e, ok := r.events[id]
+	if !ok || !e.IsTrashed() || e.Owner != username {
+		return models.Event{}, &errs.EventNotFoundError{}
 	}
 
-	return newEvent, &errs.EventNotFoundError{}
+	e.Version++
+	e.DeletedAt = nil
+	e.DeletedBy = ""
+	r.store(e)
+
+	return e.Clone(), nil
 }
 
-func (r *EventRepository) Delete(id int) error {
+func (r *EventRepository) Purge(id int, username string) error {
+	r.Lock()
+	defer r.Unlock()
+	e, ok := r.events[id]
+	if !ok || !e.IsTrashed() || e.Owner != username {
+		return &errs.EventNotFoundError{}
+	}
+
+	r.remove(e)
 
+	return nil
+}
+
+func (r *EventRepository) PurgeDeletedBefore(limit time.Time) ([]models.Event, error) {
 	r.Lock()
 	defer r.Unlock()
-	for i, e := range r.Events {
-		if e.ID == id {
-			r.Events = append(r.Events[:i], r.Events[i+1:]...)
-			return nil
-		}
+	purged := r.collect(r.ids, func(e models.Event) bool {
+		return e.IsTrashed() && e.DeletedAt.Before(limit)
+	})
+
+	for _, e := range purged {
+		r.remove(e)
 	}
 
-	return &errs.EventNotFoundError{}
+	return purged, nil
 }
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"sync"
+	"workshop2/internal/app/models"
+)
+
+type HistoryRepository struct {
+	Entries []models.HistoryEntry
+	sync.RWMutex
+}
+
+func (r *HistoryRepository) Create(entry models.HistoryEntry) (models.HistoryEntry, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	entry.ID = len(r.Entries) + 1
+	r.Entries = append(r.Entries, entry)
+
+	return entry, nil
+}
+
+func (r *HistoryRepository) GetByResource(resourceType string, resourceID int) ([]models.HistoryEntry, error) {
+	r.RLock()
+	defer r.RUnlock()
+	entries := make([]models.HistoryEntry, 0)
+	for _, e := range r.Entries {
+		if e.ResourceType == resourceType && e.ResourceID == resourceID {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, nil
+}
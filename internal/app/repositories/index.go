@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"sort"
+	"time"
+)
+
+// idList keeps IDs in ascending order. IDs come from a monotonic counter,
+// so new ones are always appended.
+type idList []int
+
+func (l *idList) add(id int) {
+	*l = append(*l, id)
+}
+
+func (l *idList) remove(id int) {
+	i := sort.SearchInts(*l, id)
+	if i < len(*l) && (*l)[i] == id {
+		*l = append((*l)[:i], (*l)[i+1:]...)
+	}
+}
+
+type timeIndexEntry struct {
+	time time.Time
+	id   int
+}
+
+func (e timeIndexEntry) less(other timeIndexEntry) bool {
+	if e.time.Equal(other.time) {
+		return e.id < other.id
+	}
+
+	return e.time.Before(other.time)
+}
+
+// timeIndex keeps IDs sorted by time and then ID so range queries are a
+// binary search instead of a scan.
+type timeIndex []timeIndexEntry
+
+func (x timeIndex) search(entry timeIndexEntry) int {
+	return sort.Search(len(x), func(i int) bool {
+		return !x[i].less(entry)
+	})
+}
+
+func (x *timeIndex) add(t time.Time, id int) {
+	entry := timeIndexEntry{t, id}
+	i := x.search(entry)
+	*x = append(*x, timeIndexEntry{})
+	copy((*x)[i+1:], (*x)[i:])
+	(*x)[i] = entry
+}
+
+func (x *timeIndex) remove(t time.Time, id int) {
+	entry := timeIndexEntry{t, id}
+	i := x.search(entry)
+	if i < len(*x) && (*x)[i] == entry {
+		*x = append((*x)[:i], (*x)[i+1:]...)
+	}
+}
+
+// between returns the IDs with from <= time < to in index order.
+func (x timeIndex) between(from time.Time, to time.Time) []int {
+	start := sort.Search(len(x), func(i int) bool {
+		return !x[i].time.Before(from)
+	})
+
+	ids := make([]int, 0)
+	for _, e := range x[start:] {
+		if !e.time.Before(to) {
+			break
+		}
+		ids = append(ids, e.id)
+	}
+
+	return ids
+}
@@ -2,34 +2,108 @@ package repositories
 
 import (
 	"sync"
+	"time"
 	"workshop2/internal/app/errs"
 	"workshop2/internal/app/models"
 )
 
+// NotificationRepository keeps notifications in memory. The zero value is
+// ready to use.
 type NotificationRepository struct {
-	Notifications []models.Notification
+	notifications map[int]models.Notification
+	ids           idList
+	byTime        timeIndex
+	lastID        int
 	sync.RWMutex
 }
 
+func (r *NotificationRepository) collect(ids []int, keep func(n models.Notification) bool) []models.Notification {
+	notifications := make([]models.Notification, 0)
+	for _, id := range ids {
+		if n := r.notifications[id]; keep(n) {
+			notifications = append(notifications, n.Clone())
+		}
+	}
+
+	return notifications
+}
+
+func (r *NotificationRepository) store(notification models.Notification) {
+	if previous, ok := r.notifications[notification.ID]; ok {
+		r.byTime.remove(previous.TimeUTC, previous.ID)
+	}
+
+	r.byTime.add(notification.TimeUTC, notification.ID)
+	r.notifications[notification.ID] = notification.Clone()
+}
+
+func (r *NotificationRepository) remove(notification models.Notification) {
+	r.byTime.remove(notification.TimeUTC, notification.ID)
+	r.ids.remove(notification.ID)
+	delete(r.notifications, notification.ID)
+}
+
 func (r *NotificationRepository) GetAll() ([]models.Notification, error) {
 	r.RLock()
 	defer r.RUnlock()
-	return r.Notifications, nil
+
+	return r.collect(r.ids, func(n models.Notification) bool {
+		return true
+	}), nil
+}
+
+func (r *NotificationRepository) GetBetween(from time.Time, to time.Time) ([]models.Notification, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.collect(r.byTime.between(from, to), func(n models.Notification) bool {
+		return true
+	}), nil
+}
+
+func (r *NotificationRepository) GetByRecipient(recipient string) ([]models.Notification, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.collect(r.ids, func(n models.Notification) bool {
+		return n.Recipient == recipient
+	}), nil
+}
+
+func (r *NotificationRepository) GetByEvent(eventID int) ([]models.Notification, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.collect(r.ids, func(n models.Notification) bool {
+		return n.EventID == eventID
+	}), nil
+}
+
+func (r *NotificationRepository) Get(id int) (models.Notification, error) {
+	r.RLock()
+	defer r.RUnlock()
+	if n, ok := r.notifications[id]; ok {
+		return n.Clone(), nil
+	}
+
+	return models.Notification{}, &errs.NotificationNotFoundError{}
 }
 
 func (r *NotificationRepository) Create(notification models.Notification) (models.Notification, error) {
 	r.Lock()
 	defer r.Unlock()
-	id := 1
-	if len(r.Notifications) > 0 {
-		id = (r.Notifications[len(r.Notifications)-1]).ID + 1
+	if r.notifications == nil {
+		r.notifications = make(map[int]models.Notification)
 	}
 
-	notification.ID = id
+	r.lastID++
+	notification.ID = r.lastID
+	notification.Version = 1
 
-	r.Notifications = append(r.Notifications, notification)
+	r.ids.add(notification.ID)
+	r.store(notification)
 
-	return notification, nil
+	return notification.Clone(), nil
 }
 
 func (r *NotificationRepository) Update(id int, newNotification models.Notification) (models.Notification, error) {
@@ -37,13 +111,64 @@ func (r *NotificationRepository) Update(id int, newNotification models.Notificat
 	newNotification.ID = id
 	r.Lock()
 	defer r.Unlock()
-	for i, n := range r.Notifications {
-		if n.ID == newNotification.ID {
-			r.Notifications[i] = newNotification
+	n, ok := r.notifications[id]
+	if !ok {
+		return newNotification, &errs.NotificationNotFoundError{}
+	}
+
+	if newNotification.Version != 0 && newNotification.Version != n.Version {
+		return n.Clone(), errs.NewVersionMismatchError()
+	}
+
+	newNotification.Version = n.Version + 1
+	r.store(newNotification)
+
+	return newNotification.Clone(), nil
+}
 
-			return newNotification, nil
+func (r *NotificationRepository) Delete(id int, version int) (models.Notification, error) {
+	r.Lock()
+	defer r.Unlock()
+	n, ok := r.notifications[id]
+	if !ok {
+		return models.Notification{}, &errs.NotificationNotFoundError{}
+	}
+
+	if version != 0 && version != n.Version {
+		return n.Clone(), errs.NewVersionMismatchError()
+	}
+
+	r.remove(n)
+
+	return n, nil
+}
+
+// DeleteMany removes either all of the given notifications or, when any of
+// them is missing, none of them.
+func (r *NotificationRepository) DeleteMany(ids []int) ([]models.Notification, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	unique := make([]int, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if _, ok := r.notifications[id]; !ok {
+			return nil, &errs.NotificationNotFoundError{}
 		}
+		unique = append(unique, id)
+	}
+
+	deleted := make([]models.Notification, 0, len(unique))
+	for _, id := range unique {
+		n := r.notifications[id]
+		r.remove(n)
+		deleted = append(deleted, n)
 	}
 
-	return newNotification, &errs.NotificationNotFoundError{}
+	return deleted, nil
 }
@@ -0,0 +1,475 @@
+// Package repotest holds the conformance suite every repository backend
+// has to pass, so the in-memory and persistent stores cannot drift apart.
+package repotest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/services"
+)
+
+type EventFactory func(t *testing.T) services.EventRepositoryInterface
+
+var base = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newEvent(title string, at time.Time) models.Event {
+	return models.Event{Title: title, Owner: "alice", Time: at, TimeUTC: at}
+}
+
+func assertEventNotFound(t *testing.T, err error) {
+	t.Helper()
+
+	var notFound *errs.EventNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("expected EventNotFoundError, got %v", err)
+	}
+}
+
+func assertVersionMismatch(t *testing.T, err error) {
+	t.Helper()
+
+	var mismatch *errs.VersionMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("expected VersionMismatchError, got %v", err)
+	}
+}
+
+func eventIDs(events []models.Event) []int {
+	ids := make([]int, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+
+	return ids
+}
+
+// TestEventRepository checks that an event repository behaves like the
+// in-memory one. newRepo must return an empty repository on every call.
+func TestEventRepository(t *testing.T, newRepo EventFactory) {
+	t.Run("CreateAssignsIDs", func(t *testing.T) {
+		r := newRepo(t)
+		seen := make(map[int]bool)
+		previous := 0
+		for i := 0; i < 5; i++ {
+			e, err := r.Create(newEvent("e", base))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if e.ID <= previous || seen[e.ID] {
+				t.Fatalf("IDs must be unique and increasing, got %d after %d", e.ID, previous)
+			}
+			if e.Version != 1 {
+				t.Errorf("new events start at version 1, got %d", e.Version)
+			}
+
+			seen[e.ID] = true
+			previous = e.ID
+		}
+	})
+
+	t.Run("CreateIgnoresClientState", func(t *testing.T) {
+		r := newRepo(t)
+		deletedAt := base
+		e := newEvent("e", base)
+		e.ID = 99
+		e.Version = 7
+		e.DeletedAt = &deletedAt
+		e.DeletedBy = "alice"
+
+		created, err := r.Create(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if created.Version != 1 || created.IsTrashed() || created.DeletedBy != "" {
+			t.Errorf("create must reset version and trash state, got %+v", created)
+		}
+		if created.Owner != "alice" {
+			t.Errorf("create must keep the owner, got %q", created.Owner)
+		}
+		if _, err = r.Get(created.ID); err != nil {
+			t.Errorf("created event should be visible: %v", err)
+		}
+	})
+
+	t.Run("IDsNotReused", func(t *testing.T) {
+		r := newRepo(t)
+		r.Create(newEvent("first", base))
+		last, _ := r.Create(newEvent("last", base))
+
+		r.Trash(last.ID, 0, "alice", base)
+		r.Purge(last.ID, "alice")
+
+		next, _ := r.Create(newEvent("next", base))
+		if next.ID <= last.ID {
+			t.Errorf("ID %d of a purged event was reused", next.ID)
+		}
+	})
+
+	t.Run("ReadsAreCopies", func(t *testing.T) {
+		r := newRepo(t)
+		e := newEvent("e", base)
+		e.Reminders = []int{10}
+		created, _ := r.Create(e)
+		e.Reminders[0] = 20
+
+		got, _ := r.Get(created.ID)
+		got.Title = "changed"
+		got.Reminders[0] = 30
+
+		all, _ := r.GetAll()
+		all[0].Reminders[0] = 40
+		between, _ := r.GetBetween(base, base.Add(time.Hour))
+		between[0].TimeUTC = base.Add(time.Hour)
+
+		r.Trash(created.ID, 0, "alice", base)
+		trash, _ := r.GetTrash("alice")
+		*trash[0].DeletedAt = base.Add(-time.Hour * 24 * 365)
+		if purged, _ := r.PurgeDeletedBefore(base.Add(-time.Hour)); len(purged) != 0 {
+			t.Errorf("changing a returned DeletedAt must not affect the stored event")
+		}
+
+		restored, _ := r.Restore(created.ID, "alice")
+		if restored.Title != "e" || len(restored.Reminders) != 1 || restored.Reminders[0] != 10 || !restored.TimeUTC.Equal(base) {
+			t.Errorf("stored event was changed through a returned value: %+v", restored)
+		}
+	})
+
+	t.Run("GetRoundTrip", func(t *testing.T) {
+		r := newRepo(t)
+		e := newEvent("Planning", base)
+		e.Description = "Quarterly"
+		e.Reminders = []int{10, 60}
+
+		created, _ := r.Create(e)
+		got, err := r.Get(created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Title != "Planning" || got.Description != "Quarterly" || !got.TimeUTC.Equal(base) || len(got.Reminders) != 2 {
+			t.Errorf("stored event differs: %+v", got)
+		}
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.Get(42)
+		assertEventNotFound(t, err)
+	})
+
+	t.Run("GetAllOrderedByID", func(t *testing.T) {
+		r := newRepo(t)
+		if events, err := r.GetAll(); err != nil || len(events) != 0 {
+			t.Fatalf("empty repository should return no events, got %v %v", events, err)
+		}
+
+		for i := 3; i > 0; i-- {
+			r.Create(newEvent("e", base.Add(time.Hour*time.Duration(i))))
+		}
+
+		events, _ := r.GetAll()
+		ids := eventIDs(events)
+		if len(ids) != 3 || ids[0] >= ids[1] || ids[1] >= ids[2] {
+			t.Errorf("expected 3 events ordered by ID, got %v", ids)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := newRepo(t)
+		created, _ := r.Create(newEvent("old", base))
+
+		change := created
+		change.Title = "new"
+		updated, err := r.Update(created.ID, change)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if updated.Version != 2 || updated.Title != "new" {
+			t.Errorf("unexpected update result %+v", updated)
+		}
+		if got, _ := r.Get(created.ID); got.Title != "new" || got.Version != 2 {
+			t.Errorf("update not stored: %+v", got)
+		}
+
+		change.Owner = "bob"
+		change.Version = 0
+		r.Update(created.ID, change)
+		if got, _ := r.Get(created.ID); got.Owner != "alice" {
+			t.Errorf("update must keep the owner, got %q", got.Owner)
+		}
+	})
+
+	t.Run("UpdateVersionMismatch", func(t *testing.T) {
+		r := newRepo(t)
+		created, _ := r.Create(newEvent("old", base))
+
+		stale := created
+		stale.Version = 5
+		stale.Title = "stale"
+		current, err := r.Update(created.ID, stale)
+		assertVersionMismatch(t, err)
+
+		if current.Version != 1 || current.Title != "old" {
+			t.Errorf("mismatch should return the stored event, got %+v", current)
+		}
+
+		unchecked := created
+		unchecked.Version = 0
+		if _, err = r.Update(created.ID, unchecked); err != nil {
+			t.Errorf("version 0 should skip the check: %v", err)
+		}
+	})
+
+	t.Run("UpdateMissing", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.Update(42, newEvent("e", base))
+		assertEventNotFound(t, err)
+	})
+
+	t.Run("GetBetweenOrderedByTime", func(t *testing.T) {
+		r := newRepo(t)
+		offsets := []int{5, -3, 0, 2, 9, 2}
+		for _, h := range offsets {
+			r.Create(newEvent("e", base.Add(time.Hour*time.Duration(h))))
+		}
+
+		events, err := r.GetBetween(base, base.Add(time.Hour*9))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(events) != 4 {
+			t.Fatalf("expected 4 events in [0h, 9h), got %d", len(events))
+		}
+		for i := 1; i < len(events); i++ {
+			prev, cur := events[i-1], events[i]
+			if cur.TimeUTC.Before(prev.TimeUTC) || (cur.TimeUTC.Equal(prev.TimeUTC) && cur.ID < prev.ID) {
+				t.Errorf("events not ordered by time then ID: %v", eventIDs(events))
+			}
+		}
+
+		moved := events[0]
+		moved.TimeUTC = base.Add(-time.Hour)
+		r.Update(moved.ID, moved)
+		if events, _ = r.GetBetween(base, base.Add(time.Hour*9)); len(events) != 3 {
+			t.Errorf("moved event should leave the range, got %v", eventIDs(events))
+		}
+	})
+
+	t.Run("TrashAndRestore", func(t *testing.T) {
+		r := newRepo(t)
+		created, _ := r.Create(newEvent("e", base))
+
+		_, err := r.Trash(created.ID, 3, "alice", base)
+		assertVersionMismatch(t, err)
+
+		trashed, err := r.Trash(created.ID, created.Version, "alice", base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !trashed.IsTrashed() || trashed.DeletedBy != "alice" || trashed.Version != 2 {
+			t.Errorf("unexpected trashed event %+v", trashed)
+		}
+
+		_, err = r.Get(created.ID)
+		assertEventNotFound(t, err)
+		_, err = r.Update(created.ID, created)
+		assertEventNotFound(t, err)
+		_, err = r.Trash(created.ID, 0, "alice", base)
+		assertEventNotFound(t, err)
+
+		if events, _ := r.GetAll(); len(events) != 0 {
+			t.Errorf("trashed events must not be listed")
+		}
+		if events, _ := r.GetBetween(base.Add(-time.Hour), base.Add(time.Hour)); len(events) != 0 {
+			t.Errorf("trashed events must not be found by time")
+		}
+		if trash, _ := r.GetTrash("bob"); len(trash) != 0 {
+			t.Errorf("trash is per user")
+		}
+		if trash, _ := r.GetTrash("alice"); len(trash) != 1 {
+			t.Errorf("expected one trashed event for alice, got %d", len(trash))
+		}
+
+		_, err = r.Restore(created.ID, "bob")
+		assertEventNotFound(t, err)
+
+		restored, err := r.Restore(created.ID, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.IsTrashed() || restored.Version != 3 {
+			t.Errorf("unexpected restored event %+v", restored)
+		}
+		if _, err = r.Get(created.ID); err != nil {
+			t.Errorf("restored event should be visible: %v", err)
+		}
+
+		_, err = r.Restore(created.ID, "alice")
+		assertEventNotFound(t, err)
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		r := newRepo(t)
+		created, _ := r.Create(newEvent("e", base))
+
+		assertEventNotFound(t, r.Purge(created.ID, "alice"))
+
+		r.Trash(created.ID, 0, "alice", base)
+		assertEventNotFound(t, r.Purge(created.ID, "bob"))
+
+		if err := r.Purge(created.ID, "alice"); err != nil {
+			t.Fatal(err)
+		}
+		if trash, _ := r.GetTrash("alice"); len(trash) != 0 {
+			t.Errorf("purged event still in trash")
+		}
+		_, err := r.Restore(created.ID, "alice")
+		assertEventNotFound(t, err)
+	})
+
+	t.Run("PurgeDeletedBefore", func(t *testing.T) {
+		r := newRepo(t)
+		old, _ := r.Create(newEvent("old", base))
+		recent, _ := r.Create(newEvent("recent", base))
+		kept, _ := r.Create(newEvent("kept", base))
+
+		r.Trash(old.ID, 0, "alice", base.Add(-time.Hour*48))
+		r.Trash(recent.ID, 0, "alice", base)
+
+		purged, err := r.PurgeDeletedBefore(base.Add(-time.Hour * 24))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ids := eventIDs(purged); len(ids) != 1 || ids[0] != old.ID {
+			t.Errorf("expected only the old event to be purged, got %v", ids)
+		}
+		if trash, _ := r.GetTrash("alice"); len(trash) != 1 || trash[0].ID != recent.ID {
+			t.Errorf("recently trashed event should stay in the trash")
+		}
+		if _, err = r.Get(kept.ID); err != nil {
+			t.Errorf("live events must not be purged: %v", err)
+		}
+	})
+
+	t.Run("GetByOwner", func(t *testing.T) {
+		r := newRepo(t)
+		first, _ := r.Create(newEvent("first", base))
+		bobs := newEvent("bob's", base)
+		bobs.Owner = "bob"
+		second, _ := r.Create(bobs)
+		third, _ := r.Create(newEvent("third", base))
+
+		byOwner := func(owner string) []int {
+			events, err := r.GetByOwner(owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return eventIDs(events)
+		}
+		trashOf := func(owner string) []int {
+			events, err := r.GetTrash(owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return eventIDs(events)
+		}
+
+		// The owner of an event never changes.
+		third.Owner = "bob"
+		r.Update(third.ID, third)
+
+		if ids := byOwner("alice"); len(ids) != 2 || ids[0] != first.ID || ids[1] != third.ID {
+			t.Errorf("unexpected events of alice: %v", ids)
+		}
+		if ids := byOwner("bob"); len(ids) != 1 || ids[0] != second.ID {
+			t.Errorf("unexpected events of bob: %v", ids)
+		}
+
+		r.Trash(first.ID, 0, "alice", base)
+		if ids := byOwner("alice"); len(ids) != 1 || ids[0] != third.ID {
+			t.Errorf("trashed events must not be listed by owner, got %v", ids)
+		}
+		if ids := trashOf("alice"); len(ids) != 1 || ids[0] != first.ID {
+			t.Errorf("unexpected trash of alice: %v", ids)
+		}
+
+		r.Restore(first.ID, "alice")
+		if ids := byOwner("alice"); len(ids) != 2 || ids[0] != first.ID {
+			t.Errorf("restored events must be listed by owner again, got %v", ids)
+		}
+
+		r.Trash(second.ID, 0, "bob", base)
+		r.Purge(second.ID, "bob")
+		r.Trash(third.ID, 0, "alice", base)
+		r.PurgeDeletedBefore(base.Add(time.Hour))
+		if ids := byOwner("bob"); len(ids) != 0 {
+			t.Errorf("purged events must not be listed by owner, got %v", ids)
+		}
+		if ids := trashOf("alice"); len(ids) != 0 {
+			t.Errorf("purged events must leave the trash, got %v", ids)
+		}
+		if ids := byOwner("alice"); len(ids) != 1 || ids[0] != first.ID {
+			t.Errorf("unexpected events of alice after purging: %v", ids)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		r := newRepo(t)
+		const workers, perWorker = 8, 10
+
+		var wg sync.WaitGroup
+		ids := make(chan int, workers*perWorker)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					e, err := r.Create(newEvent("e", base.Add(time.Minute*time.Duration(w*perWorker+i))))
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					ids <- e.ID
+
+					e.Title = "updated"
+					if _, err = r.Update(e.ID, e); err != nil {
+						t.Error(err)
+					}
+					r.GetAll()
+					r.GetBetween(base, base.Add(time.Hour))
+				}
+			}(w)
+		}
+		wg.Wait()
+		close(ids)
+
+		seen := make(map[int]bool)
+		for id := range ids {
+			if seen[id] {
+				t.Errorf("ID %d assigned twice", id)
+			}
+			seen[id] = true
+		}
+
+		events, _ := r.GetAll()
+		if len(events) != workers*perWorker {
+			t.Errorf("expected %d events, got %d", workers*perWorker, len(events))
+		}
+		for _, e := range events {
+			if e.Title != "updated" || e.Version != 2 {
+				t.Errorf("lost update on event %d: %+v", e.ID, e)
+			}
+		}
+	})
+}
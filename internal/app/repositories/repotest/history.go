@@ -0,0 +1,120 @@
+package repotest
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/services"
+)
+
+type HistoryFactory func(t *testing.T) services.HistoryRepositoryInterface
+
+func newEntry(resourceType string, resourceID int, version int) models.HistoryEntry {
+	return models.HistoryEntry{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Version:      version,
+		Action:       models.HistoryActionUpdated,
+		Actor:        "alice",
+		Timestamp:    base,
+	}
+}
+
+// TestHistoryRepository checks that a history repository behaves like the
+// in-memory one. newRepo must return an empty repository on every call.
+func TestHistoryRepository(t *testing.T, newRepo HistoryFactory) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		r := newRepo(t)
+		entry := newEntry(models.HistoryResourceEvent, 1, 2)
+		entry.Before = json.RawMessage(`{"title":"old"}`)
+		entry.After = json.RawMessage(`{"title":"new"}`)
+		entry.Changes = []models.FieldChange{{Field: "title", Before: "old", After: "new"}}
+
+		created, err := r.Create(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created.ID == 0 {
+			t.Errorf("expected an ID to be assigned")
+		}
+
+		entries, err := r.GetByResource(models.HistoryResourceEvent, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("expected one entry, got %+v", entries)
+		}
+
+		got := entries[0]
+		if got.ID != created.ID || got.Version != 2 || got.Action != models.HistoryActionUpdated || got.Actor != "alice" || !got.Timestamp.Equal(base) {
+			t.Errorf("stored entry differs: %+v", got)
+		}
+		if string(got.Before) != `{"title":"old"}` || string(got.After) != `{"title":"new"}` {
+			t.Errorf("snapshots differ: %s, %s", got.Before, got.After)
+		}
+		if len(got.Changes) != 1 || got.Changes[0].Field != "title" || got.Changes[0].After != "new" {
+			t.Errorf("changes differ: %+v", got.Changes)
+		}
+	})
+
+	t.Run("GetByResource", func(t *testing.T) {
+		r := newRepo(t)
+		if entries, err := r.GetByResource(models.HistoryResourceEvent, 1); err != nil || entries == nil || len(entries) != 0 {
+			t.Fatalf("expected no entries, got %v %v", entries, err)
+		}
+
+		for version := 1; version <= 3; version++ {
+			r.Create(newEntry(models.HistoryResourceEvent, 1, version))
+			r.Create(newEntry(models.HistoryResourceEvent, 2, version))
+			r.Create(newEntry(models.HistoryResourceNotification, 1, version))
+		}
+
+		entries, _ := r.GetByResource(models.HistoryResourceEvent, 1)
+		if len(entries) != 3 {
+			t.Fatalf("expected 3 entries of event 1, got %+v", entries)
+		}
+		for i, e := range entries {
+			if e.ResourceType != models.HistoryResourceEvent || e.ResourceID != 1 || e.Version != i+1 {
+				t.Errorf("expected version %d of event 1 in order, got %+v", i+1, e)
+			}
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		r := newRepo(t)
+		const workers, perWorker = 8, 10
+
+		var wg sync.WaitGroup
+		ids := make(chan int, workers*perWorker)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					e, err := r.Create(newEntry(models.HistoryResourceEvent, 1, i))
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					ids <- e.ID
+				}
+			}()
+		}
+		wg.Wait()
+		close(ids)
+
+		seen := make(map[int]bool)
+		for id := range ids {
+			if seen[id] {
+				t.Errorf("ID %d assigned twice", id)
+			}
+			seen[id] = true
+		}
+
+		if entries, _ := r.GetByResource(models.HistoryResourceEvent, 1); len(entries) != workers*perWorker {
+			t.Errorf("expected %d entries, got %d", workers*perWorker, len(entries))
+		}
+	})
+}
@@ -0,0 +1,346 @@
+package repotest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/services"
+)
+
+type NotificationFactory func(t *testing.T) services.NotificationRepositoryInterface
+
+func newNotification(recipient string, at time.Time) models.Notification {
+	return models.Notification{Title: "n", Time: at, TimeUTC: at, Recipient: recipient}
+}
+
+func assertNotificationNotFound(t *testing.T, err error) {
+	t.Helper()
+
+	var notFound *errs.NotificationNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("expected NotificationNotFoundError, got %v", err)
+	}
+}
+
+func notificationIDs(notifications []models.Notification) []int {
+	ids := make([]int, len(notifications))
+	for i, n := range notifications {
+		ids[i] = n.ID
+	}
+
+	return ids
+}
+
+// TestNotificationRepository checks that a notification repository behaves
+// like the in-memory one. newRepo must return an empty repository on every
+// call.
+func TestNotificationRepository(t *testing.T, newRepo NotificationFactory) {
+	t.Run("CreateAssignsIDs", func(t *testing.T) {
+		r := newRepo(t)
+		previous := 0
+		for i := 0; i < 5; i++ {
+			n := newNotification("alice", base)
+			n.ID = 99
+			n.Version = 4
+
+			created, err := r.Create(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if created.ID <= previous {
+				t.Fatalf("IDs must be unique and increasing, got %d after %d", created.ID, previous)
+			}
+			if created.Version != 1 {
+				t.Errorf("new notifications start at version 1, got %d", created.Version)
+			}
+
+			previous = created.ID
+		}
+	})
+
+	t.Run("IDsNotReused", func(t *testing.T) {
+		r := newRepo(t)
+		r.Create(newNotification("alice", base))
+		last, _ := r.Create(newNotification("alice", base))
+
+		r.Delete(last.ID, 0)
+
+		next, _ := r.Create(newNotification("alice", base))
+		if next.ID <= last.ID {
+			t.Errorf("ID %d of a deleted notification was reused", next.ID)
+		}
+	})
+
+	t.Run("ReadsAreCopies", func(t *testing.T) {
+		r := newRepo(t)
+		snoozed := base.Add(time.Hour)
+		n := newNotification("alice", base)
+		n.SnoozedUntil = &snoozed
+		created, _ := r.Create(n)
+		snoozed = base.Add(time.Hour * 2)
+
+		got, _ := r.Get(created.ID)
+		*got.SnoozedUntil = base.Add(time.Hour * 3)
+		all, _ := r.GetAll()
+		all[0].Title = "changed"
+		mine, _ := r.GetByRecipient("alice")
+		mine[0].Read = true
+
+		stored, _ := r.Get(created.ID)
+		if stored.Title != "n" || stored.Read || !stored.SnoozedUntil.Equal(base.Add(time.Hour)) {
+			t.Errorf("stored notification was changed through a returned value: %+v", stored)
+		}
+	})
+
+	t.Run("GetRoundTrip", func(t *testing.T) {
+		r := newRepo(t)
+		snoozed := base.Add(time.Hour)
+		n := newNotification("alice", base)
+		n.Description = "Standup"
+		n.EventID = 3
+		n.ReminderMinutes = 10
+		n.Read = true
+		n.SnoozedUntil = &snoozed
+
+		created, _ := r.Create(n)
+		got, err := r.Get(created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Description != "Standup" || got.Recipient != "alice" || got.EventID != 3 || got.ReminderMinutes != 10 ||
+			!got.Read || got.SnoozedUntil == nil || !got.SnoozedUntil.Equal(snoozed) || !got.TimeUTC.Equal(base) {
+			t.Errorf("stored notification differs: %+v", got)
+		}
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.Get(42)
+		assertNotificationNotFound(t, err)
+	})
+
+	t.Run("GetAllOrderedByID", func(t *testing.T) {
+		r := newRepo(t)
+		if notifications, err := r.GetAll(); err != nil || len(notifications) != 0 {
+			t.Fatalf("empty repository should return no notifications, got %v %v", notifications, err)
+		}
+
+		for i := 3; i > 0; i-- {
+			r.Create(newNotification("alice", base.Add(time.Hour*time.Duration(i))))
+		}
+
+		notifications, _ := r.GetAll()
+		ids := notificationIDs(notifications)
+		if len(ids) != 3 || ids[0] >= ids[1] || ids[1] >= ids[2] {
+			t.Errorf("expected 3 notifications ordered by ID, got %v", ids)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := newRepo(t)
+		created, _ := r.Create(newNotification("alice", base))
+
+		change := created
+		change.Read = true
+		updated, err := r.Update(created.ID, change)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if updated.Version != 2 || !updated.Read {
+			t.Errorf("unexpected update result %+v", updated)
+		}
+		if got, _ := r.Get(created.ID); !got.Read || got.Version != 2 {
+			t.Errorf("update not stored: %+v", got)
+		}
+
+		stale := created
+		current, err := r.Update(created.ID, stale)
+		assertVersionMismatch(t, err)
+		if current.Version != 2 {
+			t.Errorf("mismatch should return the stored notification, got %+v", current)
+		}
+
+		_, err = r.Update(42, newNotification("alice", base))
+		assertNotificationNotFound(t, err)
+	})
+
+	t.Run("Indexes", func(t *testing.T) {
+		r := newRepo(t)
+		first := newNotification("alice", base)
+		first.EventID = 1
+		first, _ = r.Create(first)
+		second, _ := r.Create(newNotification("bob", base))
+		third := newNotification("alice", base)
+		third.EventID = 2
+		third, _ = r.Create(third)
+
+		byRecipient := func(recipient string) []int {
+			notifications, err := r.GetByRecipient(recipient)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return notificationIDs(notifications)
+		}
+		byEvent := func(eventID int) []int {
+			notifications, err := r.GetByEvent(eventID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return notificationIDs(notifications)
+		}
+
+		if ids := byRecipient("alice"); len(ids) != 2 || ids[0] != first.ID || ids[1] != third.ID {
+			t.Errorf("unexpected notifications for alice: %v", ids)
+		}
+		if ids := byRecipient("carol"); len(ids) != 0 {
+			t.Errorf("unknown recipient should have no notifications, got %v", ids)
+		}
+		if ids := byEvent(1); len(ids) != 1 || ids[0] != first.ID {
+			t.Errorf("unexpected notifications for event 1: %v", ids)
+		}
+
+		first.Recipient = "bob"
+		first.EventID = 2
+		r.Update(first.ID, first)
+		if ids := byRecipient("bob"); len(ids) != 2 || ids[0] != first.ID || ids[1] != second.ID {
+			t.Errorf("updated recipient not reflected: %v", ids)
+		}
+		if ids := byEvent(1); len(ids) != 0 {
+			t.Errorf("updated event not reflected: %v", ids)
+		}
+		if ids := byEvent(2); len(ids) != 2 {
+			t.Errorf("expected two notifications for event 2, got %v", ids)
+		}
+
+		r.Delete(third.ID, 0)
+		if ids := byRecipient("alice"); len(ids) != 0 {
+			t.Errorf("deleted notification still indexed: %v", ids)
+		}
+	})
+
+	t.Run("GetBetweenOrderedByTime", func(t *testing.T) {
+		r := newRepo(t)
+		offsets := []int{5, -3, 0, 2, 9, 2}
+		for _, h := range offsets {
+			r.Create(newNotification("alice", base.Add(time.Hour*time.Duration(h))))
+		}
+
+		notifications, err := r.GetBetween(base, base.Add(time.Hour*9))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(notifications) != 4 {
+			t.Fatalf("expected 4 notifications in [0h, 9h), got %d", len(notifications))
+		}
+		for i := 1; i < len(notifications); i++ {
+			prev, cur := notifications[i-1], notifications[i]
+			if cur.TimeUTC.Before(prev.TimeUTC) || (cur.TimeUTC.Equal(prev.TimeUTC) && cur.ID < prev.ID) {
+				t.Errorf("notifications not ordered by time then ID: %v", notificationIDs(notifications))
+			}
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := newRepo(t)
+		created, _ := r.Create(newNotification("alice", base))
+
+		_, err := r.Delete(created.ID, 5)
+		assertVersionMismatch(t, err)
+
+		deleted, err := r.Delete(created.ID, created.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted.ID != created.ID {
+			t.Errorf("delete should return the removed notification, got %+v", deleted)
+		}
+
+		_, err = r.Get(created.ID)
+		assertNotificationNotFound(t, err)
+		_, err = r.Delete(created.ID, 0)
+		assertNotificationNotFound(t, err)
+	})
+
+	t.Run("DeleteManyIsAtomic", func(t *testing.T) {
+		r := newRepo(t)
+		first, _ := r.Create(newNotification("alice", base))
+		second, _ := r.Create(newNotification("alice", base))
+		third, _ := r.Create(newNotification("alice", base))
+
+		_, err := r.DeleteMany([]int{first.ID, 42})
+		assertNotificationNotFound(t, err)
+		if _, err = r.Get(first.ID); err != nil {
+			t.Errorf("failed bulk delete must not remove anything: %v", err)
+		}
+
+		deleted, err := r.DeleteMany([]int{first.ID, third.ID, first.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deleted) != 2 {
+			t.Errorf("expected 2 deleted notifications, got %d", len(deleted))
+		}
+
+		if remaining, _ := r.GetAll(); len(remaining) != 1 || remaining[0].ID != second.ID {
+			t.Errorf("unexpected remaining notifications %v", notificationIDs(remaining))
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		r := newRepo(t)
+		const workers, perWorker = 8, 10
+
+		var wg sync.WaitGroup
+		ids := make(chan int, workers*perWorker)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					n, err := r.Create(newNotification("alice", base))
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					ids <- n.ID
+
+					n.Read = true
+					if _, err = r.Update(n.ID, n); err != nil {
+						t.Error(err)
+					}
+					r.GetByRecipient("alice")
+					r.GetAll()
+				}
+			}()
+		}
+		wg.Wait()
+		close(ids)
+
+		seen := make(map[int]bool)
+		for id := range ids {
+			if seen[id] {
+				t.Errorf("ID %d assigned twice", id)
+			}
+			seen[id] = true
+		}
+
+		notifications, _ := r.GetByRecipient("alice")
+		if len(notifications) != workers*perWorker {
+			t.Errorf("expected %d notifications, got %d", workers*perWorker, len(notifications))
+		}
+		for _, n := range notifications {
+			if !n.Read || n.Version != 2 {
+				t.Errorf("lost update on notification %d: %+v", n.ID, n)
+			}
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package repotest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"workshop2/internal/app/services"
+)
+
+// TransactorFactory returns a transactor together with the repositories
+// it writes to, all empty.
+type TransactorFactory func(t *testing.T) (services.TransactorInterface, services.Store)
+
+// TestTransactor checks that a transaction spanning the event,
+// notification and user repositories commits or rolls back as a whole.
+func TestTransactor(t *testing.T, newTransactor TransactorFactory) {
+	t.Run("Commit", func(t *testing.T) {
+		transactor, store := newTransactor(t)
+
+		var eventID int
+		err := transactor.Transaction(func(tx services.Store) error {
+			e, err := tx.Events.Create(newEvent("e", base))
+			if err != nil {
+				return err
+			}
+			eventID = e.ID
+
+			n := newNotification("alice", base)
+			n.EventID = e.ID
+			if _, err = tx.Notifications.Create(n); err != nil {
+				return err
+			}
+
+			if _, err = tx.Users.Create(newUser("alice")); err != nil {
+				return err
+			}
+
+			if _, err = tx.Events.Get(e.ID); err != nil {
+				t.Errorf("writes should be visible inside the transaction: %v", err)
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = store.Events.Get(eventID); err != nil {
+			t.Errorf("committed event missing: %v", err)
+		}
+		if notifications, _ := store.Notifications.GetByEvent(eventID); len(notifications) != 1 {
+			t.Errorf("committed notification missing")
+		}
+		if _, err = store.Users.Get("alice"); err != nil {
+			t.Errorf("committed user missing: %v", err)
+		}
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		transactor, store := newTransactor(t)
+		kept, _ := store.Events.Create(newEvent("kept", base))
+		failure := errors.New("failure")
+
+		err := transactor.Transaction(func(tx services.Store) error {
+			tx.Events.Create(newEvent("e", base))
+			tx.Notifications.Create(newNotification("alice", base))
+			tx.Users.Create(newUser("alice"))
+
+			moved := kept
+			moved.Title = "changed"
+			tx.Events.Update(kept.ID, moved)
+
+			return failure
+		})
+		if err != failure {
+			t.Fatalf("expected the error of fn, got %v", err)
+		}
+
+		if events, _ := store.Events.GetAll(); len(events) != 1 || events[0].Title != "kept" || events[0].Version != 1 {
+			t.Errorf("rolled back event writes persisted: %+v", events)
+		}
+		if events, _ := store.Events.GetByOwner("alice"); len(events) != 1 || events[0].ID != kept.ID {
+			t.Errorf("rolled back event left in the owner index: %+v", events)
+		}
+		if notifications, _ := store.Notifications.GetAll(); len(notifications) != 0 {
+			t.Errorf("rolled back notification persisted")
+		}
+		_, err = store.Users.Get("alice")
+		assertUserNotFound(t, err)
+	})
+
+	t.Run("ConcurrentWriters", func(t *testing.T) {
+		transactor, store := newTransactor(t)
+		const workers = 8
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				transactor.Transaction(func(tx services.Store) error {
+					if _, err := tx.Events.Create(newEvent("tx", base)); err != nil {
+						return err
+					}
+
+					_, err := tx.Notifications.Create(newNotification("alice", base))
+					return err
+				})
+			}()
+			go func() {
+				defer wg.Done()
+				store.Events.Create(newEvent("direct", base))
+			}()
+		}
+		wg.Wait()
+
+		if events, _ := store.Events.GetAll(); len(events) != workers*2 {
+			t.Errorf("expected %d events, got %d", workers*2, len(events))
+		}
+		if notifications, _ := store.Notifications.GetAll(); len(notifications) != workers {
+			t.Errorf("expected %d notifications, got %d", workers, len(notifications))
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/services"
+)
+
+type UserFactory func(t *testing.T) services.UserRepositoryInterface
+
+func newUser(username string) models.User {
+	return models.User{Username: username, Password: "passw0rd!", Timezone: "Europe/Kiev"}
+}
+
+func assertUserNotFound(t *testing.T, err error) {
+	t.Helper()
+
+	var notFound *errs.UserNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("expected UserNotFoundError, got %v", err)
+	}
+}
+
+// TestUserRepository checks that a user repository behaves like the
+// in-memory one. newRepo must return an empty repository on every call.
+func TestUserRepository(t *testing.T, newRepo UserFactory) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepo(t)
+		user := newUser("alice")
+		user.Email = "alice@example.com"
+		user.EmailVerificationToken = "token"
+		user.Preferences.Digest = models.DigestDaily
+
+		if _, err := r.Create(user); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.Get("alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Password != user.Password || got.Email != user.Email || got.EmailVerificationToken != "token" || got.Preferences.Digest != models.DigestDaily {
+			t.Errorf("stored user differs: %+v", got)
+		}
+	})
+
+	t.Run("ReadsAreCopies", func(t *testing.T) {
+		r := newRepo(t)
+		user := newUser("alice")
+		user.Preferences.Channels = map[string][]string{models.NotificationTypeReminder: {"push"}}
+		user.Preferences.QuietHours = &models.QuietHours{Start: "22:00", End: "07:00"}
+		r.Create(user)
+		user.Preferences.Channels[models.NotificationTypeReminder][0] = "email"
+
+		got, _ := r.Get("alice")
+		got.Preferences.QuietHours.Start = "20:00"
+		got.Preferences.Channels[models.NotificationTypeGeneral] = []string{"email"}
+		all, _ := r.GetAll()
+		all[0].Preferences.Channels[models.NotificationTypeReminder][0] = "email"
+
+		stored, _ := r.Get("alice")
+		if stored.Preferences.QuietHours.Start != "22:00" || len(stored.Preferences.Channels) != 1 ||
+			stored.Preferences.Channels[models.NotificationTypeReminder][0] != "push" {
+			t.Errorf("stored user was changed through a returned value: %+v", stored.Preferences)
+		}
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.Get("nobody")
+		assertUserNotFound(t, err)
+	})
+
+	t.Run("DuplicateUsername", func(t *testing.T) {
+		r := newRepo(t)
+		r.Create(newUser("alice"))
+
+		duplicate := newUser("alice")
+		duplicate.Password = "different1"
+		_, err := r.Create(duplicate)
+
+		var exists *errs.UserAlreadyExistsError
+		if !errors.As(err, &exists) {
+			t.Errorf("expected UserAlreadyExistsError, got %v", err)
+		}
+		if got, _ := r.Get("alice"); got.Password != "passw0rd!" {
+			t.Errorf("duplicate must not overwrite the existing user")
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.Create(models.User{Username: "x"})
+
+		var invalid *errs.UserValidationError
+		if !errors.As(err, &invalid) {
+			t.Errorf("expected UserValidationError, got %v", err)
+		}
+		if users, _ := r.GetAll(); len(users) != 0 {
+			t.Errorf("invalid user must not be stored")
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := newRepo(t)
+		r.Create(newUser("alice"))
+
+		user := newUser("alice")
+		user.Timezone = "UTC"
+		if err := r.Update(user); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := r.Get("alice"); got.Timezone != "UTC" {
+			t.Errorf("update not stored: %+v", got)
+		}
+
+		assertUserNotFound(t, r.Update(newUser("bob")))
+	})
+
+	t.Run("GetAll", func(t *testing.T) {
+		r := newRepo(t)
+		r.Create(newUser("alice"))
+		r.Create(newUser("bob"))
+
+		users, err := r.GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 2 {
+			t.Errorf("expected 2 users, got %d", len(users))
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		r := newRepo(t)
+		const workers = 8
+
+		var wg sync.WaitGroup
+		created := make(chan string, workers*2)
+		for w := 0; w < workers*2; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				username := fmt.Sprintf("user%d", w%workers)
+				if _, err := r.Create(newUser(username)); err == nil {
+					created <- username
+				}
+				r.Get(username)
+				r.GetAll()
+			}(w)
+		}
+		wg.Wait()
+		close(created)
+
+		seen := make(map[string]bool)
+		for username := range created {
+			if seen[username] {
+				t.Errorf("%s created twice", username)
+			}
+			seen[username] = true
+		}
+
+		if users, _ := r.GetAll(); len(users) != workers || len(seen) != workers {
+			t.Errorf("expected %d users, got %d", workers, len(users))
+		}
+	})
+}
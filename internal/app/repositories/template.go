@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"sync"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+)
+
+type TemplateRepository struct {
+	Templates []models.NotificationTemplate
+	lastID    int
+	sync.RWMutex
+}
+
+func (r *TemplateRepository) GetAll() ([]models.NotificationTemplate, error) {
+	r.RLock()
+	defer r.RUnlock()
+	templates := make([]models.NotificationTemplate, len(r.Templates))
+	copy(templates, r.Templates)
+
+	return templates, nil
+}
+
+func (r *TemplateRepository) Get(id int) (models.NotificationTemplate, error) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, t := range r.Templates {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+
+	return models.NotificationTemplate{}, errs.NewTemplateNotFoundError()
+}
+
+func (r *TemplateRepository) Create(template models.NotificationTemplate) (models.NotificationTemplate, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastID++
+	template.ID = r.lastID
+
+	r.Templates = append(r.Templates, template)
+
+	return template, nil
+}
+
+func (r *TemplateRepository) Update(template models.NotificationTemplate) (models.NotificationTemplate, error) {
+	r.Lock()
+	defer r.Unlock()
+	for i, t := range r.Templates {
+		if t.ID == template.ID {
+			r.Templates[i] = template
+
+			return template, nil
+		}
+	}
+
+	return template, errs.NewTemplateNotFoundError()
+}
+
+func (r *TemplateRepository) Delete(id int) error {
+	r.Lock()
+	defer r.Unlock()
+	for i, t := range r.Templates {
+		if t.ID == id {
+			r.Templates = append(r.Templates[:i], r.Templates[i+1:]...)
+
+			return nil
+		}
+	}
+
+	return errs.NewTemplateNotFoundError()
+}
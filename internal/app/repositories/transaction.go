@@ -0,0 +1,294 @@
+package repositories
+
+import (
+	"sync"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+// MemoryStore groups the in-memory repositories that can be written to in
+// one transaction. Nil repositories are left out of it.
+type MemoryStore struct {
+	Events        *EventRepository
+	Notifications *NotificationRepository
+	Users         *UserRepository
+
+	transactions sync.Mutex
+}
+
+// Transaction hands fn the repositories of the store and commits what fn
+// wrote through them only when it returns nil. A repository is locked and
+// copied the first time fn uses it, so writers to it wait until the
+// transaction ends while the repositories fn doesn't touch stay available.
+//
+// Transactions run one at a time; that way the order in which they lock
+// repositories can't deadlock them.
+func (s *MemoryStore) Transaction(fn func(tx MemoryTx) error) error {
+	s.transactions.Lock()
+	defer s.transactions.Unlock()
+
+	state := &memoryTx{}
+	defer state.unlock()
+
+	var tx MemoryTx
+	if s.Events != nil {
+		tx.Events = &EventTx{source: s.Events, tx: state}
+	}
+	if s.Notifications != nil {
+		tx.Notifications = &NotificationTx{source: s.Notifications, tx: state}
+	}
+	if s.Users != nil {
+		tx.Users = &UserTx{source: s.Users, tx: state}
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	for _, r := range state.touched {
+		r.commit()
+	}
+
+	return nil
+}
+
+// MemoryTx holds the repositories of a MemoryStore transaction. It must
+// only be used by the goroutine running the transaction.
+type MemoryTx struct {
+	Events        *EventTx
+	Notifications *NotificationTx
+	Users         *UserTx
+}
+
+type txRepository interface {
+	commit()
+	unlock()
+}
+
+type memoryTx struct {
+	touched []txRepository
+}
+
+func (t *memoryTx) unlock() {
+	for _, r := range t.touched {
+		r.unlock()
+	}
+}
+
+// EventTx is the EventRepository of a transaction.
+type EventTx struct {
+	source *EventRepository
+	copy   *EventRepository
+	tx     *memoryTx
+}
+
+func (t *EventTx) repository() *EventRepository {
+	if t.copy == nil {
+		t.source.Lock()
+		t.copy = t.source.snapshot()
+		t.tx.touched = append(t.tx.touched, t)
+	}
+
+	return t.copy
+}
+
+func (t *EventTx) commit() { t.source.restore(t.copy) }
+func (t *EventTx) unlock() { t.source.Unlock() }
+
+func (t *EventTx) GetAll() ([]models.Event, error) {
+	return t.repository().GetAll()
+}
+
+func (t *EventTx) GetBetween(from time.Time, to time.Time) ([]models.Event, error) {
+	return t.repository().GetBetween(from, to)
+}
+
+func (t *EventTx) GetByOwner(owner string) ([]models.Event, error) {
+	return t.repository().GetByOwner(owner)
+}
+
+func (t *EventTx) Get(id int) (models.Event, error) {
+	return t.repository().Get(id)
+}
+
+func (t *EventTx) Create(event models.Event) (models.Event, error) {
+	return t.repository().Create(event)
+}
+
+func (t *EventTx) Update(id int, newEvent models.Event) (models.Event, error) {
+	return t.repository().Update(id, newEvent)
+}
+
+func (t *EventTx) Trash(id int, version int, username string, deletedAt time.Time) (models.Event, error) {
+	return t.repository().Trash(id, version, username, deletedAt)
+}
+
+func (t *EventTx) GetTrash(username string) ([]models.Event, error) {
+	return t.repository().GetTrash(username)
+}
+
+func (t *EventTx) Restore(id int, username string) (models.Event, error) {
+	return t.repository().Restore(id, username)
+}
+
+func (t *EventTx) Purge(id int, username string) error {
+	return t.repository().Purge(id, username)
+}
+
+func (t *EventTx) PurgeDeletedBefore(limit time.Time) ([]models.Event, error) {
+	return t.repository().PurgeDeletedBefore(limit)
+}
+
+// NotificationTx is the NotificationRepository of a transaction.
+type NotificationTx struct {
+	source *NotificationRepository
+	copy   *NotificationRepository
+	tx     *memoryTx
+}
+
+func (t *NotificationTx) repository() *NotificationRepository {
+	if t.copy == nil {
+		t.source.Lock()
+		t.copy = t.source.snapshot()
+		t.tx.touched = append(t.tx.touched, t)
+	}
+
+	return t.copy
+}
+
+func (t *NotificationTx) commit() { t.source.restore(t.copy) }
+func (t *NotificationTx) unlock() { t.source.Unlock() }
+
+func (t *NotificationTx) GetAll() ([]models.Notification, error) {
+	return t.repository().GetAll()
+}
+
+func (t *NotificationTx) GetBetween(from time.Time, to time.Time) ([]models.Notification, error) {
+	return t.repository().GetBetween(from, to)
+}
+
+func (t *NotificationTx) GetByRecipient(recipient string) ([]models.Notification, error) {
+	return t.repository().GetByRecipient(recipient)
+}
+
+func (t *NotificationTx) GetByEvent(eventID int) ([]models.Notification, error) {
+	return t.repository().GetByEvent(eventID)
+}
+
+func (t *NotificationTx) Get(id int) (models.Notification, error) {
+	return t.repository().Get(id)
+}
+
+func (t *NotificationTx) Create(notification models.Notification) (models.Notification, error) {
+	return t.repository().Create(notification)
+}
+
+func (t *NotificationTx) Update(id int, newNotification models.Notification) (models.Notification, error) {
+	return t.repository().Update(id, newNotification)
+}
+
+func (t *NotificationTx) Delete(id int, version int) (models.Notification, error) {
+	return t.repository().Delete(id, version)
+}
+
+func (t *NotificationTx) DeleteMany(ids []int) ([]models.Notification, error) {
+	return t.repository().DeleteMany(ids)
+}
+
+// UserTx is the UserRepository of a transaction.
+type UserTx struct {
+	source *UserRepository
+	copy   *UserRepository
+	tx     *memoryTx
+}
+
+func (t *UserTx) repository() *UserRepository {
+	if t.copy == nil {
+		t.source.Lock()
+		t.copy = t.source.snapshot()
+		t.tx.touched = append(t.tx.touched, t)
+	}
+
+	return t.copy
+}
+
+func (t *UserTx) commit() { t.source.restore(t.copy) }
+func (t *UserTx) unlock() { t.source.Unlock() }
+
+func (t *UserTx) GetAll() ([]models.User, error) {
+	return t.repository().GetAll()
+}
+
+func (t *UserTx) Get(username string) (models.User, error) {
+	return t.repository().Get(username)
+}
+
+func (t *UserTx) Create(user models.User) (models.User, error) {
+	return t.repository().Create(user)
+}
+
+func (t *UserTx) Update(user models.User) error {
+	return t.repository().Update(user)
+}
+
+// The snapshots share stored records with the original. That is safe
+// because records are replaced rather than modified in place.
+
+func (r *EventRepository) snapshot() *EventRepository {
+	events := make(map[int]models.Event, len(r.events))
+	for id, e := range r.events {
+		events[id] = e
+	}
+
+	byOwner := make(map[string]idList, len(r.byOwner))
+	for owner, ids := range r.byOwner {
+		byOwner[owner] = append(idList(nil), ids...)
+	}
+
+	return &EventRepository{
+		events:  events,
+		ids:     append(idList(nil), r.ids...),
+		byTime:  append(timeIndex(nil), r.byTime...),
+		byOwner: byOwner,
+		lastID:  r.lastID,
+	}
+}
+
+func (r *EventRepository) restore(from *EventRepository) {
+	r.events, r.ids, r.byTime, r.byOwner, r.lastID = from.events, from.ids, from.byTime, from.byOwner, from.lastID
+}
+
+func (r *NotificationRepository) snapshot() *NotificationRepository {
+	notifications := make(map[int]models.Notification, len(r.notifications))
+	for id, n := range r.notifications {
+		notifications[id] = n
+	}
+
+	return &NotificationRepository{
+		notifications: notifications,
+		ids:           append(idList(nil), r.ids...),
+		byTime:        append(timeIndex(nil), r.byTime...),
+		lastID:        r.lastID,
+	}
+}
+
+func (r *NotificationRepository) restore(from *NotificationRepository) {
+	r.notifications, r.ids, r.byTime, r.lastID = from.notifications, from.ids, from.byTime, from.lastID
+}
+
+func (r *UserRepository) snapshot() *UserRepository {
+	users := make(map[string]models.User, len(r.users))
+	for username, u := range r.users {
+		users[username] = u
+	}
+
+	return &UserRepository{
+		users:     users,
+		usernames: append([]string(nil), r.usernames...),
+		Validator: r.Validator,
+	}
+}
+
+func (r *UserRepository) restore(from *UserRepository) {
+	r.users, r.usernames = from.users, from.usernames
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/utils"
+)
+
+func TestMemoryTransactionLocksTouchedRepositories(t *testing.T) {
+	store := &MemoryStore{
+		Events:        &EventRepository{},
+		Notifications: &NotificationRepository{},
+		Users:         &UserRepository{Validator: utils.NewValidator()},
+	}
+
+	inside := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- store.Transaction(func(tx MemoryTx) error {
+			if _, err := tx.Events.Create(models.Event{Title: "tx"}); err != nil {
+				return err
+			}
+			close(inside)
+			<-release
+
+			return nil
+		})
+	}()
+	<-inside
+
+	// Untouched repositories stay available while the transaction runs.
+	created := make(chan error)
+	go func() {
+		_, err := store.Notifications.Create(models.Notification{Title: "direct"})
+		created <- err
+	}()
+	select {
+	case err := <-created:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a write to an untouched repository waited for the transaction")
+	}
+
+	// Writers to a touched repository wait for the commit.
+	go func() {
+		_, err := store.Events.Create(models.Event{Title: "direct"})
+		created <- err
+	}()
+	select {
+	case <-created:
+		t.Fatal("a write to a touched repository did not wait for the transaction")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-created; err != nil {
+		t.Fatal(err)
+	}
+
+	if events, _ := store.Events.GetAll(); len(events) != 2 {
+		t.Errorf("expected the committed and the waiting event, got %+v", events)
+	}
+}
+
+func TestMemoryTransactionRollsBackTouchedRepositories(t *testing.T) {
+	store := &MemoryStore{
+		Events:        &EventRepository{},
+		Notifications: &NotificationRepository{},
+	}
+	kept, _ := store.Events.Create(models.Event{Title: "kept"})
+
+	failed := errors.New("failed")
+	err := store.Transaction(func(tx MemoryTx) error {
+		if _, err := tx.Events.Update(kept.ID, models.Event{Title: "changed"}); err != nil {
+			return err
+		}
+		if _, err := tx.Notifications.Create(models.Notification{Title: "new"}); err != nil {
+			return err
+		}
+
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("expected the error of fn, got %v", err)
+	}
+
+	if event, _ := store.Events.Get(kept.ID); event.Title != "kept" || event.Version != kept.Version {
+		t.Errorf("expected the event to be unchanged, got %+v", event)
+	}
+	if notifications, _ := store.Notifications.GetAll(); len(notifications) != 0 {
+		t.Errorf("expected no notifications, got %+v", notifications)
+	}
+
+	// Both repositories are unlocked again.
+	if _, err = store.Events.Create(models.Event{Title: "after"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Notifications.Create(models.Notification{Title: "after"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,19 +7,30 @@ import (
 	"workshop2/internal/app/utils"
 )
 
+// UserRepository keeps users in memory. Only Validator has to be set.
 type UserRepository struct {
-	Users []models.User
+	users     map[string]models.User
+	usernames []string
 	sync.RWMutex
 	Validator utils.ValidatorInterface
 }
 
+func (r *UserRepository) GetAll() ([]models.User, error) {
+	r.RLock()
+	defer r.RUnlock()
+	users := make([]models.User, 0, len(r.usernames))
+	for _, username := range r.usernames {
+		users = append(users, r.users[username].Clone())
+	}
+
+	return users, nil
+}
+
 func (r *UserRepository) Get(username string) (models.User, error) {
 	r.RLock()
 	defer r.RUnlock()
-	for _, u := range r.Users {
-		if u.Username == username {
-			return u, nil
-		}
+	if u, ok := r.users[username]; ok {
+		return u.Clone(), nil
 	}
 
 	return models.User{}, errs.NewUserNotFoundError()
@@ -29,19 +40,21 @@ func (r *UserRepository) Create(user models.User) (models.User, error) {
 	err := r.Validator.Struct(user)
 
 	if err != nil {
-		return user, errs.NewUserValidationError(err.Error())
+		return user, errs.NewUserValidationError(err)
 	}
 
 	r.Lock()
 	defer r.Unlock()
+	if r.users == nil {
+		r.users = make(map[string]models.User)
+	}
 
-	for _, u := range r.Users {
-		if u.Username == user.Username {
-			return user, errs.NewUserAlreadyExistsError()
-		}
+	if _, ok := r.users[user.Username]; ok {
+		return user, errs.NewUserAlreadyExistsError()
 	}
 
-	r.Users = append(r.Users, user)
+	r.users[user.Username] = user.Clone()
+	r.usernames = append(r.usernames, user.Username)
 
 	return user, nil
 }
@@ -50,18 +63,16 @@ func (r *UserRepository) Update(user models.User) error {
 	err := r.Validator.Struct(user)
 
 	if err != nil {
-		return errs.NewUserValidationError(err.Error())
+		return errs.NewUserValidationError(err)
 	}
 
 	r.Lock()
 	defer r.Unlock()
-	for i, u := range r.Users {
-		if u.Username == user.Username {
-			r.Users[i] = user
-
-			return nil
-		}
+	if _, ok := r.users[user.Username]; !ok {
+		return errs.NewUserNotFoundError()
 	}
 
-	return errs.NewUserNotFoundError()
+	r.users[user.Username] = user.Clone()
+
+	return nil
 }
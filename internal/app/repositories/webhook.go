@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"sync"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+)
+
+const maxDeliveriesPerWebhook = 100
+
+type WebhookRepository struct {
+	Webhooks     []models.Webhook
+	Deliveries   []models.WebhookDelivery
+	lastID       int
+	lastDelivery int
+	sync.RWMutex
+}
+
+func (r *WebhookRepository) GetAll() ([]models.Webhook, error) {
+	r.RLock()
+	defer r.RUnlock()
+	webhooks := make([]models.Webhook, len(r.Webhooks))
+	copy(webhooks, r.Webhooks)
+
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) Get(id int) (models.Webhook, error) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, w := range r.Webhooks {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+
+	return models.Webhook{}, errs.NewWebhookNotFoundError()
+}
+
+func (r *WebhookRepository) Create(webhook models.Webhook) (models.Webhook, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastID++
+	webhook.ID = r.lastID
+
+	r.Webhooks = append(r.Webhooks, webhook)
+
+	return webhook, nil
+}
+
+func (r *WebhookRepository) Update(webhook models.Webhook) (models.Webhook, error) {
+	r.Lock()
+	defer r.Unlock()
+	for i, w := range r.Webhooks {
+		if w.ID == webhook.ID {
+			r.Webhooks[i] = webhook
+
+			return webhook, nil
+		}
+	}
+
+	return webhook, errs.NewWebhookNotFoundError()
+}
+
+func (r *WebhookRepository) Delete(id int) error {
+	r.Lock()
+	defer r.Unlock()
+	for i, w := range r.Webhooks {
+		if w.ID == id {
+			r.Webhooks = append(r.Webhooks[:i], r.Webhooks[i+1:]...)
+
+			deliveries := r.Deliveries[:0]
+			for _, d := range r.Deliveries {
+				if d.WebhookID != id {
+					deliveries = append(deliveries, d)
+				}
+			}
+			r.Deliveries = deliveries
+
+			return nil
+		}
+	}
+
+	return errs.NewWebhookNotFoundError()
+}
+
+// RecordDeliveryResult resets the failure counter on success, or increments
+// it and disables the webhook once it reaches disableAfter.
+func (r *WebhookRepository) RecordDeliveryResult(id int, success bool, disableAfter int) (models.Webhook, error) {
+	r.Lock()
+	defer r.Unlock()
+	for i, w := range r.Webhooks {
+		if w.ID == id {
+			if success {
+				w.ConsecutiveFailures = 0
+			} else {
+				w.ConsecutiveFailures++
+				if disableAfter > 0 && w.ConsecutiveFailures >= disableAfter {
+					w.Active = false
+				}
+			}
+			r.Webhooks[i] = w
+
+			return w, nil
+		}
+	}
+
+	return models.Webhook{}, errs.NewWebhookNotFoundError()
+}
+
+func (r *WebhookRepository) AddDelivery(delivery models.WebhookDelivery) (models.WebhookDelivery, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastDelivery++
+	delivery.ID = r.lastDelivery
+
+	count := 0
+	for _, d := range r.Deliveries {
+		if d.WebhookID == delivery.WebhookID {
+			count++
+		}
+	}
+
+	if count >= maxDeliveriesPerWebhook {
+		for i, d := range r.Deliveries {
+			if d.WebhookID == delivery.WebhookID {
+				r.Deliveries = append(r.Deliveries[:i], r.Deliveries[i+1:]...)
+				break
+			}
+		}
+	}
+
+	r.Deliveries = append(r.Deliveries, delivery)
+
+	return delivery, nil
+}
+
+func (r *WebhookRepository) GetDeliveries(webhookID int) ([]models.WebhookDelivery, error) {
+	r.RLock()
+	defer r.RUnlock()
+	deliveries := make([]models.WebhookDelivery, 0)
+	for _, d := range r.Deliveries {
+		if d.WebhookID == webhookID {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	return deliveries, nil
+}
@@ -18,6 +18,7 @@ type Claims struct {
 
 type AuthService struct {
 	Users                UserRepositoryInterface
+	Transactor           TransactorInterface
 	Validator            utils.ValidatorInterface
 	tokenLifetime        time.Duration
 	refreshTokenLifetime time.Duration
@@ -41,12 +42,12 @@ func (s *AuthService) SignUp(request models.SignUp) ([]models.Token, error) {
 	err := s.Validator.Struct(request)
 
 	if err != nil {
-		return tokens, errs.NewAuthValidationError(err.Error())
+		return tokens, errs.NewAuthValidationError(err)
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(request.RepeatPassword), bcrypt.DefaultCost)
 	if err != nil {
-		return tokens, errs.NewAuthValidationError(err.Error())
+		return tokens, errs.NewAuthValidationError(err)
 	}
 
 	user := models.User{
@@ -55,17 +56,23 @@ func (s *AuthService) SignUp(request models.SignUp) ([]models.Token, error) {
 		Timezone: request.Timezone,
 	}
 
-	tokens, err = s.GenerateTokens(user.Username, user.Timezone)
-	if err != nil {
-		return tokens, err
+	signUp := func(users UserRepositoryInterface) error {
+		user, err = users.Create(user)
+		if err != nil {
+			return err
+		}
+
+		tokens, err = s.GenerateTokens(user.Username, user.Timezone)
+		return err
 	}
 
-	user, err = s.Users.Create(user)
-	if err != nil {
-		return tokens, err
+	if s.Transactor == nil {
+		return tokens, signUp(s.Users)
 	}
 
-	return tokens, nil
+	return tokens, s.Transactor.Transaction(func(store Store) error {
+		return signUp(store.Users)
+	})
 }
 
 func (s *AuthService) SignIn(request models.SignIn) ([]models.Token, error) {
@@ -73,7 +80,7 @@ func (s *AuthService) SignIn(request models.SignIn) ([]models.Token, error) {
 	err := s.Validator.Struct(request)
 
 	if err != nil {
-		return tokens, errs.NewAuthValidationError(err.Error())
+		return tokens, errs.NewAuthValidationError(err)
 	}
 
 	user, err := s.Users.Get(request.Username)
@@ -143,6 +150,17 @@ func (s *AuthService) VerifyToken(tokenString string) error {
 	return nil
 }
 
+// UserClaims returns the claims a token of username would carry, for
+// requests authenticated by other means such as a client certificate.
+func (s *AuthService) UserClaims(username string) (jwt.MapClaims, error) {
+	user, err := s.Users.Get(username)
+	if err != nil {
+		return jwt.MapClaims{}, err
+	}
+
+	return jwt.MapClaims{"Username": user.Username, "Timezone": user.Timezone}, nil
+}
+
 func (s *AuthService) parseTokenString(tokenString string) (*jwt.Token, error) {
 	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
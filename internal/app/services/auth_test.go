@@ -2,18 +2,26 @@ package services
 
 import (
 	"testing"
+	"time"
 	"workshop2/internal/app/models"
 	"workshop2/internal/app/repositories"
 	"workshop2/internal/app/utils"
+
+	"github.com/golang-jwt/jwt"
 )
 
 func TestSignUp(t *testing.T) {
-	auth := AuthService{
+	validator := utils.NewValidator()
+	auth := NewAuth(
 		&repositories.UserRepository{
-			Users:     make([]models.User, 0),
-			Validator: utils.NewValidator(),
+			Validator: validator,
 		},
-	}
+		validator,
+		time.Hour,
+		time.Hour*24,
+		"test",
+		jwt.SigningMethodHS256,
+	)
 
 	t.Run("returns validation error", func(t *testing.T) {
 		request := models.SignUp{
@@ -21,7 +29,7 @@ func TestSignUp(t *testing.T) {
 			Password:       "adsfnsd323",
 			RepeatPassword: "adsfnsd323",
 		}
-		err := auth.SignUp(request)
+		_, err := auth.SignUp(request)
 		if err == nil {
 			t.Errorf("username must be specified")
 		}
@@ -33,7 +41,7 @@ func TestSignUp(t *testing.T) {
 			Password:       "adsfnsd323",
 			RepeatPassword: "adsfnsd323",
 		}
-		err := auth.SignUp(request)
+		_, err := auth.SignUp(request)
 		if err == nil {
 			t.Errorf("username must be minimum 3 characters")
 		}
@@ -45,7 +53,7 @@ func TestSignUp(t *testing.T) {
 			Password:       "adsfnsd!",
 			RepeatPassword: "adsfnsd323!",
 		}
-		err := auth.SignUp(request)
+		_, err := auth.SignUp(request)
 		if err == nil {
 			t.Errorf("passwords must be the same")
 		}
@@ -57,7 +65,7 @@ func TestSignUp(t *testing.T) {
 			Password:       "adsfnsd323",
 			RepeatPassword: "adsfnsd323",
 		}
-		err := auth.SignUp(request)
+		_, err := auth.SignUp(request)
 		if err == nil {
 			t.Errorf("password must contain specific characters")
 		}
@@ -69,10 +77,51 @@ func TestSignUp(t *testing.T) {
 			Password:       "adsfnsd323!",
 			RepeatPassword: "adsfnsd323!",
 		}
-		err := auth.SignUp(request)
+		_, err := auth.SignUp(request)
 		if err == nil {
 			t.Errorf("passwords must not be the same as you username")
 		}
 	})
 
 }
+
+func TestUserClaims(t *testing.T) {
+	validator := utils.NewValidator()
+	auth := NewAuth(
+		&repositories.UserRepository{
+			Validator: validator,
+		},
+		validator,
+		time.Hour,
+		time.Hour*24,
+		"test",
+		jwt.SigningMethodHS256,
+	)
+
+	_, err := auth.SignUp(models.SignUp{
+		Username:       "billing",
+		Password:       "adsfnsd323!",
+		RepeatPassword: "adsfnsd323!",
+		Timezone:       "Europe/Kiev",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("returns the claims of a token", func(t *testing.T) {
+		claims, err := auth.UserClaims("billing")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if claims["Username"] != "billing" || claims["Timezone"] != "Europe/Kiev" {
+			t.Errorf("unexpected claims %v", claims)
+		}
+	})
+
+	t.Run("returns error for unknown user", func(t *testing.T) {
+		if _, err := auth.UserClaims("nobody"); err == nil {
+			t.Error("expected an error for an unknown user")
+		}
+	})
+}
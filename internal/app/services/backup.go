@@ -0,0 +1,40 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+)
+
+type BackupStoreInterface interface {
+	Backup(path string) (int64, error)
+}
+
+// BackupService writes online backups of a persistent store into Dir.
+// Store is nil when the server runs on the in-memory repositories.
+type BackupService struct {
+	Store BackupStoreInterface
+	Dir   string
+}
+
+func (s *BackupService) Create() (models.Backup, error) {
+	if s.Store == nil {
+		return models.Backup{}, errs.NewBackupUnsupportedError()
+	}
+
+	err := os.MkdirAll(s.Dir, 0700)
+	if err != nil {
+		return models.Backup{}, err
+	}
+
+	backup := models.Backup{CreatedAt: time.Now().UTC()}
+	backup.Path = filepath.Join(s.Dir, "workshop2-"+backup.CreatedAt.Format("20060102T150405.000Z")+".db")
+	backup.Size, err = s.Store.Backup(backup.Path)
+	if err != nil {
+		return models.Backup{}, err
+	}
+
+	return backup, nil
+}
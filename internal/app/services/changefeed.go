@@ -0,0 +1,223 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+)
+
+type ChangeLogRepositoryInterface interface {
+	Append(change models.SyncChange) (models.SyncChange, error)
+	// Since returns up to limit changes after seq visible to owner and the
+	// newest sequence number, or SyncTokenExpiredError when changes after
+	// seq are no longer kept.
+	Since(seq int64, owner string, limit int) ([]models.SyncChange, int64, error)
+	Last() (int64, error)
+	PruneBefore(t time.Time) error
+	// Epoch identifies the log; tokens of another log, e.g. an in-memory
+	// log lost on restart, are refused.
+	Epoch() (int64, error)
+}
+
+// ChangeFeed records every event and notification change in a log that
+// offline clients can sync from incrementally. Changes are only visible
+// to the owner of the event or the recipient of the notification.
+type ChangeFeed struct {
+	Log           ChangeLogRepositoryInterface
+	Events        EventRepositoryInterface
+	Notifications NotificationRepositoryInterface
+	Retention     time.Duration
+	PageSize      int
+	// epoch tells tokens of another log apart, see Log.Epoch.
+	epoch int64
+}
+
+func NewChangeFeed(log ChangeLogRepositoryInterface, er EventRepositoryInterface, nr NotificationRepositoryInterface, retention time.Duration, pageSize int) (*ChangeFeed, error) {
+	epoch, err := log.Epoch()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangeFeed{
+		Log:           log,
+		Events:        er,
+		Notifications: nr,
+		Retention:     retention,
+		PageSize:      pageSize,
+		epoch:         epoch,
+	}, nil
+}
+
+func (f *ChangeFeed) Notify(change models.Change) {
+	entry := models.SyncChange{
+		Type:      change.Type,
+		Owner:     changeRecipient(change),
+		Timestamp: change.Timestamp,
+	}
+
+	switch data := change.Data.(type) {
+	case models.Event:
+		entry.Resource = models.HistoryResourceEvent
+		entry.ID = data.ID
+		entry.Data = data
+		entry.Deleted = change.Type == models.ChangeEventDeleted
+	case models.Notification:
+		if change.Type != models.ChangeNotificationCreated && change.Type != models.ChangeNotificationUpdated && change.Type != models.ChangeNotificationDeleted {
+			return
+		}
+
+		entry.Resource = models.HistoryResourceNotification
+		entry.ID = data.ID
+		entry.Data = data
+		entry.Deleted = change.Type == models.ChangeNotificationDeleted
+	default:
+		return
+	}
+
+	if entry.Deleted {
+		entry.Data = nil
+	}
+
+	_, _ = f.Log.Append(entry)
+	if f.Retention > 0 {
+		_ = f.Log.PruneBefore(time.Now().UTC().Add(-f.Retention))
+	}
+}
+
+// Sync returns what changed for the user since token and the token to use
+// next time. Without a token it returns everything the user can see.
+func (f *ChangeFeed) Sync(username string, token string, timezone time.Location) (models.SyncResponse, error) {
+	if token == "" {
+		return f.snapshot(username, timezone)
+	}
+
+	seq, err := f.parseToken(token)
+	if err != nil {
+		return models.SyncResponse{}, err
+	}
+
+	limit := 0
+	if f.PageSize > 0 {
+		limit = f.PageSize + 1
+	}
+
+	changes, last, err := f.Log.Since(seq, username, limit)
+	if err != nil {
+		return models.SyncResponse{}, err
+	}
+
+	response := models.SyncResponse{Token: f.token(last)}
+	if limit > 0 && len(changes) == limit {
+		changes = changes[:f.PageSize]
+		response.Token = f.token(changes[len(changes)-1].Seq)
+		response.More = true
+	}
+
+	response.Changes = latest(changes)
+	for i := range response.Changes {
+		response.Changes[i].Data = inTimezone(response.Changes[i].Data, timezone)
+	}
+
+	return response, nil
+}
+
+// snapshot lists every visible event and notification as a change. The
+// token is taken first, so anything written meanwhile is sent again on the
+// next sync rather than lost.
+func (f *ChangeFeed) snapshot(username string, timezone time.Location) (models.SyncResponse, error) {
+	last, err := f.Log.Last()
+	if err != nil {
+		return models.SyncResponse{}, err
+	}
+
+	events, err := f.Events.GetByOwner(username)
+	if err != nil {
+		return models.SyncResponse{}, err
+	}
+
+	notifications, err := f.Notifications.GetByRecipient(username)
+	if err != nil {
+		return models.SyncResponse{}, err
+	}
+
+	now := time.Now().UTC()
+	changes := make([]models.SyncChange, 0, len(events)+len(notifications))
+	for _, e := range events {
+		changes = append(changes, models.SyncChange{
+			Type:      models.ChangeEventCreated,
+			Resource:  models.HistoryResourceEvent,
+			ID:        e.ID,
+			Data:      e.ConvertInTimezone(timezone),
+			Timestamp: now,
+		})
+	}
+
+	for _, n := range notifications {
+		changes = append(changes, models.SyncChange{
+			Type:      models.ChangeNotificationCreated,
+			Resource:  models.HistoryResourceNotification,
+			ID:        n.ID,
+			Data:      n.ConvertInTimezone(timezone),
+			Timestamp: now,
+		})
+	}
+
+	return models.SyncResponse{Changes: changes, Token: f.token(last)}, nil
+}
+
+func (f *ChangeFeed) token(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", f.epoch, seq)))
+}
+
+func (f *ChangeFeed) parseToken(token string) (int64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errs.NewBadSyncTokenError()
+	}
+
+	var epoch, seq int64
+	if n, err := fmt.Sscanf(string(decoded), "%d.%d", &epoch, &seq); err != nil || n != 2 || seq < 0 {
+		return 0, errs.NewBadSyncTokenError()
+	}
+
+	if epoch != f.epoch {
+		return 0, errs.NewSyncTokenExpiredError()
+	}
+
+	return seq, nil
+}
+
+// latest keeps only the newest change of every resource, in log order.
+func latest(changes []models.SyncChange) []models.SyncChange {
+	type key struct {
+		resource string
+		id       int
+	}
+
+	newest := make(map[key]int64, len(changes))
+	for _, c := range changes {
+		newest[key{c.Resource, c.ID}] = c.Seq
+	}
+
+	compacted := make([]models.SyncChange, 0, len(newest))
+	for _, c := range changes {
+		if newest[key{c.Resource, c.ID}] == c.Seq {
+			compacted = append(compacted, c)
+		}
+	}
+
+	return compacted
+}
+
+func inTimezone(data interface{}, timezone time.Location) interface{} {
+	switch d := data.(type) {
+	case models.Event:
+		return d.ConvertInTimezone(timezone)
+	case models.Notification:
+		return d.ConvertInTimezone(timezone)
+	}
+
+	return data
+}
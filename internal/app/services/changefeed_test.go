@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/repositories"
+)
+
+func newTestChangeFeed(maxEntries int, pageSize int) (*ChangeFeed, *EventService, *NotificationService) {
+	notifications := newTestNotificationService()
+	events := newTestEventService(notifications)
+	feed, _ := NewChangeFeed(&repositories.ChangeLogRepository{MaxEntries: maxEntries}, events.Events, notifications.Notifications, 0, pageSize)
+	notifications.Observers = []ObserverInterface{feed}
+	events.Observers = []ObserverInterface{feed}
+
+	return feed, events, notifications
+}
+
+func TestChangeFeedSync(t *testing.T) {
+	feed, events, notifications := newTestChangeFeed(0, 100)
+	start := time.Now().Add(time.Hour * 48).UTC()
+
+	initial, err := feed.Sync("alice", "", *time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, _ := events.Create(models.Event{Title: "Planning", Time: start}, "alice")
+	event.Title = "Planning (moved)"
+	events.Update(event.ID, event, "alice")
+	doomed, _ := events.Create(models.Event{Title: "Doomed", Time: start}, "alice")
+	events.Delete(doomed.ID, 0, "alice")
+	notifications.Create(models.Notification{Title: "Bob's", Time: start}, "bob")
+
+	response, err := feed.Sync("alice", initial.Token, *time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Changes) != 2 {
+		t.Fatalf("expected one change per event and none of bob's, got %+v", response.Changes)
+	}
+	if e, ok := response.Changes[0].Data.(models.Event); !ok || e.Title != "Planning (moved)" || response.Changes[0].Deleted {
+		t.Errorf("expected only the latest version of the first event, got %+v", response.Changes[0])
+	}
+	if tombstone := response.Changes[1]; !tombstone.Deleted || tombstone.ID != doomed.ID || tombstone.Data != nil {
+		t.Errorf("expected a tombstone for the deleted event, got %+v", tombstone)
+	}
+
+	next, _ := feed.Sync("alice", response.Token, *time.UTC)
+	if len(next.Changes) != 0 || next.Token != response.Token {
+		t.Errorf("nothing changed, got %+v", next)
+	}
+
+	bobs, _ := feed.Sync("bob", initial.Token, *time.UTC)
+	if len(bobs.Changes) != 1 || bobs.Changes[0].Resource != models.HistoryResourceNotification {
+		t.Errorf("bob should see only his notification, got %+v", bobs.Changes)
+	}
+
+	snapshot, _ := feed.Sync("bob", "", *time.UTC)
+	if len(snapshot.Changes) != 1 {
+		t.Errorf("bob's snapshot should hold only his notification, got %+v", snapshot.Changes)
+	}
+	if snapshot, _ = feed.Sync("alice", "", *time.UTC); len(snapshot.Changes) != 1 || snapshot.Changes[0].ID != event.ID {
+		t.Errorf("alice's snapshot should hold her live event, got %+v", snapshot.Changes)
+	}
+}
+
+func TestChangeFeedPages(t *testing.T) {
+	feed, events, _ := newTestChangeFeed(0, 2)
+	initial, _ := feed.Sync("alice", "", *time.UTC)
+
+	for i := 0; i < 5; i++ {
+		events.Create(models.Event{Title: "e", Time: time.Now().Add(time.Hour)}, "alice")
+	}
+
+	token := initial.Token
+	seen := 0
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("paging does not end")
+		}
+
+		response, err := feed.Sync("alice", token, *time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen += len(response.Changes)
+		token = response.Token
+		if !response.More {
+			break
+		}
+	}
+
+	if seen != 5 {
+		t.Errorf("expected 5 changes over all pages, got %d", seen)
+	}
+}
+
+func TestChangeFeedExpiredTokens(t *testing.T) {
+	feed, events, _ := newTestChangeFeed(2, 100)
+	initial, _ := feed.Sync("alice", "", *time.UTC)
+
+	for i := 0; i < 3; i++ {
+		events.Create(models.Event{Title: "e", Time: time.Now().Add(time.Hour)}, "alice")
+	}
+
+	var expired *errs.SyncTokenExpiredError
+	if _, err := feed.Sync("alice", initial.Token, *time.UTC); !errors.As(err, &expired) {
+		t.Errorf("token older than the kept log should expire, got %v", err)
+	}
+
+	// A fresh log, e.g. the in-memory one after a restart, has an epoch
+	// of its own.
+	time.Sleep(time.Millisecond)
+	other, _ := NewChangeFeed(&repositories.ChangeLogRepository{}, events.Events, nil, 0, 100)
+	if _, err := other.Sync("alice", initial.Token, *time.UTC); !errors.As(err, &expired) {
+		t.Errorf("token of another log should expire, got %v", err)
+	}
+
+	var malformed *errs.BadSyncTokenError
+	if _, err := feed.Sync("alice", "not a token", *time.UTC); !errors.As(err, &malformed) {
+		t.Errorf("expected BadSyncTokenError, got %v", err)
+	}
+}
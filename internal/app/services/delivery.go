@@ -0,0 +1,549 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+type NotificationChannelInterface interface {
+	Name() string
+	Accepts(user models.User) bool
+	Deliver(notification models.Notification, user models.User) error
+	DeliverDigest(notifications []models.Notification, user models.User) error
+}
+
+// CreationChannelInterface is implemented by channels that also announce
+// notifications as soon as they are created, not only once they are due.
+type CreationChannelInterface interface {
+	DeliverCreated(notification models.Notification, user models.User) error
+}
+
+type DeliveryRepositoryInterface interface {
+	AddAttempt(attempt models.DeliveryAttempt) (models.DeliveryAttempt, error)
+	GetAttempts(key string) ([]models.DeliveryAttempt, error)
+	// Claim reserves a key for one delivery at a time. It reports false
+	// when the key is already being delivered or has been; a claim ends
+	// with MarkDelivered on success or Release on failure.
+	Claim(key string) (bool, error)
+	Release(key string) error
+	// MarkDelivered reports false when the key had already been delivered.
+	MarkDelivered(key string, at time.Time) (bool, error)
+	AddDeadLetter(letter models.DeadLetter) (models.DeadLetter, error)
+	GetDeadLetters() ([]models.DeadLetter, error)
+	GetDeadLetter(id int) (models.DeadLetter, error)
+	DeleteDeadLetter(id int) (models.DeadLetter, error)
+}
+
+// RetryPolicy controls how often a failed delivery is retried. The delay
+// doubles with every attempt up to MaxBackoff and is jittered to between
+// half and all of it.
+type RetryPolicy struct {
+	MaxAttempts        map[string]int
+	DefaultMaxAttempts int
+	Backoff            time.Duration
+	MaxBackoff         time.Duration
+}
+
+func (p *RetryPolicy) maxAttempts(channel string) int {
+	if n, ok := p.MaxAttempts[channel]; ok {
+		return n
+	}
+
+	return p.DefaultMaxAttempts
+}
+
+// Horizon is the longest a delivery can keep being retried, not counting
+// the time its attempts spend queued and sending.
+func (p *RetryPolicy) Horizon() time.Duration {
+	attempts := p.DefaultMaxAttempts
+	for _, n := range p.MaxAttempts {
+		if n > attempts {
+			attempts = n
+		}
+	}
+
+	var horizon time.Duration
+	d := p.Backoff
+	for i := 1; i < attempts; i++ {
+		if p.MaxBackoff > 0 && d > p.MaxBackoff {
+			d = p.MaxBackoff
+		}
+		horizon += d
+		d *= 2
+	}
+
+	return horizon
+}
+
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	if d <= 1 {
+		return d
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+type notificationJob struct {
+	key           string
+	channel       NotificationChannelInterface
+	notifications []models.Notification
+	user          models.User
+	digest        bool
+	created       bool
+	attempt       int
+}
+
+// deliveryKey identifies a delivery so retries and re-drives of it are
+// sent at most once. Notification versions are part of the key, so a
+// snoozed notification that comes due again is a new delivery; kind tells
+// due notifications, digests and creations apart.
+func deliveryKey(channel string, kind string, recipient string, notifications []models.Notification) string {
+	parts := make([]string, 0, len(notifications))
+	for _, n := range notifications {
+		parts = append(parts, fmt.Sprintf("%d.%d", n.ID, n.Version))
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s", channel, kind, recipient, strings.Join(parts, ","))))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+type heldNotifications struct {
+	since         time.Time
+	notifications []models.Notification
+}
+
+// NotificationDispatcher delivers due notifications to their recipient
+// through each channel that can reach them. Notifications arriving during
+// the recipient's quiet hours, or for recipients who asked for a digest,
+// are held back and released by the periodic flush.
+//
+// New notifications are announced right away through the channels that
+// implement CreationChannelInterface, unless they would be held back; the
+// recipient hears of them once they are due then.
+//
+// Failed deliveries are retried according to the retry policy and end up
+// in the dead-letter list once a channel's attempts are used up.
+type NotificationDispatcher struct {
+	sync.Mutex
+	Users      UserRepositoryInterface
+	Deliveries DeliveryRepositoryInterface
+	Channels   []NotificationChannelInterface
+	Policy     RetryPolicy
+	queue      chan notificationJob
+	held       map[string]*heldNotifications
+	retries    map[*time.Timer]struct{}
+	closed     bool
+}
+
+func NewNotificationDispatcher(ur UserRepositoryInterface, dr DeliveryRepositoryInterface, channels []NotificationChannelInterface, policy RetryPolicy, queueSize int) *NotificationDispatcher {
+	return &NotificationDispatcher{
+		Users:      ur,
+		Deliveries: dr,
+		Channels:   channels,
+		Policy:     policy,
+		queue:      make(chan notificationJob, queueSize),
+		held:       make(map[string]*heldNotifications),
+		retries:    make(map[*time.Timer]struct{}),
+	}
+}
+
+func (d *NotificationDispatcher) Notify(change models.Change) {
+	if change.Type != models.ChangeNotificationDue && change.Type != models.ChangeNotificationCreated {
+		return
+	}
+
+	notification, ok := change.Data.(models.Notification)
+	if !ok {
+		return
+	}
+
+	u, err := d.Users.Get(notification.Recipient)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	held := u.Preferences.IsDigest() || d.inQuietHours(u, now)
+	switch {
+	case change.Type == models.ChangeNotificationCreated:
+		if !held {
+			d.announce(u, notification)
+		}
+	case held:
+		d.hold(u.Username, notification, now)
+	default:
+		d.dispatch(u, []models.Notification{notification}, false)
+	}
+}
+
+func (d *NotificationDispatcher) Run(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-d.queue:
+					d.deliver(job)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				d.flush(now)
+			}
+		}
+	}()
+}
+
+func (d *NotificationDispatcher) deliver(job notificationJob) {
+	attempt := models.DeliveryAttempt{
+		Key:             job.key,
+		Channel:         job.channel.Name(),
+		Recipient:       job.user.Username,
+		NotificationIDs: make([]int, 0, len(job.notifications)),
+		Attempt:         job.attempt,
+		Timestamp:       time.Now().UTC(),
+	}
+	for _, n := range job.notifications {
+		attempt.NotificationIDs = append(attempt.NotificationIDs, n.ID)
+	}
+
+	claimed, err := d.Deliveries.Claim(job.key)
+	if err == nil && !claimed {
+		attempt.Skipped = true
+		_, _ = d.Deliveries.AddAttempt(attempt)
+		return
+	}
+
+	if err == nil {
+		err = d.send(job)
+	}
+
+	if err == nil {
+		attempt.Success = true
+		_, _ = d.Deliveries.AddAttempt(attempt)
+
+		marked, err := d.Deliveries.MarkDelivered(job.key, attempt.Timestamp)
+		if err != nil {
+			logrus.WithError(err).WithField("key", job.key).Error("recording a delivery")
+		} else if !marked {
+			logrus.WithFields(logrus.Fields{
+				"channel":   attempt.Channel,
+				"key":       job.key,
+				"recipient": job.user.Username,
+			}).Warn("notifications were delivered more than once")
+		}
+
+		return
+	}
+
+	attempt.Error = err.Error()
+	_, _ = d.Deliveries.AddAttempt(attempt)
+
+	if job.attempt < d.Policy.maxAttempts(attempt.Channel) {
+		next := job
+		next.attempt++
+		d.retry(next, d.Policy.delay(job.attempt))
+
+		return
+	}
+
+	logrus.WithError(err).WithFields(logrus.Fields{
+		"channel":   attempt.Channel,
+		"key":       job.key,
+		"recipient": job.user.Username,
+		"attempts":  job.attempt,
+	}).Warn("delivery failed, moved to dead letters")
+	_, _ = d.Deliveries.AddDeadLetter(models.DeadLetter{
+		Key:           job.key,
+		Channel:       attempt.Channel,
+		Recipient:     job.user.Username,
+		Notifications: job.notifications,
+		Digest:        job.digest,
+		Created:       job.created,
+		Attempts:      job.attempt,
+		LastError:     attempt.Error,
+		FailedAt:      attempt.Timestamp,
+	})
+}
+
+// send delivers a claimed job and releases the claim when it fails, so the
+// next attempt can claim it again.
+func (d *NotificationDispatcher) send(job notificationJob) error {
+	var err error
+	switch {
+	case job.digest:
+		err = job.channel.DeliverDigest(job.notifications, job.user)
+	case job.created:
+		err = job.channel.(CreationChannelInterface).DeliverCreated(job.notifications[0], job.user)
+	default:
+		err = job.channel.Deliver(job.notifications[0], job.user)
+	}
+
+	if err != nil {
+		if releaseErr := d.Deliveries.Release(job.key); releaseErr != nil {
+			logrus.WithError(releaseErr).WithField("key", job.key).Error("releasing a failed delivery")
+		}
+	}
+
+	return err
+}
+
+// retry queues job again after delay unless the dispatcher is closed by
+// then.
+func (d *NotificationDispatcher) retry(job notificationJob, delay time.Duration) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		d.Lock()
+		_, pending := d.retries[timer]
+		delete(d.retries, timer)
+		d.Unlock()
+
+		if pending {
+			d.enqueue(job)
+		}
+	})
+	d.retries[timer] = struct{}{}
+}
+
+// Close stops the pending retries; deliveries failing after it are not
+// retried.
+func (d *NotificationDispatcher) Close() {
+	d.Lock()
+	defer d.Unlock()
+
+	d.closed = true
+	for timer := range d.retries {
+		timer.Stop()
+		delete(d.retries, timer)
+	}
+}
+
+func (d *NotificationDispatcher) enqueue(job notificationJob) {
+	select {
+	case d.queue <- job:
+	default:
+		logrus.WithFields(logrus.Fields{
+			"channel":       job.channel.Name(),
+			"notifications": len(job.notifications),
+			"recipient":     job.user.Username,
+		}).Warn("delivery queue is full, dropping notifications")
+	}
+}
+
+func (d *NotificationDispatcher) GetDeadLetters() ([]models.DeadLetter, error) {
+	return d.Deliveries.GetDeadLetters()
+}
+
+func (d *NotificationDispatcher) GetDeadLetter(id int) (models.DeadLetter, error) {
+	return d.Deliveries.GetDeadLetter(id)
+}
+
+func (d *NotificationDispatcher) GetAttempts(key string) ([]models.DeliveryAttempt, error) {
+	return d.Deliveries.GetAttempts(key)
+}
+
+func (d *NotificationDispatcher) DeleteDeadLetter(id int) error {
+	_, err := d.Deliveries.DeleteDeadLetter(id)
+	return err
+}
+
+// Redrive takes a delivery off the dead-letter list and queues it again
+// with a fresh set of attempts under the same idempotency key.
+func (d *NotificationDispatcher) Redrive(id int) error {
+	letter, err := d.Deliveries.GetDeadLetter(id)
+	if err != nil {
+		return err
+	}
+
+	var channel NotificationChannelInterface
+	for _, c := range d.Channels {
+		if c.Name() == letter.Channel {
+			channel = c
+		}
+	}
+
+	if channel == nil {
+		return errs.NewDeadLetterNotFoundError()
+	}
+
+	u, err := d.Users.Get(letter.Recipient)
+	if err != nil {
+		return err
+	}
+
+	letter, err = d.Deliveries.DeleteDeadLetter(id)
+	if err != nil {
+		return err
+	}
+
+	d.enqueue(notificationJob{
+		key:           letter.Key,
+		channel:       channel,
+		notifications: letter.Notifications,
+		user:          u,
+		digest:        letter.Digest,
+		created:       letter.Created,
+		attempt:       1,
+	})
+
+	return nil
+}
+
+func (d *NotificationDispatcher) inQuietHours(u models.User, now time.Time) bool {
+	return u.Preferences.QuietHours != nil && u.Preferences.QuietHours.Contains(now.In(u.Location()))
+}
+
+func (d *NotificationDispatcher) hold(username string, notification models.Notification, now time.Time) {
+	d.Lock()
+	defer d.Unlock()
+
+	held, ok := d.held[username]
+	if !ok {
+		held = &heldNotifications{since: now}
+		d.held[username] = held
+	}
+	held.notifications = append(held.notifications, notification)
+}
+
+// flush releases held notifications whose quiet hours are over, and
+// digests whose scheduled time has passed since they started collecting.
+func (d *NotificationDispatcher) flush(now time.Time) {
+	d.Lock()
+	held := d.held
+	d.held = make(map[string]*heldNotifications)
+	d.Unlock()
+
+	for username, h := range held {
+		u, err := d.Users.Get(username)
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case d.inQuietHours(u, now):
+		case !u.Preferences.IsDigest():
+			for _, n := range h.notifications {
+				d.dispatch(u, []models.Notification{n}, false)
+			}
+			continue
+		case u.Preferences.LastDigest(now.In(u.Location())).After(h.since):
+			d.dispatch(u, h.notifications, true)
+			continue
+		}
+
+		d.restore(username, h)
+	}
+}
+
+// restore puts back notifications that are still being held, ahead of
+// any that arrived while the flush was running.
+func (d *NotificationDispatcher) restore(username string, h *heldNotifications) {
+	d.Lock()
+	defer d.Unlock()
+
+	if arrived, ok := d.held[username]; ok {
+		h.notifications = append(h.notifications, arrived.notifications...)
+	}
+	d.held[username] = h
+}
+
+func (d *NotificationDispatcher) dispatch(u models.User, notifications []models.Notification, digest bool) {
+	kind := models.ChangeNotificationDue
+	if digest {
+		kind = models.ChangeNotificationDigest
+	}
+
+	for _, c := range d.Channels {
+		if !c.Accepts(u) {
+			continue
+		}
+
+		enabled := enabledFor(u, c, notifications)
+		if len(enabled) == 0 {
+			continue
+		}
+
+		d.enqueue(notificationJob{
+			key:           deliveryKey(c.Name(), kind, u.Username, enabled),
+			channel:       c,
+			notifications: enabled,
+			user:          u,
+			digest:        digest,
+			attempt:       1,
+		})
+	}
+}
+
+// announce queues a new notification for the channels that announce
+// creations.
+func (d *NotificationDispatcher) announce(u models.User, notification models.Notification) {
+	for _, c := range d.Channels {
+		if _, ok := c.(CreationChannelInterface); !ok || !c.Accepts(u) {
+			continue
+		}
+
+		enabled := enabledFor(u, c, []models.Notification{notification})
+		if len(enabled) == 0 {
+			continue
+		}
+
+		d.enqueue(notificationJob{
+			key:           deliveryKey(c.Name(), models.ChangeNotificationCreated, u.Username, enabled),
+			channel:       c,
+			notifications: enabled,
+			user:          u,
+			created:       true,
+			attempt:       1,
+		})
+	}
+}
+
+// enabledFor keeps the notifications the user wants through channel c.
+func enabledFor(u models.User, c NotificationChannelInterface, notifications []models.Notification) []models.Notification {
+	enabled := make([]models.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		if u.Preferences.ChannelEnabled(n.Type(), c.Name()) {
+			enabled = append(enabled, n)
+		}
+	}
+
+	return enabled
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/repositories"
+	"workshop2/internal/app/utils"
+)
+
+type recordingChannel struct {
+	sync.Mutex
+	name      string
+	delivered []models.Notification
+	digests   [][]models.Notification
+}
+
+func (c *recordingChannel) Name() string {
+	return c.name
+}
+
+func (c *recordingChannel) Accepts(user models.User) bool {
+	return true
+}
+
+func (c *recordingChannel) Deliver(notification models.Notification, user models.User) error {
+	c.Lock()
+	defer c.Unlock()
+	c.delivered = append(c.delivered, notification)
+
+	return nil
+}
+
+func (c *recordingChannel) DeliverDigest(notifications []models.Notification, user models.User) error {
+	c.Lock()
+	defer c.Unlock()
+	c.digests = append(c.digests, notifications)
+
+	return nil
+}
+
+func newTestDispatcher(t *testing.T, user models.User, channels ...NotificationChannelInterface) *NotificationDispatcher {
+	t.Helper()
+
+	users := &repositories.UserRepository{Validator: utils.NewValidator()}
+	if _, err := users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewNotificationDispatcher(users, &repositories.DeliveryRepository{}, channels, RetryPolicy{DefaultMaxAttempts: 1}, 16)
+}
+
+func drain(d *NotificationDispatcher) {
+	for {
+		select {
+		case job := <-d.queue:
+			d.deliver(job)
+		default:
+			return
+		}
+	}
+}
+
+func due(n models.Notification) models.Change {
+	return models.Change{Type: models.ChangeNotificationDue, Data: n}
+}
+
+func TestDispatcherChannelPreferences(t *testing.T) {
+	email := &recordingChannel{name: "email"}
+	push := &recordingChannel{name: "push"}
+	d := newTestDispatcher(t, models.User{
+		Username: "alice",
+		Password: "passw0rd!",
+		Timezone: "UTC",
+		Preferences: models.NotificationPreferences{
+			Channels: map[string][]string{models.NotificationTypeReminder: {"push"}},
+		},
+	}, email, push)
+
+	d.Notify(due(models.Notification{ID: 1, Recipient: "alice"}))
+	d.Notify(due(models.Notification{ID: 2, Recipient: "alice", EventID: 7}))
+	d.Notify(due(models.Notification{ID: 3, Recipient: "bob"}))
+	drain(d)
+
+	if len(email.delivered) != 1 || email.delivered[0].ID != 1 {
+		t.Errorf("email should only get the general notification, got %+v", email.delivered)
+	}
+	if len(push.delivered) != 2 {
+		t.Errorf("push should get both notifications, got %+v", push.delivered)
+	}
+}
+
+func TestDispatcherQuietHours(t *testing.T) {
+	push := &recordingChannel{name: "push"}
+	now := time.Now().UTC()
+	d := newTestDispatcher(t, models.User{
+		Username: "alice",
+		Password: "passw0rd!",
+		Timezone: "UTC",
+		Preferences: models.NotificationPreferences{
+			QuietHours: &models.QuietHours{
+				Start: now.Add(-time.Hour).Format("15:04"),
+				End:   now.Add(time.Hour).Format("15:04"),
+			},
+		},
+	}, push)
+
+	d.Notify(due(models.Notification{ID: 1, Recipient: "alice"}))
+	drain(d)
+	if len(push.delivered) != 0 {
+		t.Fatal("notification should be held during quiet hours")
+	}
+
+	d.flush(now)
+	drain(d)
+	if len(push.delivered) != 0 {
+		t.Fatal("notification should stay held while quiet hours last")
+	}
+
+	d.flush(now.Add(time.Hour * 2))
+	drain(d)
+	if len(push.delivered) != 1 {
+		t.Errorf("notification should be released after quiet hours, got %d", len(push.delivered))
+	}
+}
+
+func TestDispatcherDigest(t *testing.T) {
+	push := &recordingChannel{name: "push"}
+	d := newTestDispatcher(t, models.User{
+		Username: "alice",
+		Password: "passw0rd!",
+		Timezone: "UTC",
+		Preferences: models.NotificationPreferences{
+			Digest:     models.DigestDaily,
+			DigestHour: 8,
+		},
+	}, push)
+
+	d.Notify(due(models.Notification{ID: 1, Recipient: "alice"}))
+	d.Notify(due(models.Notification{ID: 2, Recipient: "alice"}))
+	drain(d)
+	if len(push.delivered) != 0 || len(push.digests) != 0 {
+		t.Fatal("notifications should be collected for the digest")
+	}
+
+	d.flush(time.Now().Add(time.Hour * 24))
+	drain(d)
+	if len(push.digests) != 1 || len(push.digests[0]) != 2 {
+		t.Errorf("expected one digest with two notifications, got %+v", push.digests)
+	}
+}
+
+func TestQuietHoursAcrossMidnight(t *testing.T) {
+	q := models.QuietHours{Start: "22:00", End: "07:00"}
+
+	cases := map[string]bool{"23:30": true, "03:00": true, "07:00": false, "12:00": false, "22:00": true}
+	for clock, want := range cases {
+		at, _ := time.Parse("15:04", clock)
+		if q.Contains(at) != want {
+			t.Errorf("%s: expected %v", clock, want)
+		}
+	}
+}
+
+type failingChannel struct {
+	recordingChannel
+	failures int
+}
+
+func (c *failingChannel) Deliver(notification models.Notification, user models.User) error {
+	c.Lock()
+	if c.failures > 0 {
+		c.failures--
+		c.Unlock()
+		return errors.New("mail server unavailable")
+	}
+	c.Unlock()
+
+	return c.recordingChannel.Deliver(notification, user)
+}
+
+func (c *recordingChannel) count() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.delivered)
+}
+
+func TestDispatcherRetriesAndDeadLetters(t *testing.T) {
+	email := &failingChannel{recordingChannel: recordingChannel{name: "email"}, failures: 3}
+	d := newTestDispatcher(t, models.User{Username: "alice", Password: "passw0rd!", Timezone: "UTC"}, email)
+	d.Policy = RetryPolicy{
+		MaxAttempts:        map[string]int{"email": 2},
+		DefaultMaxAttempts: 5,
+		Backoff:            time.Millisecond,
+		MaxBackoff:         time.Millisecond * 4,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.Run(ctx, 2)
+
+	d.Notify(due(models.Notification{ID: 1, Version: 1, Recipient: "alice"}))
+	waitFor(t, func() bool {
+		letters, _ := d.GetDeadLetters()
+		return len(letters) == 1
+	})
+
+	letters, _ := d.GetDeadLetters()
+	attempts, _ := d.GetAttempts(letters[0].Key)
+	if len(attempts) != 2 || letters[0].Attempts != 2 {
+		t.Fatalf("expected 2 failed attempts, got %d", len(attempts))
+	}
+
+	if err := d.Redrive(letters[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Redrive(letters[0].ID); err == nil {
+		t.Error("a dead letter can only be re-driven once")
+	}
+
+	// One more failure is left, then the re-drive succeeds.
+	waitFor(t, func() bool { return email.count() == 1 })
+
+	d.enqueue(notificationJob{key: letters[0].Key, channel: email, notifications: letters[0].Notifications, user: models.User{Username: "alice"}, attempt: 1})
+	waitFor(t, func() bool {
+		attempts, _ := d.GetAttempts(letters[0].Key)
+		return len(attempts) == 5
+	})
+
+	if email.count() != 1 {
+		t.Errorf("a delivered key must not be sent twice, sent %d times", email.count())
+	}
+}
+
+// blockingChannel holds deliveries until release is closed.
+type blockingChannel struct {
+	recordingChannel
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingChannel) Deliver(notification models.Notification, user models.User) error {
+	c.started <- struct{}{}
+	<-c.release
+
+	return c.recordingChannel.Deliver(notification, user)
+}
+
+func TestDispatcherDeliversKeyOnce(t *testing.T) {
+	push := &blockingChannel{
+		recordingChannel: recordingChannel{name: "push"},
+		started:          make(chan struct{}, 8),
+		release:          make(chan struct{}),
+	}
+	d := newTestDispatcher(t, models.User{Username: "alice", Password: "passw0rd!", Timezone: "UTC"}, push)
+	job := notificationJob{
+		key:           "concurrent",
+		channel:       push,
+		notifications: []models.Notification{{ID: 1, Recipient: "alice"}},
+		user:          models.User{Username: "alice"},
+		attempt:       1,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(job)
+		}()
+	}
+
+	// Every worker but the one holding the claim skips the key without
+	// waiting for the delivery in flight.
+	<-push.started
+	waitFor(t, func() bool {
+		attempts, _ := d.GetAttempts(job.key)
+		return len(attempts) == 3
+	})
+	close(push.release)
+	wg.Wait()
+
+	if push.count() != 1 {
+		t.Errorf("expected one delivery, got %d", push.count())
+	}
+
+	attempts, _ := d.GetAttempts(job.key)
+	skipped := 0
+	for _, a := range attempts {
+		if a.Skipped {
+			skipped++
+		}
+	}
+	if len(attempts) != 4 || skipped != 3 {
+		t.Errorf("expected one delivered and three skipped attempts, got %+v", attempts)
+	}
+}
+
+func TestDispatcherReleasesFailedClaims(t *testing.T) {
+	email := &failingChannel{recordingChannel: recordingChannel{name: "email"}, failures: 1}
+	d := newTestDispatcher(t, models.User{Username: "alice", Password: "passw0rd!", Timezone: "UTC"}, email)
+	job := notificationJob{
+		key:           "retried",
+		channel:       email,
+		notifications: []models.Notification{{ID: 1, Recipient: "alice"}},
+		user:          models.User{Username: "alice"},
+		attempt:       1,
+	}
+
+	d.deliver(job)
+	if email.count() != 0 {
+		t.Fatal("expected the first attempt to fail")
+	}
+
+	d.deliver(job)
+	if email.count() != 1 {
+		t.Errorf("expected the failed claim to be released for the next attempt, got %d deliveries", email.count())
+	}
+
+	d.deliver(job)
+	if email.count() != 1 {
+		t.Errorf("expected a delivered key to stay claimed, got %d deliveries", email.count())
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: time.Second, MaxBackoff: time.Second * 5}
+
+	for attempt, max := range map[int]time.Duration{1: time.Second, 2: time.Second * 2, 3: time.Second * 4, 4: time.Second * 5, 10: time.Second * 5} {
+		d := p.delay(attempt)
+		if d < max/2 || d > max {
+			t.Errorf("attempt %d: delay %s outside [%s, %s]", attempt, d, max/2, max)
+		}
+	}
+}
+
+func TestRetryPolicyHorizon(t *testing.T) {
+	p := RetryPolicy{
+		MaxAttempts:        map[string]int{"email": 5},
+		DefaultMaxAttempts: 3,
+		Backoff:            time.Second,
+		MaxBackoff:         time.Second * 5,
+	}
+
+	// Four retries of the email channel: 1s, 2s, 4s and 5s.
+	if h := p.Horizon(); h != time.Second*12 {
+		t.Errorf("expected a horizon of 12s, got %s", h)
+	}
+}
+
+func TestDispatcherCloseStopsRetries(t *testing.T) {
+	email := &failingChannel{recordingChannel: recordingChannel{name: "email"}, failures: 1}
+	d := newTestDispatcher(t, models.User{Username: "alice", Password: "passw0rd!", Timezone: "UTC"}, email)
+	d.Policy = RetryPolicy{DefaultMaxAttempts: 2, Backoff: time.Millisecond * 20, MaxBackoff: time.Millisecond * 20}
+
+	d.Notify(due(models.Notification{ID: 1, Recipient: "alice"}))
+	drain(d)
+	d.Close()
+
+	time.Sleep(time.Millisecond * 50)
+	drain(d)
+	if email.count() != 0 {
+		t.Errorf("expected the retry to be stopped, delivered %d", email.count())
+	}
+
+	d.retry(notificationJob{key: "late", channel: email, notifications: []models.Notification{{ID: 2}}, attempt: 2}, 0)
+	time.Sleep(time.Millisecond * 10)
+	if len(d.queue) != 0 {
+		t.Error("expected no retries to be scheduled after closing")
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+const emailTimeFormat = "Monday, 02 January 2006 15:04 MST"
+
+var notificationTextTemplate = template.Must(template.New("text").Parse(`{{.Title}}
+
+When: {{.Time}}
+{{if .Description}}
+{{.Description}}
+{{end}}`))
+
+var notificationHTMLTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<body>
+<h2>{{.Title}}</h2>
+<p><strong>When:</strong> {{.Time}}</p>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+</body>
+</html>
+`))
+
+var digestTextTemplate = template.Must(template.New("digest-text").Parse(`{{range .}}- {{.Title}} ({{.Time}})
+{{end}}`))
+
+var digestHTMLTemplate = htmltemplate.Must(htmltemplate.New("digest-html").Parse(`<!DOCTYPE html>
+<html>
+<body>
+<ul>
+{{range .}}<li><strong>{{.Title}}</strong> &ndash; {{.Time}}</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+type emailNotification struct {
+	Title       string
+	Description string
+	Time        string
+}
+
+func newEmailNotification(notification models.Notification, loc *time.Location) emailNotification {
+	return emailNotification{
+		Title:       notification.Title,
+		Description: notification.Description,
+		Time:        notification.TimeUTC.In(loc).Format(emailTimeFormat),
+	}
+}
+
+type EmailChannel struct {
+	Mailer MailerInterface
+}
+
+func (c *EmailChannel) Name() string {
+	return "email"
+}
+
+func (c *EmailChannel) Accepts(user models.User) bool {
+	return user.Email != "" && user.EmailVerified
+}
+
+func (c *EmailChannel) Deliver(notification models.Notification, user models.User) error {
+	message, err := renderNotificationEmail(c.Mailer.From(), user.Email, notification, user.Location())
+	if err != nil {
+		return err
+	}
+
+	return c.Mailer.Send(user.Email, message)
+}
+
+func (c *EmailChannel) DeliverDigest(notifications []models.Notification, user models.User) error {
+	loc := user.Location()
+	data := make([]emailNotification, 0, len(notifications))
+	for _, n := range notifications {
+		data = append(data, newEmailNotification(n, loc))
+	}
+
+	var text, html bytes.Buffer
+	if err := digestTextTemplate.Execute(&text, data); err != nil {
+		return err
+	}
+
+	if err := digestHTMLTemplate.Execute(&html, data); err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Your notification digest (%d)", len(notifications))
+	message, err := buildMultipartEmail(c.Mailer.From(), user.Email, subject, text.String(), html.String())
+	if err != nil {
+		return err
+	}
+
+	return c.Mailer.Send(user.Email, message)
+}
+
+func renderNotificationEmail(from string, to string, notification models.Notification, loc *time.Location) ([]byte, error) {
+	data := newEmailNotification(notification, loc)
+
+	var text, html bytes.Buffer
+	if err := notificationTextTemplate.Execute(&text, data); err != nil {
+		return nil, err
+	}
+
+	if err := notificationHTMLTemplate.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	return buildMultipartEmail(from, to, notification.Title, text.String(), html.String())
+}
+
+func buildMultipartEmail(from string, to string, subject string, text string, html string) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	}
+
+	for _, p := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", p.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(part)
+		if _, err = encoder.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+
+		if err = encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	messageID, err := newMessageID(from)
+	if err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	headers := [][2]string{
+		{"From", from},
+		{"To", to},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + writer.Boundary()},
+	}
+
+	for _, h := range headers {
+		message.WriteString(h[0] + ": " + h[1] + "\r\n")
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+func newMessageID(from string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">", nil
+}
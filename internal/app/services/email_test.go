@@ -0,0 +1,152 @@
+package services
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+type smtpSink struct {
+	sync.Mutex
+	listener net.Listener
+	messages []string
+	rcpts    []string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &smtpSink{listener: l}
+	go s.serve()
+	t.Cleanup(func() { l.Close() })
+
+	return s
+}
+
+func (s *smtpSink) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpSink) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 sink ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 sink")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.Lock()
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			s.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var body strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				body.WriteString(l)
+			}
+			s.Lock()
+			s.messages = append(s.messages, body.String())
+			s.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpSink) received() ([]string, []string) {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]string(nil), s.messages...), append([]string(nil), s.rcpts...)
+}
+
+func TestEmailChannelDeliver(t *testing.T) {
+	sink := newSMTPSink(t)
+	channel := &EmailChannel{Mailer: NewSMTPMailer(SMTPConfig{
+		Host:     "127.0.0.1",
+		Port:     sink.port(),
+		From:     "workshop2@localhost",
+		Security: models.SMTPSecurityNone,
+		Timeout:  time.Second,
+	})}
+
+	user := models.User{Username: "alice", Timezone: "Europe/Kiev", Email: "alice@example.com", EmailVerified: true}
+	notification := models.Notification{
+		ID:          1,
+		Title:       "Standup",
+		Description: "Daily sync",
+		TimeUTC:     time.Date(2021, 3, 1, 7, 30, 0, 0, time.UTC),
+	}
+
+	if !channel.Accepts(user) {
+		t.Fatal("verified user should be accepted")
+	}
+
+	err := channel.Deliver(notification, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, rcpts := sink.received()
+	if len(messages) != 1 || len(rcpts) != 1 || rcpts[0] != user.Email {
+		t.Fatalf("unexpected deliveries: %v to %v", len(messages), rcpts)
+	}
+
+	message := messages[0]
+	for _, want := range []string{"multipart/alternative", "text/plain", "text/html", "Standup", "Daily sync", "09:30 EET"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message does not contain %q", want)
+		}
+	}
+}
+
+func TestEmailChannelSkipsUnverifiedUsers(t *testing.T) {
+	channel := &EmailChannel{}
+
+	if channel.Accepts(models.User{Email: "bob@example.com"}) {
+		t.Error("unverified user should not be accepted")
+	}
+	if channel.Accepts(models.User{EmailVerified: true}) {
+		t.Error("user without email should not be accepted")
+	}
+}
@@ -1,38 +1,112 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"time"
+	"workshop2/internal/app/errs"
 	"workshop2/internal/app/models"
+	"workshop2/internal/app/utils"
 )
 
 type EventRepositoryInterface interface {
 	GetAll() ([]models.Event, error)
+	// GetBetween returns events with from <= TimeUTC < to ordered by TimeUTC.
+	GetBetween(from time.Time, to time.Time) ([]models.Event, error)
+	// GetByOwner returns the events of owner that aren't trashed, ordered by ID.
+	GetByOwner(owner string) ([]models.Event, error)
 	Get(id int) (models.Event, error)
 	Create(event models.Event) (models.Event, error)
+	// Update and Trash only succeed when the version matches the stored
+	// one; a zero version skips the check. Update keeps the owner.
 	Update(id int, newEvent models.Event) (models.Event, error)
-	Delete(id int) error
+	Trash(id int, version int, username string, deletedAt time.Time) (models.Event, error)
+	// GetTrash, Restore and Purge only see the trashed events of owner.
+	GetTrash(owner string) ([]models.Event, error)
+	Restore(id int, owner string) (models.Event, error)
+	Purge(id int, owner string) error
+	PurgeDeletedBefore(limit time.Time) ([]models.Event, error)
 }
 
 type EventService struct {
-	Events EventRepositoryInterface
-	Users  UserRepositoryInterface
+	Events         EventRepositoryInterface
+	Users          UserRepositoryInterface
+	History        HistoryRepositoryInterface
+	Observers      []ObserverInterface
+	Reminders      ReminderServiceInterface
+	Transactor     TransactorInterface
+	Validator      utils.ValidatorInterface
+	TrashRetention time.Duration
+	// session is the sync session changes are made from, see InSession.
+	session string
 }
 
-func (s *EventService) GetAll(interval string, timezone time.Location) ([]models.Event, error) {
-	var suitableEvents = make([]models.Event, 0)
-	events, _ := s.Events.GetAll()
+// InSession returns a copy of the service whose changes are marked as made
+// from the given sync session, so they aren't sent back to it.
+func (s *EventService) InSession(session string) *EventService {
+	tx := *s
+	tx.session = session
 
-	for i, e := range events {
-		events[i] = e.ConvertInTimezone(timezone)
+	return &tx
+}
+
+// transaction runs fn with a copy of the service bound to one unit of
+// work, so an event and its reminders are written together or not at all.
+// Without a Transactor fn runs against the service itself.
+func (s *EventService) transaction(fn func(tx *EventService) error) error {
+	if s.Transactor == nil {
+		return fn(s)
 	}
 
+	return inTransaction(s.Transactor, func(u *unitOfWork) error {
+		tx := *s
+		tx.Events = u.Events
+		tx.Users = u.Users
+		tx.Observers = u.observers(s.Observers)
+		tx.History = u.history(s.History)
+		if s.Reminders != nil {
+			tx.Reminders = s.Reminders.inUnit(u)
+		}
+
+		return fn(&tx)
+	})
+}
+
+// ownedBy keeps the events of owner.
+func ownedBy(events []models.Event, owner string) []models.Event {
+	owned := make([]models.Event, 0, len(events))
+	for _, e := range events {
+		if e.Owner == owner {
+			owned = append(owned, e)
+		}
+	}
+
+	return owned
+}
+
+func (s *EventService) GetAll(interval string, username string, timezone time.Location) ([]models.Event, error) {
+	var suitableEvents = make([]models.Event, 0)
+
 	if !isInterval(intervals, interval) {
+		events, _ := s.Events.GetByOwner(username)
+		for i, e := range events {
+			events[i] = e.ConvertInTimezone(timezone)
+		}
+
 		return events, nil
 	}
 
 	var limit time.Time = identifyLimit(interval)
 	now := time.Now().UTC()
 
+	// The range is widened to whole minutes so repeated requests hit the
+	// same cached query; the exact bounds are applied below.
+	events, _ := s.Events.GetBetween(limit.Truncate(time.Minute), now.Truncate(time.Minute).Add(time.Minute))
+	events = ownedBy(events, username)
+	for i, e := range events {
+		events[i] = e.ConvertInTimezone(timezone)
+	}
+
 	for _, e := range events {
 		if now.After(e.TimeUTC) && limit.Before(e.TimeUTC) {
 			suitableEvents = append(suitableEvents, e)
@@ -42,19 +116,289 @@ func (s *EventService) GetAll(interval string, timezone time.Location) ([]models
 	return suitableEvents, nil
 }
 
-func (s *EventService) Get(id int) (models.Event, error) {
-	return s.Events.Get(id)
+// Get returns the event only to its owner; to everyone else it does not
+// exist.
+func (s *EventService) Get(id int, username string) (models.Event, error) {
+	return getOwnedEvent(s.Events, id, username)
 }
 
-func (s *EventService) Create(event models.Event) (models.Event, error) {
+func getOwnedEvent(events EventRepositoryInterface, id int, username string) (models.Event, error) {
+	event, err := events.Get(id)
+	if err != nil {
+		return event, err
+	}
+
+	if event.Owner != username {
+		return models.Event{}, &errs.EventNotFoundError{}
+	}
+
+	return event, nil
+}
+
+func (s *EventService) Create(event models.Event, username string) (models.Event, error) {
+	err := s.Validator.Struct(event)
+	if err != nil {
+		return event, errs.NewEventValidationError(err)
+	}
+
 	event.TimeUTC = event.Time.UTC()
-	return s.Events.Create(event)
+	event.Owner = username
+	created := event
+	err = s.transaction(func(tx *EventService) error {
+		created, err = tx.Events.Create(event)
+		if err != nil {
+			return err
+		}
+
+		publishFrom(tx.Observers, models.ChangeEventCreated, username, tx.session, created)
+		err = recordHistory(tx.History, models.HistoryResourceEvent, created.ID, created.Version, models.HistoryActionCreated, username, nil, created)
+		if err != nil {
+			return err
+		}
+
+		return tx.syncReminders(created, username)
+	})
+
+	return created, err
 }
 
-func (s *EventService) Update(id int, event models.Event) (models.Event, error) {
-	return s.Events.Update(id, event)
+func (s *EventService) Update(id int, event models.Event, username string) (models.Event, error) {
+	return s.update(id, event, username, models.HistoryActionUpdated)
 }
 
-func (s *EventService) Delete(id int) error {
-	return s.Events.Delete(id)
+func (s *EventService) update(id int, event models.Event, username string, action string) (models.Event, error) {
+	err := s.Validator.Struct(event)
+	if err != nil {
+		return event, errs.NewEventValidationError(err)
+	}
+
+	event.TimeUTC = event.Time.UTC()
+	updated := event
+	err = s.transaction(func(tx *EventService) error {
+		before, err := getOwnedEvent(tx.Events, id, username)
+		if err != nil {
+			return err
+		}
+
+		updated, err = tx.Events.Update(id, event)
+		if err != nil {
+			return err
+		}
+
+		publishFrom(tx.Observers, models.ChangeEventUpdated, username, tx.session, updated)
+		err = recordHistory(tx.History, models.HistoryResourceEvent, id, updated.Version, action, username, before, updated)
+		if err != nil {
+			return err
+		}
+
+		return tx.syncReminders(updated, username)
+	})
+
+	return updated, err
+}
+
+func (s *EventService) syncReminders(event models.Event, username string) error {
+	if s.Reminders == nil {
+		return nil
+	}
+
+	return s.Reminders.SyncReminders(event, username)
+}
+
+func (s *EventService) Patch(id int, version int, patch utils.PatchInterface, username string) (models.Event, error) {
+	event, err := s.Get(id, username)
+	if err != nil {
+		return event, err
+	}
+
+	if version != 0 && version != event.Version {
+		return event, errs.NewVersionMismatchError()
+	}
+
+	document, err := json.Marshal(event)
+	if err != nil {
+		return event, err
+	}
+
+	document, err = patch.Apply(document)
+	if err != nil {
+		return event, err
+	}
+
+	var patched models.Event
+	err = json.Unmarshal(document, &patched)
+	if err != nil {
+		return event, errs.NewInvalidPatchError(err.Error())
+	}
+
+	patched.Version = event.Version
+	return s.Update(id, patched, username)
+}
+
+func (s *EventService) Delete(id int, version int, username string) error {
+	return s.transaction(func(tx *EventService) error {
+		before, err := getOwnedEvent(tx.Events, id, username)
+		if err != nil {
+			return err
+		}
+
+		event, err := tx.Events.Trash(id, version, username, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+
+		publishFrom(tx.Observers, models.ChangeEventDeleted, username, tx.session, before)
+		err = recordHistory(tx.History, models.HistoryResourceEvent, id, event.Version, models.HistoryActionDeleted, username, before, nil)
+		if err != nil {
+			return err
+		}
+
+		if tx.Reminders == nil {
+			return nil
+		}
+
+		return tx.Reminders.DeleteReminders(id, username)
+	})
+}
+
+func (s *EventService) GetTrash(username string, timezone time.Location) ([]models.Event, error) {
+	events, err := s.Events.GetTrash(username)
+	if err != nil {
+		return events, err
+	}
+
+	for i, e := range events {
+		events[i] = e.ConvertInTimezone(timezone)
+	}
+
+	return events, nil
+}
+
+func (s *EventService) Restore(id int, username string) (models.Event, error) {
+	var event models.Event
+	err := s.transaction(func(tx *EventService) error {
+		var err error
+		event, err = tx.Events.Restore(id, username)
+		if err != nil {
+			return err
+		}
+
+		publishFrom(tx.Observers, models.ChangeEventRestored, username, tx.session, event)
+		err = recordHistory(tx.History, models.HistoryResourceEvent, id, event.Version, models.HistoryActionRestored, username, nil, event)
+		if err != nil {
+			return err
+		}
+
+		return tx.syncReminders(event, username)
+	})
+
+	return event, err
+}
+
+func (s *EventService) Purge(id int, username string) error {
+	err := s.Events.Purge(id, username)
+	if err != nil {
+		return err
+	}
+
+	return recordHistory(s.History, models.HistoryResourceEvent, id, 0, models.HistoryActionPurged, username, nil, nil)
+}
+
+func (s *EventService) PurgeExpiredTrash() (int, error) {
+	if s.TrashRetention <= 0 {
+		return 0, nil
+	}
+
+	events, err := s.Events.PurgeDeletedBefore(time.Now().UTC().Add(-s.TrashRetention))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range events {
+		err = recordHistory(s.History, models.HistoryResourceEvent, e.ID, 0, models.HistoryActionPurged, models.HistoryActorSystem, nil, nil)
+		if err != nil {
+			return len(events), err
+		}
+	}
+
+	return len(events), nil
+}
+
+func (s *EventService) PurgeTrashPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = s.PurgeExpiredTrash()
+		}
+	}
+}
+
+// GetHistory stays readable while the event is in the trash, so its owner
+// can see what happened to it before restoring it.
+func (s *EventService) GetHistory(id int, username string) ([]models.HistoryEntry, error) {
+	_, err := s.Get(id, username)
+	if _, ok := err.(*errs.EventNotFoundError); ok {
+		err = s.findInTrash(id, username)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.History.GetByResource(models.HistoryResourceEvent, id)
+}
+
+func (s *EventService) findInTrash(id int, username string) error {
+	trash, err := s.Events.GetTrash(username)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range trash {
+		if e.ID == id {
+			return nil
+		}
+	}
+
+	return &errs.EventNotFoundError{}
+}
+
+func (s *EventService) Revert(id int, toVersion int, version int, username string) (models.Event, error) {
+	current, err := s.Get(id, username)
+	if err != nil {
+		return current, err
+	}
+
+	entries, err := s.History.GetByResource(models.HistoryResourceEvent, id)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	var snapshot json.RawMessage
+	for _, e := range entries {
+		if e.Version == toVersion && len(e.After) > 0 {
+			snapshot = e.After
+		}
+	}
+
+	if snapshot == nil {
+		return models.Event{}, errs.NewHistoryEntryNotFoundError()
+	}
+
+	if version != 0 && version != current.Version {
+		return current, errs.NewVersionMismatchError()
+	}
+
+	var reverted models.Event
+	err = json.Unmarshal(snapshot, &reverted)
+	if err != nil {
+		return current, err
+	}
+
+	reverted.Version = current.Version
+	return s.update(id, reverted, username, models.HistoryActionReverted)
 }
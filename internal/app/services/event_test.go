@@ -0,0 +1,359 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/repositories"
+	"workshop2/internal/app/utils"
+)
+
+func newTestTrashService() *EventService {
+	return &EventService{
+		Events:         &repositories.EventRepository{},
+		History:        &repositories.HistoryRepository{},
+		Validator:      utils.NewValidator(),
+		TrashRetention: time.Hour * 24,
+	}
+}
+
+func createEvent(t *testing.T, s *EventService, title string) models.Event {
+	t.Helper()
+
+	event, err := s.Create(models.Event{Title: title, Time: time.Now().Add(-time.Minute)}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return event
+}
+
+func TestEventTrash(t *testing.T) {
+	t.Run("hides trashed events", func(t *testing.T) {
+		s := newTestTrashService()
+		kept := createEvent(t, s, "kept")
+		trashed := createEvent(t, s, "trashed")
+
+		if err := s.Delete(trashed.ID, trashed.Version, "alice"); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, interval := range []string{"", "day"} {
+			events, _ := s.GetAll(interval, "alice", *time.UTC)
+			if len(events) != 1 || events[0].ID != kept.ID {
+				t.Errorf("interval %q: expected only the kept event, got %+v", interval, events)
+			}
+		}
+
+		if _, err := s.Get(trashed.ID, "alice"); err == nil {
+			t.Error("expected a trashed event not to be found")
+		}
+
+		trash, _ := s.GetTrash("alice", *time.UTC)
+		if len(trash) != 1 || trash[0].ID != trashed.ID {
+			t.Errorf("expected the event in alice's trash, got %+v", trash)
+		}
+	})
+
+	t.Run("only the owner deletes", func(t *testing.T) {
+		s := newTestTrashService()
+		event := createEvent(t, s, "standup")
+
+		if err := s.Delete(event.ID, 0, "bob"); err == nil {
+			t.Error("expected bob not to delete alice's event")
+		}
+		if _, err := s.Get(event.ID, "alice"); err != nil {
+			t.Errorf("expected the event to survive, got %v", err)
+		}
+	})
+
+	t.Run("only the owner restores", func(t *testing.T) {
+		s := newTestTrashService()
+		event := createEvent(t, s, "standup")
+		if err := s.Delete(event.ID, 0, "alice"); err != nil {
+			t.Fatal(err)
+		}
+
+		if trash, _ := s.GetTrash("bob", *time.UTC); len(trash) != 0 {
+			t.Errorf("expected bob's trash to be empty, got %+v", trash)
+		}
+		if _, err := s.Restore(event.ID, "bob"); err == nil {
+			t.Error("expected bob not to restore alice's event")
+		}
+
+		restored, err := s.Restore(event.ID, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.IsTrashed() || restored.Version != event.Version+2 {
+			t.Errorf("expected a live event at version %d, got %+v", event.Version+2, restored)
+		}
+		if _, err = s.Get(event.ID, "alice"); err != nil {
+			t.Errorf("expected the restored event to be found, got %v", err)
+		}
+	})
+
+	t.Run("purges only trashed events", func(t *testing.T) {
+		s := newTestTrashService()
+		event := createEvent(t, s, "standup")
+
+		err := s.Purge(event.ID, "alice")
+		if _, ok := err.(*errs.EventNotFoundError); !ok {
+			t.Errorf("expected a not found error for a live event, got %v", err)
+		}
+		if _, err = s.Get(event.ID, "alice"); err != nil {
+			t.Errorf("expected the live event to survive, got %v", err)
+		}
+
+		if err = s.Delete(event.ID, 0, "alice"); err != nil {
+			t.Fatal(err)
+		}
+		if err = s.Purge(event.ID, "bob"); err == nil {
+			t.Error("expected bob not to purge alice's event")
+		}
+		if err = s.Purge(event.ID, "alice"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = s.Restore(event.ID, "alice"); err == nil {
+			t.Error("expected a purged event not to be restorable")
+		}
+	})
+
+	t.Run("purges events deleted before the retention", func(t *testing.T) {
+		s := newTestTrashService()
+		expired := createEvent(t, s, "expired")
+		recent := createEvent(t, s, "recent")
+		live := createEvent(t, s, "live")
+
+		if _, err := s.Events.Trash(expired.ID, 0, "alice", time.Now().UTC().Add(-time.Hour*25)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Events.Trash(recent.ID, 0, "alice", time.Now().UTC().Add(-time.Hour*23)); err != nil {
+			t.Fatal(err)
+		}
+
+		purged, err := s.PurgeExpiredTrash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if purged != 1 {
+			t.Errorf("expected 1 purged event, got %d", purged)
+		}
+
+		trash, _ := s.GetTrash("alice", *time.UTC)
+		if len(trash) != 1 || trash[0].ID != recent.ID {
+			t.Errorf("expected only the recent event in the trash, got %+v", trash)
+		}
+		if _, err = s.Get(live.ID, "alice"); err != nil {
+			t.Errorf("expected the live event to survive, got %v", err)
+		}
+
+		history, _ := s.History.GetByResource(models.HistoryResourceEvent, expired.ID)
+		last := history[len(history)-1]
+		if last.Action != models.HistoryActionPurged || last.Actor != models.HistoryActorSystem {
+			t.Errorf("expected a purge by the system to be recorded, got %+v", last)
+		}
+	})
+}
+
+func TestEventOwnership(t *testing.T) {
+	s := newTestTrashService()
+	event := createEvent(t, s, "standup")
+
+	if events, _ := s.GetAll("", "bob", *time.UTC); len(events) != 0 {
+		t.Errorf("expected bob to list none of alice's events, got %+v", events)
+	}
+	if events, _ := s.GetAll("day", "alice", *time.UTC); len(events) != 1 || events[0].Owner != "alice" {
+		t.Errorf("expected alice to list her event, got %+v", events)
+	}
+
+	_, err := s.Get(event.ID, "bob")
+	assertNotFound(t, "get", err)
+	changed := event
+	changed.Title = "retro"
+	_, err = s.Update(event.ID, changed, "bob")
+	assertNotFound(t, "update", err)
+	_, err = s.GetHistory(event.ID, "bob")
+	assertNotFound(t, "history", err)
+	_, err = s.Revert(event.ID, event.Version, 0, "bob")
+	assertNotFound(t, "revert", err)
+
+	// An owner sent by the client is ignored.
+	changed.Owner = "bob"
+	if updated, err := s.Update(event.ID, changed, "alice"); err != nil || updated.Owner != "alice" {
+		t.Errorf("expected alice to keep the event, got %+v, %v", updated, err)
+	}
+}
+
+func assertNotFound(t *testing.T, action string, err error) {
+	t.Helper()
+
+	if _, ok := err.(*errs.EventNotFoundError); !ok {
+		t.Errorf("%s: expected a not found error, got %v", action, err)
+	}
+}
+
+func changedFields(entry models.HistoryEntry) map[string]models.FieldChange {
+	changes := make(map[string]models.FieldChange, len(entry.Changes))
+	for _, c := range entry.Changes {
+		changes[c.Field] = c
+	}
+
+	return changes
+}
+
+func TestEventHistory(t *testing.T) {
+	t.Run("records diffs and reverts", func(t *testing.T) {
+		s := newTestTrashService()
+		created := createEvent(t, s, "standup")
+
+		updated := created
+		updated.Title = "retro"
+		updated, err := s.Update(created.ID, updated, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		history, err := s.GetHistory(created.ID, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 || history[1].Action != models.HistoryActionUpdated || history[1].Actor != "alice" {
+			t.Fatalf("expected a create and an update by alice, got %+v", history)
+		}
+
+		changes := changedFields(history[1])
+		if changes["title"].Before != "standup" || changes["title"].After != "retro" {
+			t.Errorf("expected the title change to be recorded, got %+v", history[1].Changes)
+		}
+		if version := changes["version"]; version.Before != float64(created.Version) || version.After != float64(updated.Version) {
+			t.Errorf("expected the version to go from %d to %d, got %+v", created.Version, updated.Version, version)
+		}
+
+		reverted, err := s.Revert(created.ID, created.Version, updated.Version, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reverted.Title != "standup" || reverted.Version != updated.Version+1 {
+			t.Errorf("expected the original title at version %d, got %+v", updated.Version+1, reverted)
+		}
+
+		history, _ = s.GetHistory(created.ID, "alice")
+		last := history[len(history)-1]
+		if last.Action != models.HistoryActionReverted || changedFields(last)["title"].After != "standup" {
+			t.Errorf("expected the revert to be recorded, got %+v", last)
+		}
+	})
+
+	t.Run("records the version that was read", func(t *testing.T) {
+		s := newTestTrashService()
+		event := createEvent(t, s, "standup")
+
+		// A zero version skips the check, so the stored version is whatever
+		// was there when the write ran.
+		for i := 0; i < 2; i++ {
+			event.Version = 0
+			if _, err := s.Update(event.ID, event, "alice"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Delete(event.ID, 0, "alice"); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, _ := s.History.GetByResource(models.HistoryResourceEvent, event.ID)
+		for _, entry := range entries[1:] {
+			if version := changedFields(entry)["version"].Before; version != float64(entry.Version-1) {
+				t.Errorf("%s at version %d: expected version %d before, got %v", entry.Action, entry.Version, entry.Version-1, version)
+			}
+		}
+	})
+
+	t.Run("rejects an unknown version", func(t *testing.T) {
+		s := newTestTrashService()
+		event := createEvent(t, s, "standup")
+
+		_, err := s.Revert(event.ID, event.Version+5, 0, "alice")
+		if _, ok := err.(*errs.HistoryEntryNotFoundError); !ok {
+			t.Errorf("expected a history entry not found error, got %v", err)
+		}
+	})
+
+	t.Run("rejects a stale version", func(t *testing.T) {
+		s := newTestTrashService()
+		created := createEvent(t, s, "standup")
+
+		updated := created
+		updated.Title = "retro"
+		if _, err := s.Update(created.ID, updated, "alice"); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := s.Revert(created.ID, created.Version, created.Version, "alice")
+		if _, ok := err.(*errs.VersionMismatchError); !ok {
+			t.Errorf("expected a version mismatch error, got %v", err)
+		}
+
+		current, _ := s.Get(created.ID, "alice")
+		if current.Title != "retro" {
+			t.Errorf("expected the stale revert to change nothing, got %+v", current)
+		}
+	})
+
+	t.Run("stays readable in the trash", func(t *testing.T) {
+		s := newTestTrashService()
+		event := createEvent(t, s, "standup")
+		if err := s.Delete(event.ID, 0, "alice"); err != nil {
+			t.Fatal(err)
+		}
+
+		history, err := s.GetHistory(event.ID, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 || history[1].Action != models.HistoryActionDeleted {
+			t.Errorf("expected the create and the delete, got %+v", history)
+		}
+
+		_, err = s.GetHistory(event.ID, "bob")
+		assertNotFound(t, "history of another user's trash", err)
+	})
+
+	t.Run("needs an existing event", func(t *testing.T) {
+		s := newTestTrashService()
+		event := createEvent(t, s, "standup")
+		if err := s.Delete(event.ID, 0, "alice"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Purge(event.ID, "alice"); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, id := range []int{event.ID, event.ID + 1} {
+			if _, err := s.GetHistory(id, "alice"); err == nil {
+				t.Errorf("expected no history for event %d", id)
+			} else if _, ok := err.(*errs.EventNotFoundError); !ok {
+				t.Errorf("expected a not found error for event %d, got %v", id, err)
+			}
+		}
+	})
+}
+
+func TestEventInSession(t *testing.T) {
+	s := newTestTrashService()
+	recorder := &changeRecorder{}
+	s.Observers = []ObserverInterface{recorder}
+
+	event := createEvent(t, s, "standup")
+	if err := s.InSession("phone").Delete(event.ID, 0, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorder.changes) != 2 || recorder.changes[0].Session != "" || recorder.changes[1].Session != "phone" {
+		t.Errorf("expected only the delete to come from the session, got %+v", recorder.changes)
+	}
+	if s.session != "" {
+		t.Error("expected the service itself to stay unbound")
+	}
+}
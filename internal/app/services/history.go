@@ -0,0 +1,82 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+// HistoryRepositoryInterface is append-only: recorded entries are never
+// changed or removed.
+type HistoryRepositoryInterface interface {
+	Create(entry models.HistoryEntry) (models.HistoryEntry, error)
+	GetByResource(resourceType string, resourceID int) ([]models.HistoryEntry, error)
+}
+
+func recordHistory(history HistoryRepositoryInterface, resourceType string, resourceID int, version int, action string, actor string, before interface{}, after interface{}) error {
+	entry := models.HistoryEntry{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Version:      version,
+		Action:       action,
+		Actor:        actor,
+		Timestamp:    time.Now().UTC(),
+	}
+
+	var err error
+	if before != nil {
+		if entry.Before, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+
+	if after != nil {
+		if entry.After, err = json.Marshal(after); err != nil {
+			return err
+		}
+	}
+
+	if entry.Changes, err = diff(entry.Before, entry.After); err != nil {
+		return err
+	}
+
+	_, err = history.Create(entry)
+	return err
+}
+
+func diff(before json.RawMessage, after json.RawMessage) ([]models.FieldChange, error) {
+	var b, a map[string]interface{}
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &b); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &a); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := make([]string, 0, len(b)+len(a))
+	for field := range b {
+		fields = append(fields, field)
+	}
+	for field := range a {
+		if _, ok := b[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := make([]models.FieldChange, 0)
+	for _, field := range fields {
+		if !reflect.DeepEqual(b[field], a[field]) {
+			changes = append(changes, models.FieldChange{Field: field, Before: b[field], After: a[field]})
+		}
+	}
+
+	return changes, nil
+}
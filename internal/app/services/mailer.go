@@ -0,0 +1,114 @@
+package services
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+type MailerInterface interface {
+	From() string
+	Send(to string, message []byte) error
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Security string
+	Timeout  time.Duration
+}
+
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{config: config}
+}
+
+func (m *SMTPMailer) From() string {
+	return m.config.From
+}
+
+func (m *SMTPMailer) Send(to string, message []byte) error {
+	client, err := m.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if m.config.Security == models.SMTPSecurityStartTLS {
+		if err = client.StartTLS(&tls.Config{ServerName: m.config.Host}); err != nil {
+			return err
+		}
+	}
+
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(m.config.From); err != nil {
+		return err
+	}
+
+	if err = client.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(message); err != nil {
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (m *SMTPMailer) dial() (*smtp.Client, error) {
+	address := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	dialer := &net.Dialer{Timeout: m.config.Timeout}
+
+	var conn net.Conn
+	var err error
+	switch m.config.Security {
+	case models.SMTPSecurityTLS:
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: m.config.Host})
+	case models.SMTPSecurityStartTLS, models.SMTPSecurityNone, "":
+		conn, err = dialer.Dial("tcp", address)
+	default:
+		return nil, fmt.Errorf("unknown SMTP security mode %q", m.config.Security)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if m.config.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(m.config.Timeout))
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
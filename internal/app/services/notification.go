@@ -1,23 +1,65 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"time"
+	"workshop2/internal/app/errs"
 	"workshop2/internal/app/models"
+	"workshop2/internal/app/utils"
 )
 
 type NotificationRepositoryInterface interface {
 	GetAll() ([]models.Notification, error)
+	// GetBetween returns notifications with from <= TimeUTC < to ordered by TimeUTC.
+	GetBetween(from time.Time, to time.Time) ([]models.Notification, error)
+	GetByRecipient(recipient string) ([]models.Notification, error)
+	GetByEvent(eventID int) ([]models.Notification, error)
+	Get(id int) (models.Notification, error)
 	Create(notification models.Notification) (models.Notification, error)
+	// Update only succeeds when the version matches the stored one;
+	// a zero version skips the check.
 	Update(id int, notification models.Notification) (models.Notification, error)
+	Delete(id int, version int) (models.Notification, error)
+	// DeleteMany is all-or-nothing: a single unknown ID fails the whole call.
+	DeleteMany(ids []int) ([]models.Notification, error)
+}
+
+// DueMarkRepositoryInterface remembers up to when due notifications were
+// published. Get returns the zero time until a mark is set.
+type DueMarkRepositoryInterface interface {
+	Get() (time.Time, error)
+	Set(mark time.Time) error
 }
 
 type NotificationService struct {
 	Notifications NotificationRepositoryInterface
+	History       HistoryRepositoryInterface
+	DueMark       DueMarkRepositoryInterface
+	Observers     []ObserverInterface
+	Transactor    TransactorInterface
+	Validator     utils.ValidatorInterface
+}
+
+// transaction runs fn with a copy of the service bound to one unit of
+// work. Without a Transactor fn runs against the service itself.
+func (s *NotificationService) transaction(fn func(tx *NotificationService) error) error {
+	if s.Transactor == nil {
+		return fn(s)
+	}
+
+	return inTransaction(s.Transactor, func(u *unitOfWork) error {
+		return fn(s.inUnit(u).(*NotificationService))
+	})
 }
 
-func (s *NotificationService) GetAll(interval string, timezone time.Location) ([]models.Notification, error) {
+func (s *NotificationService) GetAll(username string, interval string, state string, timezone time.Location) ([]models.Notification, error) {
 	var suitableNotifications = make([]models.Notification, 0)
-	notifications, _ := s.Notifications.GetAll()
+
+	notifications, err := s.filterByState(username, state)
+	if err != nil {
+		return notifications, err
+	}
 
 	for i, n := range notifications {
 		notifications[i] = n.ConvertInTimezone(timezone)
@@ -39,11 +81,330 @@ func (s *NotificationService) GetAll(interval string, timezone time.Location) ([
 	return suitableNotifications, nil
 }
 
-func (s *NotificationService) Create(notification models.Notification) (models.Notification, error) {
+func (s *NotificationService) filterByState(username string, state string) ([]models.Notification, error) {
+	if state == "" {
+		state = models.NotificationStateActive
+	}
+
+	if !models.IsNotificationState(state) {
+		return nil, errs.NewBadNotificationStateError()
+	}
+
+	notifications, err := s.Notifications.GetByRecipient(username)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	filtered := make([]models.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		if n.InState(state, now) {
+			filtered = append(filtered, n)
+		}
+	}
+
+	return filtered, nil
+}
+
+func (s *NotificationService) UnreadCount(username string) (int, error) {
+	notifications, err := s.filterByState(username, models.NotificationStateUnread)
+	return len(notifications), err
+}
+
+// Get hides notifications of other users behind the same not-found error
+// as missing ones.
+func (s *NotificationService) Get(id int, username string) (models.Notification, error) {
+	notification, err := s.Notifications.Get(id)
+	if err != nil {
+		return notification, err
+	}
+
+	if notification.Recipient != username {
+		return models.Notification{}, &errs.NotificationNotFoundError{}
+	}
+
+	return notification, nil
+}
+
+func (s *NotificationService) Create(notification models.Notification, username string) (models.Notification, error) {
+	err := s.Validator.Struct(notification)
+	if err != nil {
+		return notification, errs.NewNotificationValidationError(err)
+	}
+
 	notification.TimeUTC = notification.Time.UTC()
-	return s.Notifications.Create(notification)
+	notification.Recipient = username
+	notification.EventID = 0
+	notification.ReminderMinutes = 0
+	notification.Read = false
+	notification.Dismissed = false
+	notification.SnoozedUntil = nil
+	notification, err = s.Notifications.Create(notification)
+	if err != nil {
+		return notification, err
+	}
+
+	publish(s.Observers, models.ChangeNotificationCreated, username, notification)
+	return notification, recordHistory(s.History, models.HistoryResourceNotification, notification.ID, notification.Version, models.HistoryActionCreated, username, nil, notification)
+}
+
+func (s *NotificationService) Update(id int, notification models.Notification, username string) (models.Notification, error) {
+	err := s.Validator.Struct(notification)
+	if err != nil {
+		return notification, errs.NewNotificationValidationError(err)
+	}
+
+	notification.TimeUTC = notification.Time.UTC()
+	updated := notification
+	err = s.transaction(func(tx *NotificationService) error {
+		before, err := tx.Get(id, username)
+		if err != nil {
+			return err
+		}
+
+		notification.Recipient = before.Recipient
+		notification.EventID = before.EventID
+		notification.ReminderMinutes = before.ReminderMinutes
+		updated, err = tx.Notifications.Update(id, notification)
+		if err != nil {
+			return err
+		}
+
+		publish(tx.Observers, models.ChangeNotificationUpdated, username, updated)
+		return recordHistory(tx.History, models.HistoryResourceNotification, id, updated.Version, models.HistoryActionUpdated, username, before, updated)
+	})
+
+	return updated, err
+}
+
+// WatchDue publishes a notification.due change for every notification whose
+// time passes while the watcher is running. With a DueMark it starts where
+// the last watcher stopped, so nothing that came due meanwhile is missed.
+func (s *NotificationService) WatchDue(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	since := time.Now().UTC()
+	if s.DueMark != nil {
+		if mark, err := s.DueMark.Get(); err == nil && !mark.IsZero() && mark.Before(since) {
+			since = s.advanceDue(mark, since)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			since = s.advanceDue(since, now.UTC())
+		}
+	}
+}
+
+// advanceDue publishes what came due up to now and moves the mark there.
+func (s *NotificationService) advanceDue(since time.Time, now time.Time) time.Time {
+	s.publishDue(since, now)
+	if s.DueMark != nil {
+		_ = s.DueMark.Set(now)
+	}
+
+	return now
+}
+
+func (s *NotificationService) publishDue(from time.Time, to time.Time) {
+	notifications, err := s.Notifications.GetAll()
+	if err != nil {
+		return
+	}
+
+	for _, n := range notifications {
+		if n.Dismissed {
+			continue
+		}
+
+		if n.SnoozedUntil != nil && n.SnoozedUntil.After(from) && !n.SnoozedUntil.After(to) {
+			n, err = s.resurface(n)
+			if err != nil {
+				continue
+			}
+
+			publish(s.Observers, models.ChangeNotificationDue, models.HistoryActorSystem, n)
+			continue
+		}
+
+		if n.TimeUTC.After(from) && !n.TimeUTC.After(to) && !n.IsSnoozed(to) {
+			publish(s.Observers, models.ChangeNotificationDue, models.HistoryActorSystem, n)
+		}
+	}
+}
+
+// resurface brings a notification back as unread once its snooze expires.
+func (s *NotificationService) resurface(n models.Notification) (models.Notification, error) {
+	before := n
+
+	n.SnoozedUntil = nil
+	n.Read = false
+	n, err := s.Notifications.Update(n.ID, n)
+	if err != nil {
+		return n, err
+	}
+
+	publish(s.Observers, models.ChangeNotificationUpdated, models.HistoryActorSystem, n)
+	return n, recordHistory(s.History, models.HistoryResourceNotification, n.ID, n.Version, models.HistoryActionUpdated, models.HistoryActorSystem, before, n)
+}
+
+func (s *NotificationService) MarkRead(id int, version int, username string) (models.Notification, error) {
+	return s.changeState(id, version, username, func(n *models.Notification) {
+		n.Read = true
+	})
+}
+
+func (s *NotificationService) MarkUnread(id int, version int, username string) (models.Notification, error) {
+	return s.changeState(id, version, username, func(n *models.Notification) {
+		n.Read = false
+	})
+}
+
+// MarkAllRead marks every unread notification of the user as read, or
+// none of them if one fails.
+func (s *NotificationService) MarkAllRead(username string) (int, error) {
+	marked := 0
+	err := s.transaction(func(tx *NotificationService) error {
+		notifications, err := tx.filterByState(username, models.NotificationStateUnread)
+		if err != nil {
+			return err
+		}
+
+		for _, n := range notifications {
+			if _, err = tx.MarkRead(n.ID, n.Version, username); err != nil {
+				return err
+			}
+		}
+
+		marked = len(notifications)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return marked, nil
+}
+
+func (s *NotificationService) Dismiss(id int, version int, username string) (models.Notification, error) {
+	return s.changeState(id, version, username, func(n *models.Notification) {
+		n.Dismissed = true
+		n.SnoozedUntil = nil
+	})
+}
+
+func (s *NotificationService) Snooze(id int, until time.Time, version int, username string) (models.Notification, error) {
+	until = until.UTC()
+	if !until.After(time.Now().UTC()) {
+		return models.Notification{}, errs.NewBadSnoozeError()
+	}
+
+	return s.changeState(id, version, username, func(n *models.Notification) {
+		n.SnoozedUntil = &until
+		n.Dismissed = false
+	})
 }
 
-func (s *NotificationService) Update(id int, notification models.Notification) (models.Notification, error) {
-	return s.Notifications.Update(id, notification)
+func (s *NotificationService) changeState(id int, version int, username string, change func(n *models.Notification)) (models.Notification, error) {
+	before, err := s.Get(id, username)
+	if err != nil {
+		return before, err
+	}
+
+	if version != 0 && version != before.Version {
+		return before, errs.NewVersionMismatchError()
+	}
+
+	notification := before
+	change(&notification)
+	notification, err = s.Notifications.Update(id, notification)
+	if err != nil {
+		return notification, err
+	}
+
+	publish(s.Observers, models.ChangeNotificationUpdated, username, notification)
+	return notification, recordHistory(s.History, models.HistoryResourceNotification, id, notification.Version, models.HistoryActionUpdated, username, before, notification)
+}
+
+func (s *NotificationService) GetHistory(id int, username string) ([]models.HistoryEntry, error) {
+	_, err := s.Get(id, username)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.History.GetByResource(models.HistoryResourceNotification, id)
+}
+
+func (s *NotificationService) Delete(id int, version int, username string) error {
+	_, err := s.Get(id, username)
+	if err != nil {
+		return err
+	}
+
+	notification, err := s.Notifications.Delete(id, version)
+	if err != nil {
+		return err
+	}
+
+	publish(s.Observers, models.ChangeNotificationDeleted, username, notification)
+	return recordHistory(s.History, models.HistoryResourceNotification, id, notification.Version+1, models.HistoryActionDeleted, username, notification, nil)
+}
+
+func (s *NotificationService) DeleteMany(ids []int, username string) (int, error) {
+	for _, id := range ids {
+		_, err := s.Get(id, username)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	notifications, err := s.Notifications.DeleteMany(ids)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, n := range notifications {
+		publish(s.Observers, models.ChangeNotificationDeleted, username, n)
+		err = recordHistory(s.History, models.HistoryResourceNotification, n.ID, n.Version+1, models.HistoryActionDeleted, username, n, nil)
+		if err != nil {
+			return len(notifications), err
+		}
+	}
+
+	return len(notifications), nil
+}
+
+func (s *NotificationService) Patch(id int, version int, patch utils.PatchInterface, username string) (models.Notification, error) {
+	notification, err := s.Get(id, username)
+	if err != nil {
+		return notification, err
+	}
+
+	if version != 0 && version != notification.Version {
+		return notification, errs.NewVersionMismatchError()
+	}
+
+	document, err := json.Marshal(notification)
+	if err != nil {
+		return notification, err
+	}
+
+	document, err = patch.Apply(document)
+	if err != nil {
+		return notification, err
+	}
+
+	var patched models.Notification
+	err = json.Unmarshal(document, &patched)
+	if err != nil {
+		return notification, errs.NewInvalidPatchError(err.Error())
+	}
+
+	patched.Version = notification.Version
+	return s.Update(id, patched, username)
 }
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/repositories"
+	"workshop2/internal/app/utils"
+)
+
+func newTestNotificationService() *NotificationService {
+	return &NotificationService{
+		Notifications: &repositories.NotificationRepository{},
+		History:       &repositories.HistoryRepository{},
+		Validator:     utils.NewValidator(),
+	}
+}
+
+func TestNotificationStateFilters(t *testing.T) {
+	s := newTestNotificationService()
+	past := time.Now().Add(-time.Hour)
+
+	for _, title := range []string{"unread", "read", "dismissed", "snoozed"} {
+		_, err := s.Create(models.Notification{Title: title, Time: past}, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := s.MarkRead(2, 0, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Dismiss(3, 0, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Snooze(4, time.Now().Add(time.Hour), 0, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string][]string{
+		"":                                {"unread", "read"},
+		models.NotificationStateAll:       {"unread", "read", "dismissed", "snoozed"},
+		models.NotificationStateUnread:    {"unread"},
+		models.NotificationStateRead:      {"read"},
+		models.NotificationStateDismissed: {"dismissed"},
+		models.NotificationStateSnoozed:   {"snoozed"},
+	}
+
+	for state, want := range cases {
+		notifications, err := s.GetAll("alice", "", state, *time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(notifications) != len(want) {
+			t.Fatalf("state %q: expected %d notifications, got %d", state, len(want), len(notifications))
+		}
+
+		for i, n := range notifications {
+			if n.Title != want[i] {
+				t.Errorf("state %q: expected %q, got %q", state, want[i], n.Title)
+			}
+		}
+	}
+
+	count, _ := s.UnreadCount("alice")
+	if count != 1 {
+		t.Errorf("expected 1 unread notification, got %d", count)
+	}
+
+	if _, err := s.GetAll("alice", "", "bogus", *time.UTC); err == nil {
+		t.Error("expected an error for an unknown state")
+	}
+}
+
+func TestNotificationSnoozeResurfaces(t *testing.T) {
+	s := newTestNotificationService()
+	recorder := &changeRecorder{}
+	s.Observers = []ObserverInterface{recorder}
+
+	n, _ := s.Create(models.Notification{Title: "Standup", Time: time.Now().Add(-time.Hour)}, "alice")
+	n, _ = s.MarkRead(n.ID, n.Version, "alice")
+
+	until := time.Now().Add(time.Minute)
+	if _, err := s.Snooze(n.ID, until, n.Version, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Snooze(n.ID, time.Now().Add(-time.Minute), 0, "alice"); err == nil {
+		t.Error("snoozing into the past should fail")
+	}
+
+	s.publishDue(until.Add(-time.Second), until.Add(time.Second))
+
+	n, _ = s.Get(n.ID, "alice")
+	if n.SnoozedUntil != nil || n.Read {
+		t.Errorf("expected notification to resurface as unread, got %+v", n)
+	}
+
+	if recorder.count(models.ChangeNotificationDue) != 1 {
+		t.Errorf("expected one due change, got %d", recorder.count(models.ChangeNotificationDue))
+	}
+}
+
+// changeChannel hands the changes over to the test goroutine.
+type changeChannel chan models.Change
+
+func (c changeChannel) Notify(change models.Change) {
+	c <- change
+}
+
+func TestWatchDueCatchesUp(t *testing.T) {
+	s := newTestNotificationService()
+	past := time.Now().Add(-time.Hour)
+	old, _ := s.Create(models.Notification{Title: "before the mark", Time: past.Add(-time.Minute)}, "alice")
+	missed, _ := s.Create(models.Notification{Title: "while down", Time: past.Add(time.Minute)}, "alice")
+
+	s.DueMark = &repositories.DueMarkRepository{}
+	_ = s.DueMark.Set(past)
+	changes := make(changeChannel, 4)
+	s.Observers = []ObserverInterface{changes}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go s.WatchDue(ctx, time.Hour)
+
+	select {
+	case change := <-changes:
+		if n := change.Data.(models.Notification); change.Type != models.ChangeNotificationDue || n.ID != missed.ID {
+			t.Errorf("expected %d to come due, got %s of %d", missed.ID, change.Type, n.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the notification missed while down to come due")
+	}
+
+	select {
+	case change := <-changes:
+		t.Errorf("expected %d to stay behind the mark, got %s of %+v", old.ID, change.Type, change.Data)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if mark, _ := s.DueMark.Get(); !mark.After(past) {
+		t.Errorf("expected the mark to move on from %s, got %s", past, mark)
+	}
+}
+
+type changeRecorder struct {
+	changes []models.Change
+}
+
+func (r *changeRecorder) Notify(change models.Change) {
+	r.changes = append(r.changes, change)
+}
+
+func (r *changeRecorder) count(changeType string) int {
+	n := 0
+	for _, c := range r.changes {
+		if c.Type == changeType {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestNotificationOwnership(t *testing.T) {
+	s := newTestNotificationService()
+	past := time.Now().Add(-time.Hour)
+
+	a, _ := s.Create(models.Notification{Title: "alice's", Time: past}, "alice")
+	b, _ := s.Create(models.Notification{Title: "bob's", Time: past}, "bob")
+	c, _ := s.Create(models.Notification{Title: "bob's too", Time: past}, "bob")
+
+	notifications, _ := s.GetAll("alice", "", models.NotificationStateAll, *time.UTC)
+	if len(notifications) != 1 || notifications[0].ID != a.ID {
+		t.Fatalf("alice should only see her notification, got %+v", notifications)
+	}
+
+	if _, err := s.Get(b.ID, "alice"); err == nil {
+		t.Error("alice should not see bob's notification")
+	}
+
+	if err := s.Delete(b.ID, 0, "alice"); err == nil {
+		t.Error("alice should not be able to delete bob's notification")
+	}
+
+	if _, err := s.DeleteMany([]int{b.ID, 42}, "bob"); err == nil {
+		t.Error("bulk delete with an unknown ID should fail")
+	}
+
+	deleted, err := s.DeleteMany([]int{b.ID, c.ID}, "bob")
+	if err != nil || deleted != 2 {
+		t.Fatalf("expected 2 deleted notifications, got %d (%v)", deleted, err)
+	}
+
+	if err = s.Delete(b.ID, 0, "bob"); err == nil {
+		t.Error("deleting a deleted notification should fail")
+	}
+}
+
+func TestNotificationHistory(t *testing.T) {
+	store := &repositories.MemoryStore{
+		Events:        &repositories.EventRepository{},
+		Notifications: &repositories.NotificationRepository{},
+	}
+	s := newTestNotificationService()
+	s.Notifications = store.Notifications
+	s.Transactor = newTestTransactor(store, func(tx Store) Store { return tx })
+	past := time.Now().Add(-time.Hour)
+
+	created, _ := s.Create(models.Notification{Title: "standup", Time: past}, "alice")
+	update := created
+	update.Title = "retro"
+	update.Version = 0
+	updated, err := s.Update(created.ID, update, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := s.GetHistory(created.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected a create and an update, got %+v", history)
+	}
+
+	changes := make(map[string]models.FieldChange)
+	for _, c := range history[1].Changes {
+		changes[c.Field] = c
+	}
+	if changes["title"].Before != "standup" || changes["title"].After != "retro" {
+		t.Errorf("expected the title change to be recorded, got %+v", history[1].Changes)
+	}
+	if changes["version"].Before != float64(created.Version) || changes["version"].After != float64(updated.Version) {
+		t.Errorf("expected the version to go from %d to %d, got %+v", created.Version, updated.Version, changes["version"])
+	}
+
+	if _, err = s.GetHistory(created.ID, "bob"); err == nil {
+		t.Error("bob should not see the history of alice's notification")
+	}
+
+	if err = s.Delete(created.ID, 0, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{created.ID, created.ID + 1} {
+		if _, err = s.GetHistory(id, "alice"); err == nil {
+			t.Errorf("expected no history for notification %d", id)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"time"
+	"workshop2/internal/app/models"
+)
+
+// ObserverInterface receives every change published by the services.
+// Notify is called synchronously from the writer, so implementations
+// must not block.
+type ObserverInterface interface {
+	Notify(change models.Change)
+}
+
+func publish(observers []ObserverInterface, changeType string, actor string, data interface{}) {
+	publishFrom(observers, changeType, actor, "", data)
+}
+
+// publishFrom publishes a change made from the given sync session.
+func publishFrom(observers []ObserverInterface, changeType string, actor string, session string, data interface{}) {
+	change := models.Change{
+		Type:      changeType,
+		Actor:     actor,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+		Session:   session,
+	}
+
+	for _, o := range observers {
+		o.Notify(change)
+	}
+}
+
+// changeRecipient returns the user a change is addressed to, or an empty
+// string for changes that concern everyone.
+func changeRecipient(change models.Change) string {
+	switch data := change.Data.(type) {
+	case models.Notification:
+		return data.Recipient
+	case models.Event:
+		return data.Owner
+	}
+
+	return ""
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"time"
+	"workshop2/internal/app/models"
+)
+
+// ReminderServiceInterface keeps the reminder notifications of an event in
+// step with the event itself.
+type ReminderServiceInterface interface {
+	SyncReminders(event models.Event, username string) error
+	DeleteReminders(eventID int, username string) error
+	inUnit(u *unitOfWork) ReminderServiceInterface
+}
+
+func (s *NotificationService) inUnit(u *unitOfWork) ReminderServiceInterface {
+	tx := *s
+	tx.Notifications = u.Notifications
+	tx.Observers = u.observers(s.Observers)
+	tx.History = u.history(s.History)
+	tx.Transactor = nil
+
+	return &tx
+}
+
+// SyncReminders moves, creates and deletes the notifications linked to the
+// event so they match its reminder offsets. Reminders that would already
+// be in the past are not created. New reminders go to whoever owns the
+// existing ones, or to the acting user for an event without reminders.
+func (s *NotificationService) SyncReminders(event models.Event, username string) error {
+	existing, err := s.Notifications.GetByEvent(event.ID)
+	if err != nil {
+		return err
+	}
+
+	recipient := username
+	if len(existing) > 0 {
+		recipient = existing[0].Recipient
+	}
+
+	wanted := make(map[int]bool, len(event.Reminders))
+	if !event.IsTrashed() {
+		for _, minutes := range event.Reminders {
+			wanted[minutes] = true
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, n := range existing {
+		at := event.ReminderTime(n.ReminderMinutes)
+		if !wanted[n.ReminderMinutes] || (!at.Equal(n.TimeUTC) && !at.After(now)) {
+			err = s.deleteReminder(n, username)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		delete(wanted, n.ReminderMinutes)
+		if at.Equal(n.TimeUTC) && n.Title == event.Title && n.Description == event.Description {
+			continue
+		}
+
+		before := n
+		if !at.Equal(n.TimeUTC) {
+			n.Read = false
+			n.Dismissed = false
+			n.SnoozedUntil = nil
+		}
+		n.Title = event.Title
+		n.Description = event.Description
+		n.Time = at
+		n.TimeUTC = at
+
+		n, err = s.Notifications.Update(n.ID, n)
+		if err != nil {
+			return err
+		}
+
+		publish(s.Observers, models.ChangeNotificationUpdated, username, n)
+		err = recordHistory(s.History, models.HistoryResourceNotification, n.ID, n.Version, models.HistoryActionUpdated, username, before, n)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, minutes := range event.Reminders {
+		at := event.ReminderTime(minutes)
+		if !wanted[minutes] || !at.After(now) {
+			continue
+		}
+		delete(wanted, minutes)
+
+		n, err := s.Notifications.Create(models.Notification{
+			Title:           event.Title,
+			Description:     event.Description,
+			Time:            at,
+			TimeUTC:         at,
+			Recipient:       recipient,
+			EventID:         event.ID,
+			ReminderMinutes: minutes,
+		})
+		if err != nil {
+			return err
+		}
+
+		publish(s.Observers, models.ChangeNotificationCreated, username, n)
+		err = recordHistory(s.History, models.HistoryResourceNotification, n.ID, n.Version, models.HistoryActionCreated, username, nil, n)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *NotificationService) DeleteReminders(eventID int, username string) error {
+	existing, err := s.Notifications.GetByEvent(eventID)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range existing {
+		err = s.deleteReminder(n, username)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *NotificationService) deleteReminder(n models.Notification, username string) error {
+	n, err := s.Notifications.Delete(n.ID, 0)
+	if err != nil {
+		return err
+	}
+
+	publish(s.Observers, models.ChangeNotificationDeleted, username, n)
+	return recordHistory(s.History, models.HistoryResourceNotification, n.ID, n.Version+1, models.HistoryActionDeleted, username, n, nil)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/repositories"
+	"workshop2/internal/app/utils"
+)
+
+func newTestEventService(notifications *NotificationService) *EventService {
+	return &EventService{
+		Events:    &repositories.EventRepository{},
+		History:   notifications.History,
+		Reminders: notifications,
+		Validator: utils.NewValidator(),
+	}
+}
+
+func remindersOf(t *testing.T, s *NotificationService, eventID int) map[int]models.Notification {
+	t.Helper()
+
+	notifications, err := s.Notifications.GetByEvent(eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byOffset := make(map[int]models.Notification, len(notifications))
+	for _, n := range notifications {
+		byOffset[n.ReminderMinutes] = n
+	}
+
+	return byOffset
+}
+
+func TestEventRemindersFollowEvent(t *testing.T) {
+	notifications := newTestNotificationService()
+	events := newTestEventService(notifications)
+	start := time.Now().Add(time.Hour * 48).Truncate(time.Minute).UTC()
+
+	event, err := events.Create(models.Event{Title: "Planning", Time: start, Reminders: []int{10, 1440}}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reminders := remindersOf(t, notifications, event.ID)
+	if len(reminders) != 2 {
+		t.Fatalf("expected 2 reminders, got %d", len(reminders))
+	}
+	if !reminders[10].TimeUTC.Equal(start.Add(-10*time.Minute)) || reminders[10].Recipient != "alice" {
+		t.Errorf("unexpected reminder %+v", reminders[10])
+	}
+
+	moved := start.Add(time.Hour)
+	event.Time = moved
+	event.Title = "Planning (moved)"
+	event.Reminders = []int{10, 60}
+	event, err = events.Update(event.ID, event, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reminders = remindersOf(t, notifications, event.ID)
+	if len(reminders) != 2 {
+		t.Fatalf("expected 2 reminders after update, got %d", len(reminders))
+	}
+	if _, ok := reminders[1440]; ok {
+		t.Error("removed reminder offset should be deleted")
+	}
+	if !reminders[10].TimeUTC.Equal(moved.Add(-10*time.Minute)) || reminders[10].Title != event.Title {
+		t.Errorf("reminder was not moved: %+v", reminders[10])
+	}
+	if reminders[60].Recipient != "alice" {
+		t.Errorf("new reminder should go to the existing recipient, got %q", reminders[60].Recipient)
+	}
+
+	err = events.Delete(event.ID, 0, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remindersOf(t, notifications, event.ID)) != 0 {
+		t.Error("reminders should be removed with the event")
+	}
+
+	_, err = events.Restore(event.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remindersOf(t, notifications, event.ID)) != 2 {
+		t.Error("reminders should come back with the restored event")
+	}
+}
+
+func TestEventRemindersSkipPast(t *testing.T) {
+	notifications := newTestNotificationService()
+	events := newTestEventService(notifications)
+
+	event, err := events.Create(models.Event{Title: "Soon", Time: time.Now().Add(time.Minute * 5), Reminders: []int{1, 10}}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reminders := remindersOf(t, notifications, event.ID)
+	if _, ok := reminders[10]; ok || len(reminders) != 1 {
+		t.Errorf("only the future reminder should be created, got %+v", reminders)
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+// StreamEvent is identified by the epoch of the stream that published it
+// and its sequence number within that stream.
+type StreamEvent struct {
+	Epoch     int64
+	ID        int
+	Type      string
+	Recipient string
+	Data      []byte
+}
+
+// EventID is the SSE id of the event, "<epoch>-<sequence>".
+func (e *StreamEvent) EventID() string {
+	return fmt.Sprintf("%d-%d", e.Epoch, e.ID)
+}
+
+func (e *StreamEvent) IsVisibleTo(username string) bool {
+	return e.Recipient == "" || e.Recipient == username
+}
+
+type StreamSubscriber struct {
+	Username string
+	Events   chan StreamEvent
+}
+
+// NotificationStream fans notification changes out to their recipients
+// and keeps a short backlog so reconnecting clients can resume from the
+// last event they have seen. Subscribers that can't keep up are dropped
+// instead of blocking the writers; they are expected to reconnect.
+//
+// Sequence numbers start over with every process, so event IDs carry the
+// epoch the stream was created at.
+type NotificationStream struct {
+	sync.Mutex
+	epoch       int64
+	nextID      int
+	backlog     []StreamEvent
+	backlogSize int
+	bufferSize  int
+	heartbeat   time.Duration
+	subscribers map[*StreamSubscriber]struct{}
+}
+
+func NewNotificationStream(backlogSize int, bufferSize int, heartbeat time.Duration) *NotificationStream {
+	return &NotificationStream{
+		epoch:       time.Now().UnixNano(),
+		backlog:     make([]StreamEvent, 0, backlogSize),
+		backlogSize: backlogSize,
+		bufferSize:  bufferSize,
+		heartbeat:   heartbeat,
+		subscribers: make(map[*StreamSubscriber]struct{}),
+	}
+}
+
+func (s *NotificationStream) Heartbeat() time.Duration {
+	return s.heartbeat
+}
+
+// Name makes the stream the "push" channel of the dispatcher. Nothing
+// reaches the stream any other way, so delivery preferences and quiet
+// hours apply to everything it sends, new notifications included.
+func (s *NotificationStream) Name() string {
+	return "push"
+}
+
+func (s *NotificationStream) Accepts(user models.User) bool {
+	return true
+}
+
+func (s *NotificationStream) Deliver(notification models.Notification, user models.User) error {
+	return s.push(models.ChangeNotificationDue, user.Username, notification)
+}
+
+func (s *NotificationStream) DeliverCreated(notification models.Notification, user models.User) error {
+	return s.push(models.ChangeNotificationCreated, user.Username, notification)
+}
+
+func (s *NotificationStream) DeliverDigest(notifications []models.Notification, user models.User) error {
+	return s.push(models.ChangeNotificationDigest, user.Username, notifications)
+}
+
+func (s *NotificationStream) push(eventType string, recipient string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.nextID++
+	event := StreamEvent{
+		Epoch:     s.epoch,
+		ID:        s.nextID,
+		Type:      eventType,
+		Recipient: recipient,
+		Data:      data,
+	}
+
+	if len(s.backlog) >= s.backlogSize {
+		s.backlog = append(s.backlog[:0], s.backlog[1:]...)
+	}
+	s.backlog = append(s.backlog, event)
+
+	for subscriber := range s.subscribers {
+		if !event.IsVisibleTo(subscriber.Username) {
+			continue
+		}
+
+		select {
+		case subscriber.Events <- event:
+		default:
+			s.remove(subscriber)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe registers a subscriber and returns the backlog of events
+// published after lastEventID. An ID of another epoch was seen before the
+// stream started, so the whole backlog is returned; an ID that can't be
+// parsed is ignored.
+func (s *NotificationStream) Subscribe(username string, lastEventID string) (*StreamSubscriber, []StreamEvent) {
+	s.Lock()
+	defer s.Unlock()
+
+	subscriber := &StreamSubscriber{
+		Username: username,
+		Events:   make(chan StreamEvent, s.bufferSize),
+	}
+	s.subscribers[subscriber] = struct{}{}
+
+	missed := make([]StreamEvent, 0)
+	var epoch int64
+	var seq int
+	if n, err := fmt.Sscanf(lastEventID, "%d-%d", &epoch, &seq); err == nil && n == 2 {
+		if epoch != s.epoch {
+			seq = 0
+		}
+
+		for _, e := range s.backlog {
+			if e.ID > seq && e.IsVisibleTo(username) {
+				missed = append(missed, e)
+			}
+		}
+	}
+
+	return subscriber, missed
+}
+
+func (s *NotificationStream) Unsubscribe(subscriber *StreamSubscriber) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.remove(subscriber)
+}
+
+func (s *NotificationStream) remove(subscriber *StreamSubscriber) {
+	if _, ok := s.subscribers[subscriber]; ok {
+		delete(s.subscribers, subscriber)
+		close(subscriber.Events)
+	}
+}
+
+func (s *NotificationStream) SubscribersCount() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.subscribers)
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+func TestNotificationStream(t *testing.T) {
+	t.Run("resumes after last event id", func(t *testing.T) {
+		stream := NewNotificationStream(2, 4, time.Second)
+		for i := 1; i <= 3; i++ {
+			_ = stream.Deliver(models.Notification{ID: i, Recipient: "alice"}, models.User{Username: "alice"})
+		}
+
+		subscriber, missed := stream.Subscribe("alice", fmt.Sprintf("%d-1", stream.epoch))
+		defer stream.Unsubscribe(subscriber)
+
+		if len(missed) != 2 || missed[0].ID != 2 || missed[1].ID != 3 {
+			t.Errorf("expected events 2 and 3, got %+v", missed)
+		}
+		if id := missed[0].EventID(); id != fmt.Sprintf("%d-2", stream.epoch) {
+			t.Errorf("expected the id to carry the epoch, got %s", id)
+		}
+	})
+
+	t.Run("resumes after a restart", func(t *testing.T) {
+		before := NewNotificationStream(8, 4, time.Second)
+		for i := 1; i <= 5; i++ {
+			_ = before.Deliver(models.Notification{ID: i, Recipient: "alice"}, models.User{Username: "alice"})
+		}
+		lastEventID := before.backlog[4].EventID()
+
+		stream := NewNotificationStream(8, 4, time.Second)
+		stream.epoch = before.epoch + 1
+		for i := 1; i <= 2; i++ {
+			_ = stream.Deliver(models.Notification{ID: i, Recipient: "alice"}, models.User{Username: "alice"})
+		}
+
+		subscriber, missed := stream.Subscribe("alice", lastEventID)
+		defer stream.Unsubscribe(subscriber)
+		if len(missed) != 2 {
+			t.Errorf("expected every event since the restart, got %+v", missed)
+		}
+
+		ignored, missed := stream.Subscribe("alice", "5")
+		defer stream.Unsubscribe(ignored)
+		if len(missed) != 0 {
+			t.Errorf("expected a malformed id to be ignored, got %+v", missed)
+		}
+	})
+
+	t.Run("pushes only to the owner", func(t *testing.T) {
+		stream := NewNotificationStream(8, 4, time.Second)
+		alice, _ := stream.Subscribe("alice", "")
+		defer stream.Unsubscribe(alice)
+		bob, _ := stream.Subscribe("bob", "")
+		defer stream.Unsubscribe(bob)
+
+		_ = stream.Deliver(models.Notification{ID: 1, Recipient: "alice"}, models.User{Username: "alice"})
+		_ = stream.DeliverDigest([]models.Notification{{ID: 1, Recipient: "alice"}}, models.User{Username: "alice"})
+		_ = stream.Deliver(models.Notification{ID: 2, Recipient: "carol"}, models.User{Username: "carol"})
+
+		if len(alice.Events) != 2 {
+			t.Errorf("expected the owner to receive the due event and the digest, got %d", len(alice.Events))
+		}
+		if len(bob.Events) != 0 {
+			t.Errorf("other users must not receive the notification, got %d events", len(bob.Events))
+		}
+
+		resumed, missed := stream.Subscribe("bob", fmt.Sprintf("%d-1", stream.epoch))
+		defer stream.Unsubscribe(resumed)
+		if len(missed) != 0 {
+			t.Errorf("other users must not resume the notification, got %+v", missed)
+		}
+	})
+
+	t.Run("follows delivery preferences", func(t *testing.T) {
+		stream := NewNotificationStream(8, 4, time.Second)
+		subscriber, _ := stream.Subscribe("alice", "")
+		defer stream.Unsubscribe(subscriber)
+
+		d := newTestDispatcher(t, models.User{
+			Username: "alice",
+			Password: "passw0rd!",
+			Timezone: "UTC",
+			Preferences: models.NotificationPreferences{
+				Channels: map[string][]string{models.NotificationTypeGeneral: {"email"}},
+			},
+		}, stream)
+
+		notification := models.Notification{ID: 1, Recipient: "alice"}
+		d.Notify(models.Change{Type: models.ChangeNotificationCreated, Actor: "alice", Data: notification})
+		d.Notify(due(notification))
+		drain(d)
+		if len(subscriber.Events) != 0 {
+			t.Errorf("muted notifications must not be streamed, got %d events", len(subscriber.Events))
+		}
+
+		d.Notify(due(models.Notification{ID: 2, Recipient: "alice", EventID: 7}))
+		drain(d)
+		if len(subscriber.Events) != 1 {
+			t.Errorf("expected the reminder to be streamed, got %d events", len(subscriber.Events))
+		}
+	})
+
+	t.Run("pushes new notifications", func(t *testing.T) {
+		stream := NewNotificationStream(8, 4, time.Second)
+		subscriber, _ := stream.Subscribe("alice", "")
+		defer stream.Unsubscribe(subscriber)
+
+		email := &recordingChannel{name: "email"}
+		d := newTestDispatcher(t, models.User{Username: "alice", Password: "passw0rd!", Timezone: "UTC"}, email, stream)
+
+		d.Notify(models.Change{Type: models.ChangeNotificationCreated, Actor: "alice", Data: models.Notification{ID: 1, Recipient: "alice"}})
+		drain(d)
+		if len(subscriber.Events) != 1 {
+			t.Fatalf("expected the new notification to be streamed, got %d events", len(subscriber.Events))
+		}
+		if event := <-subscriber.Events; event.Type != models.ChangeNotificationCreated {
+			t.Errorf("expected a created event, got %s", event.Type)
+		}
+		if len(email.delivered) != 0 {
+			t.Errorf("new notifications must not be mailed, got %+v", email.delivered)
+		}
+	})
+
+	t.Run("keeps new notifications quiet", func(t *testing.T) {
+		stream := NewNotificationStream(8, 4, time.Second)
+		subscriber, _ := stream.Subscribe("alice", "")
+		defer stream.Unsubscribe(subscriber)
+
+		now := time.Now().UTC()
+		d := newTestDispatcher(t, models.User{
+			Username: "alice",
+			Password: "passw0rd!",
+			Timezone: "UTC",
+			Preferences: models.NotificationPreferences{
+				QuietHours: &models.QuietHours{
+					Start: now.Add(-time.Hour).Format("15:04"),
+					End:   now.Add(time.Hour).Format("15:04"),
+				},
+			},
+		}, stream)
+
+		d.Notify(models.Change{Type: models.ChangeNotificationCreated, Actor: "alice", Data: models.Notification{ID: 1, Recipient: "alice"}})
+		drain(d)
+		if len(subscriber.Events) != 0 {
+			t.Errorf("new notifications must not be streamed during quiet hours, got %d events", len(subscriber.Events))
+		}
+	})
+
+	t.Run("drops slow subscriber", func(t *testing.T) {
+		stream := NewNotificationStream(8, 1, time.Second)
+		subscriber, _ := stream.Subscribe("alice", "")
+
+		stream.Deliver(models.Notification{ID: 1}, models.User{Username: "alice"})
+		stream.Deliver(models.Notification{ID: 2}, models.User{Username: "alice"})
+
+		if stream.SubscribersCount() != 0 {
+			t.Errorf("slow subscriber must be dropped")
+		}
+
+		<-subscriber.Events
+		if _, ok := <-subscriber.Events; ok {
+			t.Errorf("events channel must be closed")
+		}
+
+		stream.Unsubscribe(subscriber)
+	})
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+type TimeWindow struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+func (w *TimeWindow) Contains(t time.Time) bool {
+	return !t.Before(w.From) && t.Before(w.To)
+}
+
+type SyncSession struct {
+	// ID is sent along with the requests of the client, so the session
+	// isn't told about changes it made itself.
+	ID       string
+	Username string
+	Changes  chan models.Change
+	windows  []TimeWindow
+	mu       sync.RWMutex
+}
+
+// SetWindows limits the session to changes of events inside the given
+// time windows; no windows means every change is delivered.
+func (s *SyncSession) SetWindows(windows []TimeWindow) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.windows = windows
+}
+
+func (s *SyncSession) Matches(change models.Change) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.windows) == 0 {
+		return true
+	}
+
+	event, ok := change.Data.(models.Event)
+	if !ok {
+		return false
+	}
+
+	for _, w := range s.windows {
+		if w.Contains(event.TimeUTC) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EventSync forwards event changes to the live sessions of the event
+// owner, except the session the change was made from. Sessions whose
+// buffer is full are closed so that a slow client never blocks the
+// writers.
+type EventSync struct {
+	sync.Mutex
+	bufferSize int
+	sessions   map[string]map[*SyncSession]struct{}
+}
+
+func NewEventSync(bufferSize int) *EventSync {
+	return &EventSync{
+		bufferSize: bufferSize,
+		sessions:   make(map[string]map[*SyncSession]struct{}),
+	}
+}
+
+func (s *EventSync) Register(username string) (*SyncSession, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	session := &SyncSession{
+		ID:       hex.EncodeToString(id),
+		Username: username,
+		Changes:  make(chan models.Change, s.bufferSize),
+	}
+
+	if s.sessions[username] == nil {
+		s.sessions[username] = make(map[*SyncSession]struct{})
+	}
+	s.sessions[username][session] = struct{}{}
+
+	return session, nil
+}
+
+func (s *EventSync) Unregister(session *SyncSession) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.remove(session)
+}
+
+func (s *EventSync) remove(session *SyncSession) {
+	sessions := s.sessions[session.Username]
+	if _, ok := sessions[session]; !ok {
+		return
+	}
+
+	delete(sessions, session)
+	if len(sessions) == 0 {
+		delete(s.sessions, session.Username)
+	}
+	close(session.Changes)
+}
+
+func (s *EventSync) Notify(change models.Change) {
+	switch change.Type {
+	case models.ChangeEventCreated, models.ChangeEventUpdated, models.ChangeEventDeleted, models.ChangeEventRestored:
+	default:
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for session := range s.sessions[changeRecipient(change)] {
+		if session.ID == change.Session || !session.Matches(change) {
+			continue
+		}
+
+		select {
+		case session.Changes <- change:
+		default:
+			s.remove(session)
+		}
+	}
+}
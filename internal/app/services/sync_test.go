@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"workshop2/internal/app/models"
+)
+
+func TestEventSync(t *testing.T) {
+	day := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	change := func(owner string, at time.Time) models.Change {
+		return models.Change{Type: models.ChangeEventCreated, Actor: owner, Data: models.Event{Owner: owner, TimeUTC: at}}
+	}
+	register := func(t *testing.T, hub *EventSync, username string) *SyncSession {
+		session, err := hub.Register(username)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return session
+	}
+
+	t.Run("delivers only to sessions of the owner", func(t *testing.T) {
+		hub := NewEventSync(4)
+		alice := register(t, hub, "alice")
+		bob := register(t, hub, "bob")
+		defer hub.Unregister(alice)
+		defer hub.Unregister(bob)
+
+		hub.Notify(change("alice", day))
+
+		if len(alice.Changes) != 1 || len(bob.Changes) != 0 {
+			t.Errorf("change must reach only alice's sessions")
+		}
+	})
+
+	t.Run("skips the session the change was made from", func(t *testing.T) {
+		hub := NewEventSync(4)
+		phone := register(t, hub, "alice")
+		laptop := register(t, hub, "alice")
+		defer hub.Unregister(phone)
+		defer hub.Unregister(laptop)
+
+		c := change("alice", day)
+		c.Session = phone.ID
+		hub.Notify(c)
+
+		if len(phone.Changes) != 0 || len(laptop.Changes) != 1 {
+			t.Errorf("expected only the other session to get the change, got %d and %d", len(phone.Changes), len(laptop.Changes))
+		}
+	})
+
+	t.Run("filters by subscribed windows", func(t *testing.T) {
+		hub := NewEventSync(4)
+		session := register(t, hub, "alice")
+		defer hub.Unregister(session)
+
+		session.SetWindows([]TimeWindow{{From: day, To: day.AddDate(0, 0, 1)}})
+		hub.Notify(change("alice", day.Add(time.Hour)))
+		hub.Notify(change("alice", day.AddDate(0, 0, 2)))
+
+		if len(session.Changes) != 1 {
+			t.Errorf("expected a single change inside the window, got %d", len(session.Changes))
+		}
+	})
+
+	t.Run("closes slow session without blocking", func(t *testing.T) {
+		hub := NewEventSync(1)
+		session := register(t, hub, "alice")
+
+		hub.Notify(change("alice", day))
+		hub.Notify(change("alice", day))
+
+		<-session.Changes
+		if _, ok := <-session.Changes; ok {
+			t.Errorf("slow session must be closed")
+		}
+
+		hub.Unregister(session)
+	})
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/utils"
+)
+
+// maxRenderedSize bounds the output of a single template field so a
+// template can't build arbitrarily large strings.
+const maxRenderedSize = 16 * 1024
+
+// maxTemplateNodes bounds the size of a template. Loops and calls of
+// other templates are not allowed, so rendering takes time linear in it.
+const maxTemplateNodes = 256
+
+var (
+	errRenderedTooLarge  = errors.New("rendered text is too large")
+	errTemplateTooLarge  = errors.New("template is too large")
+	errTemplateLoop      = errors.New("range is not supported")
+	errTemplateInclusion = errors.New("defining and calling templates is not supported")
+)
+
+type TemplateRepositoryInterface interface {
+	GetAll() ([]models.NotificationTemplate, error)
+	Get(id int) (models.NotificationTemplate, error)
+	Create(template models.NotificationTemplate) (models.NotificationTemplate, error)
+	Update(template models.NotificationTemplate) (models.NotificationTemplate, error)
+	Delete(id int) error
+}
+
+type NotificationCreatorInterface interface {
+	Create(notification models.Notification, username string) (models.Notification, error)
+}
+
+type TemplateService struct {
+	Templates     TemplateRepositoryInterface
+	Events        EventRepositoryInterface
+	Users         UserRepositoryInterface
+	Notifications NotificationCreatorInterface
+	Validator     utils.ValidatorInterface
+}
+
+func (s *TemplateService) GetAll(owner string) ([]models.NotificationTemplate, error) {
+	templates, err := s.Templates.GetAll()
+	if err != nil {
+		return templates, err
+	}
+
+	owned := make([]models.NotificationTemplate, 0)
+	for _, t := range templates {
+		if t.Owner == owner {
+			owned = append(owned, t)
+		}
+	}
+
+	return owned, nil
+}
+
+func (s *TemplateService) Get(id int, owner string) (models.NotificationTemplate, error) {
+	template, err := s.Templates.Get(id)
+	if err != nil {
+		return models.NotificationTemplate{}, err
+	}
+
+	if template.Owner != owner {
+		return models.NotificationTemplate{}, errs.NewTemplateNotFoundError()
+	}
+
+	return template, nil
+}
+
+func (s *TemplateService) Create(template models.NotificationTemplate, owner string) (models.NotificationTemplate, error) {
+	err := s.validate(template)
+	if err != nil {
+		return template, err
+	}
+
+	template.Owner = owner
+	template.CreatedAt = time.Now().UTC()
+	template.UpdatedAt = template.CreatedAt
+
+	return s.Templates.Create(template)
+}
+
+func (s *TemplateService) Update(id int, template models.NotificationTemplate, owner string) (models.NotificationTemplate, error) {
+	current, err := s.Get(id, owner)
+	if err != nil {
+		return template, err
+	}
+
+	err = s.validate(template)
+	if err != nil {
+		return template, err
+	}
+
+	template.ID = id
+	template.Owner = owner
+	template.CreatedAt = current.CreatedAt
+	template.UpdatedAt = time.Now().UTC()
+
+	return s.Templates.Update(template)
+}
+
+func (s *TemplateService) Delete(id int, owner string) error {
+	_, err := s.Get(id, owner)
+	if err != nil {
+		return err
+	}
+
+	return s.Templates.Delete(id)
+}
+
+// Preview renders the template into a notification without saving it.
+func (s *TemplateService) Preview(id int, request models.TemplateRenderRequest, username string) (models.Notification, error) {
+	notification, err := s.render(id, request, username)
+	if err != nil {
+		return notification, err
+	}
+
+	err = s.Validator.Struct(notification)
+	if err != nil {
+		return notification, errs.NewNotificationValidationError(err)
+	}
+
+	notification.TimeUTC = notification.Time.UTC()
+	notification.Recipient = username
+
+	return notification, nil
+}
+
+func (s *TemplateService) Render(id int, request models.TemplateRenderRequest, username string) (models.Notification, error) {
+	notification, err := s.render(id, request, username)
+	if err != nil {
+		return notification, err
+	}
+
+	return s.Notifications.Create(notification, username)
+}
+
+func (s *TemplateService) validate(template models.NotificationTemplate) error {
+	err := s.Validator.Struct(template)
+	if err != nil {
+		return errs.NewTemplateValidationError(err)
+	}
+
+	for _, text := range []string{template.Title, template.Description} {
+		_, err = parseNotificationTemplate(text, templateFuncs(nil, models.User{}, time.Time{}))
+		if err != nil {
+			return errs.NewBadTemplateError(err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (s *TemplateService) render(id int, request models.TemplateRenderRequest, username string) (models.Notification, error) {
+	err := s.Validator.Struct(request)
+	if err != nil {
+		return models.Notification{}, errs.NewTemplateValidationError(err)
+	}
+
+	template, err := s.Get(id, username)
+	if err != nil {
+		return models.Notification{}, err
+	}
+
+	user, err := s.Users.Get(username)
+	if err != nil {
+		return models.Notification{}, err
+	}
+
+	var event *models.Event
+	if request.EventID != 0 {
+		e, err := getOwnedEvent(s.Events, request.EventID, username)
+		if err != nil {
+			return models.Notification{}, err
+		}
+		event = &e
+	}
+
+	funcs := templateFuncs(event, user, request.Time)
+	title, err := renderNotificationTemplate(template.Title, funcs)
+	if err != nil {
+		return models.Notification{}, errs.NewBadTemplateError(err.Error())
+	}
+
+	description, err := renderNotificationTemplate(template.Description, funcs)
+	if err != nil {
+		return models.Notification{}, errs.NewBadTemplateError(err.Error())
+	}
+
+	return models.Notification{
+		Title:       strings.TrimSpace(title),
+		Description: strings.TrimSpace(description),
+		Time:        request.Time,
+	}, nil
+}
+
+// templateFuncs exposes the render context as functions, which lets
+// templates use placeholders such as {{event.title}} and
+// {{time | format "Mon 15:04"}}. Times are given in the user's timezone.
+func templateFuncs(event *models.Event, user models.User, at time.Time) template.FuncMap {
+	loc := user.Location()
+
+	return template.FuncMap{
+		"event": func() (map[string]interface{}, error) {
+			if event == nil {
+				return nil, errors.New("no event given to render the template with")
+			}
+
+			return map[string]interface{}{
+				"id":          event.ID,
+				"title":       event.Title,
+				"description": event.Description,
+				"time":        event.TimeUTC.In(loc),
+			}, nil
+		},
+		"user": func() map[string]interface{} {
+			return map[string]interface{}{
+				"username": user.Username,
+				"timezone": user.Timezone,
+				"email":    user.Email,
+			}
+		},
+		"time": func() time.Time {
+			return at.In(loc)
+		},
+		"now": func() time.Time {
+			return time.Now().In(loc)
+		},
+		"format": func(layout string, t time.Time) string {
+			return t.Format(layout)
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"default": func(fallback string, value interface{}) interface{} {
+			if value == nil || value == "" {
+				return fallback
+			}
+
+			return value
+		},
+	}
+}
+
+func parseNotificationTemplate(text string, funcs template.FuncMap) (*template.Template, error) {
+	t, err := template.New("notification").Option("missingkey=error").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(t.Templates()) > 1 {
+		return nil, errTemplateInclusion
+	}
+
+	if t.Tree != nil {
+		nodes := 0
+		if err = checkTemplateNode(t.Tree.Root, &nodes); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// checkTemplateNode rejects what would let a template run for arbitrarily
+// long, counting the nodes it has seen in nodes.
+func checkTemplateNode(node parse.Node, nodes *int) error {
+	*nodes++
+	if *nodes > maxTemplateNodes {
+		return errTemplateTooLarge
+	}
+
+	var children []parse.Node
+	switch n := node.(type) {
+	case *parse.RangeNode:
+		return errTemplateLoop
+	case *parse.TemplateNode:
+		return errTemplateInclusion
+	case *parse.ListNode:
+		if n != nil {
+			children = n.Nodes
+		}
+	case *parse.ActionNode:
+		children = []parse.Node{n.Pipe}
+	case *parse.IfNode:
+		children = []parse.Node{n.Pipe, n.List, n.ElseList}
+	case *parse.WithNode:
+		children = []parse.Node{n.Pipe, n.List, n.ElseList}
+	case *parse.PipeNode:
+		if n != nil {
+			for _, c := range n.Cmds {
+				children = append(children, c)
+			}
+		}
+	case *parse.CommandNode:
+		children = n.Args
+	case *parse.ChainNode:
+		children = []parse.Node{n.Node}
+	}
+
+	for _, c := range children {
+		if err := checkTemplateNode(c, nodes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func renderNotificationTemplate(text string, funcs template.FuncMap) (string, error) {
+	t, err := parseNotificationTemplate(text, funcs)
+	if err != nil {
+		return "", err
+	}
+
+	out := &limitedBuffer{limit: maxRenderedSize}
+	err = t.Execute(out, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errRenderedTooLarge
+	}
+
+	return b.Buffer.Write(p)
+}
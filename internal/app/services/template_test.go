@@ -0,0 +1,147 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/repositories"
+	"workshop2/internal/app/utils"
+)
+
+func newTestTemplateService(t *testing.T) *TemplateService {
+	t.Helper()
+
+	validator := utils.NewValidator()
+	users := &repositories.UserRepository{Validator: validator}
+	_, err := users.Create(models.User{Username: "alice", Password: "passw0rd!", Timezone: "Europe/Kiev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := &repositories.EventRepository{}
+	_, err = events.Create(models.Event{Title: "Retro", Owner: "alice", TimeUTC: time.Date(2021, 3, 1, 13, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifications := newTestNotificationService()
+
+	return &TemplateService{
+		Templates:     &repositories.TemplateRepository{},
+		Events:        events,
+		Users:         users,
+		Notifications: notifications,
+		Validator:     validator,
+	}
+}
+
+func TestTemplatePreview(t *testing.T) {
+	s := newTestTemplateService(t)
+
+	template, err := s.Create(models.NotificationTemplate{
+		Name:        "reminder",
+		Title:       `{{event.title}} at {{event.time | format "15:04"}}`,
+		Description: `Hi {{user.username}}, see you {{time | format "Mon 15:04"}}`,
+	}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := models.TemplateRenderRequest{EventID: 1, Time: time.Date(2021, 3, 1, 12, 50, 0, 0, time.UTC)}
+	notification, err := s.Preview(template.ID, request, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if notification.Title != "Retro at 15:00" {
+		t.Errorf("unexpected title %q", notification.Title)
+	}
+	if notification.Description != "Hi alice, see you Mon 14:50" {
+		t.Errorf("unexpected description %q", notification.Description)
+	}
+
+	all, _ := s.Notifications.(*NotificationService).Notifications.GetAll()
+	if len(all) != 0 {
+		t.Error("preview must not save the notification")
+	}
+
+	_, err = s.Render(template.ID, request, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, _ = s.Notifications.(*NotificationService).Notifications.GetAll()
+	if len(all) != 1 {
+		t.Error("render should save the notification")
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	s := newTestTemplateService(t)
+
+	_, err := s.Create(models.NotificationTemplate{Name: "broken", Title: "{{event.title"}, "alice")
+	if _, ok := err.(*errs.BadTemplateError); !ok {
+		t.Errorf("expected a bad template error, got %v", err)
+	}
+
+	_, err = s.Create(models.NotificationTemplate{Name: "unknown", Title: "{{exec}}"}, "alice")
+	if _, ok := err.(*errs.BadTemplateError); !ok {
+		t.Errorf("unknown functions should be rejected, got %v", err)
+	}
+
+	template, _ := s.Create(models.NotificationTemplate{Name: "event", Title: "{{event.title}}"}, "alice")
+	_, err = s.Preview(template.ID, models.TemplateRenderRequest{Time: time.Now()}, "alice")
+	if _, ok := err.(*errs.BadTemplateError); !ok {
+		t.Errorf("rendering without an event should fail, got %v", err)
+	}
+
+	huge, _ := s.Create(models.NotificationTemplate{Name: "huge", Title: `{{printf "%099999d" 1}}`}, "alice")
+	_, err = s.Preview(huge.ID, models.TemplateRenderRequest{Time: time.Now()}, "alice")
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("oversized output should be rejected, got %v", err)
+	}
+
+	if _, err = s.Get(template.ID, "bob"); err == nil {
+		t.Error("templates of other users must not be visible")
+	}
+}
+
+func TestTemplateExecutionBounds(t *testing.T) {
+	s := newTestTemplateService(t)
+
+	hostile := map[string]string{
+		"range":   `{{range 300000000}}{{range 300000000}}x{{end}}{{end}}`,
+		"define":  `{{define "a"}}{{template "b"}}{{template "b"}}{{end}}{{define "b"}}x{{end}}{{template "a"}}`,
+		"block":   `{{block "a" .}}x{{end}}`,
+		"nodes":   strings.Repeat(`{{upper "x"}}`, maxTemplateNodes),
+		"nesting": strings.Repeat(`{{if true}}`, maxTemplateNodes) + strings.Repeat(`{{end}}`, maxTemplateNodes),
+	}
+
+	for name, text := range hostile {
+		if _, err := s.Create(models.NotificationTemplate{Name: name, Title: text}, "alice"); err == nil {
+			t.Errorf("%s: expected the template to be refused", name)
+		}
+
+		// Templates stored before the checks are still bounded when rendered.
+		stored, err := s.Templates.Create(models.NotificationTemplate{Name: name, Title: text, Owner: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		_, err = s.Preview(stored.ID, models.TemplateRenderRequest{Time: time.Now()}, "alice")
+		if _, ok := err.(*errs.BadTemplateError); !ok {
+			t.Errorf("%s: expected a bad template error, got %v", name, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: expected a quick rejection, took %s", name, elapsed)
+		}
+	}
+
+	fine := `{{with event}}{{.title | upper}}{{else}}{{user.username}}{{end}} at {{time | format "15:04"}}`
+	if _, err := s.Create(models.NotificationTemplate{Name: "fine", Title: fine}, "alice"); err != nil {
+		t.Errorf("expected a plain template to pass, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"workshop2/internal/app/models"
+)
+
+// Store holds the repositories a transaction can write to.
+type Store struct {
+	Events        EventRepositoryInterface
+	Notifications NotificationRepositoryInterface
+	Users         UserRepositoryInterface
+}
+
+// TransactorInterface runs fn as one unit of work: everything fn writes
+// through the given store is committed together when it returns nil and
+// discarded when it returns an error.
+type TransactorInterface interface {
+	Transaction(fn func(store Store) error) error
+}
+
+// TransactorFunc lets a plain function serve as a TransactorInterface.
+type TransactorFunc func(fn func(store Store) error) error
+
+func (f TransactorFunc) Transaction(fn func(store Store) error) error {
+	return f(fn)
+}
+
+// unitOfWork is handed to the services while a transaction runs. Changes
+// published and history recorded through it are held back until commit,
+// so nobody hears about writes that end up rolled back.
+type unitOfWork struct {
+	Store
+	effects []func() error
+}
+
+// inTransaction runs fn in a transaction of t and then applies the held
+// back side effects.
+func inTransaction(t TransactorInterface, fn func(u *unitOfWork) error) error {
+	var u *unitOfWork
+	err := t.Transaction(func(store Store) error {
+		u = &unitOfWork{Store: store}
+		return fn(u)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, effect := range u.effects {
+		if err = effect(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *unitOfWork) observers(observers []ObserverInterface) []ObserverInterface {
+	deferred := make([]ObserverInterface, len(observers))
+	for i, o := range observers {
+		deferred[i] = deferredObserver{o, u}
+	}
+
+	return deferred
+}
+
+func (u *unitOfWork) history(history HistoryRepositoryInterface) HistoryRepositoryInterface {
+	if history == nil {
+		return nil
+	}
+
+	return deferredHistory{history, u}
+}
+
+type deferredObserver struct {
+	observer ObserverInterface
+	unit     *unitOfWork
+}
+
+func (o deferredObserver) Notify(change models.Change) {
+	o.unit.effects = append(o.unit.effects, func() error {
+		o.observer.Notify(change)
+		return nil
+	})
+}
+
+type deferredHistory struct {
+	HistoryRepositoryInterface
+	unit *unitOfWork
+}
+
+func (h deferredHistory) Create(entry models.HistoryEntry) (models.HistoryEntry, error) {
+	h.unit.effects = append(h.unit.effects, func() error {
+		_, err := h.HistoryRepositoryInterface.Create(entry)
+		return err
+	})
+
+	return entry, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/repositories"
+	"workshop2/internal/app/utils"
+
+	"github.com/golang-jwt/jwt"
+)
+
+type failingNotificationCreates struct {
+	NotificationRepositoryInterface
+}
+
+func (r failingNotificationCreates) Create(notification models.Notification) (models.Notification, error) {
+	return notification, errors.New("create failed")
+}
+
+type failingNotificationUpdate struct {
+	NotificationRepositoryInterface
+	id int
+}
+
+func (r failingNotificationUpdate) Update(id int, notification models.Notification) (models.Notification, error) {
+	if id == r.id {
+		return notification, errors.New("update failed")
+	}
+
+	return r.NotificationRepositoryInterface.Update(id, notification)
+}
+
+func newTestTransactor(store *repositories.MemoryStore, wrap func(tx Store) Store) TransactorInterface {
+	return TransactorFunc(func(fn func(store Store) error) error {
+		return store.Transaction(func(tx repositories.MemoryTx) error {
+			return fn(wrap(Store{Events: tx.Events, Notifications: tx.Notifications, Users: tx.Users}))
+		})
+	})
+}
+
+func TestEventCreateRollsBackWithReminders(t *testing.T) {
+	store := &repositories.MemoryStore{
+		Events:        &repositories.EventRepository{},
+		Notifications: &repositories.NotificationRepository{},
+	}
+	recorder := &changeRecorder{}
+
+	notifications := newTestNotificationService()
+	notifications.Notifications = store.Notifications
+	notifications.Observers = []ObserverInterface{recorder}
+
+	events := newTestEventService(notifications)
+	events.Events = store.Events
+	events.Observers = []ObserverInterface{recorder}
+
+	start := time.Now().Add(time.Hour * 48).UTC()
+	fail := true
+	events.Transactor = newTestTransactor(store, func(tx Store) Store {
+		if fail {
+			tx.Notifications = failingNotificationCreates{tx.Notifications}
+		}
+		return tx
+	})
+
+	_, err := events.Create(models.Event{Title: "Planning", Time: start, Reminders: []int{10}}, "alice")
+	if err == nil {
+		t.Fatal("expected the reminder failure to fail the create")
+	}
+
+	if all, _ := store.Events.GetAll(); len(all) != 0 {
+		t.Errorf("event must not persist without its reminders")
+	}
+	if len(recorder.changes) != 0 {
+		t.Errorf("rolled back writes must not be published, got %d changes", len(recorder.changes))
+	}
+	if entries, _ := events.History.GetByResource(models.HistoryResourceEvent, 1); len(entries) != 0 {
+		t.Errorf("rolled back writes must not be recorded in history")
+	}
+
+	fail = false
+	event, err := events.Create(models.Event{Title: "Planning", Time: start, Reminders: []int{10}}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reminders := remindersOf(t, notifications, event.ID); len(reminders) != 1 {
+		t.Errorf("expected one reminder, got %d", len(reminders))
+	}
+	if recorder.count(models.ChangeEventCreated) != 1 || recorder.count(models.ChangeNotificationCreated) != 1 {
+		t.Errorf("committed writes should be published once, got %+v", recorder.changes)
+	}
+	if entries, _ := events.History.GetByResource(models.HistoryResourceEvent, event.ID); len(entries) != 1 {
+		t.Errorf("committed create should be recorded in history")
+	}
+}
+
+func TestSignUpRollsBackWhenTokensFail(t *testing.T) {
+	validator := utils.NewValidator()
+	store := &repositories.MemoryStore{Users: &repositories.UserRepository{Validator: validator}}
+	auth := NewAuth(store.Users, validator, time.Hour, time.Hour*24, "test", jwt.SigningMethodRS256)
+	auth.Transactor = newTestTransactor(store, func(tx Store) Store {
+		return tx
+	})
+
+	_, err := auth.SignUp(models.SignUp{
+		Username:       "alice",
+		Password:       "passw0rd!",
+		RepeatPassword: "passw0rd!",
+		Timezone:       "UTC",
+	})
+	if err == nil {
+		t.Fatal("expected token generation to fail")
+	}
+
+	if _, err = store.Users.Get("alice"); err == nil {
+		t.Errorf("user must not be created when no tokens could be issued")
+	}
+}
+
+func TestMarkAllReadRollsBack(t *testing.T) {
+	store := &repositories.MemoryStore{Notifications: &repositories.NotificationRepository{}}
+	recorder := &changeRecorder{}
+
+	s := newTestNotificationService()
+	s.Notifications = store.Notifications
+	past := time.Now().Add(-time.Hour)
+	for _, title := range []string{"first", "second", "third"} {
+		if _, err := s.Create(models.Notification{Title: title, Time: past}, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.Observers = []ObserverInterface{recorder}
+	s.Transactor = newTestTransactor(store, func(tx Store) Store {
+		tx.Notifications = failingNotificationUpdate{tx.Notifications, 2}
+		return tx
+	})
+
+	marked, err := s.MarkAllRead("alice")
+	if err == nil || marked != 0 {
+		t.Fatalf("expected the failed update to be reported, got %d marked and %v", marked, err)
+	}
+
+	if count, _ := s.UnreadCount("alice"); count != 3 {
+		t.Errorf("expected every notification to stay unread, got %d unread", count)
+	}
+	if len(recorder.changes) != 0 {
+		t.Errorf("expected no changes to be published, got %+v", recorder.changes)
+	}
+}
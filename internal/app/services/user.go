@@ -1,20 +1,29 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"workshop2/internal/app/errs"
 	"workshop2/internal/app/models"
 )
 
 type UserRepositoryInterface interface {
+	GetAll() ([]models.User, error)
 	Create(user models.User) (models.User, error)
 	Get(username string) (models.User, error)
 	Update(models.User) error
 }
 
 type UserService struct {
-	Users UserRepositoryInterface
+	Users  UserRepositoryInterface
+	Mailer MailerInterface
 }
 
 func (s *UserService) Create(user models.User) (models.User, error) {
+	user.EmailVerified = false
+	user.EmailVerificationToken = ""
+
 	return s.Users.Create(user)
 }
 
@@ -28,3 +37,85 @@ func (s *UserService) UpdateTimezone(username string, timezone string) error {
 
 	return s.Users.Update(user)
 }
+
+// UpdateEmail stores an unverified address and mails it a verification
+// token that has to be confirmed through VerifyEmail.
+func (s *UserService) UpdateEmail(username string, email string) error {
+	user, err := s.Users.Get(username)
+	if err != nil {
+		return err
+	}
+
+	token := make([]byte, 16)
+	if _, err = rand.Read(token); err != nil {
+		return err
+	}
+
+	user.Email = email
+	user.EmailVerified = false
+	user.EmailVerificationToken = hex.EncodeToString(token)
+
+	err = s.Users.Update(user)
+	if err != nil {
+		return err
+	}
+
+	message, err := buildMultipartEmail(
+		s.Mailer.From(),
+		user.Email,
+		"Confirm your email address",
+		"Your verification code: "+user.EmailVerificationToken+"\n",
+		"<p>Your verification code: <strong>"+user.EmailVerificationToken+"</strong></p>\n",
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(user.Email, message)
+}
+
+func (s *UserService) VerifyEmail(username string, token string) error {
+	user, err := s.Users.Get(username)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerificationToken == "" || subtle.ConstantTimeCompare([]byte(user.EmailVerificationToken), []byte(token)) != 1 {
+		return errs.NewBadVerificationTokenError()
+	}
+
+	user.EmailVerified = true
+	user.EmailVerificationToken = ""
+
+	return s.Users.Update(user)
+}
+
+func (s *UserService) GetPreferences(username string) (models.NotificationPreferences, error) {
+	user, err := s.Users.Get(username)
+	if err != nil {
+		return models.NotificationPreferences{}, err
+	}
+
+	return user.Preferences, nil
+}
+
+func (s *UserService) UpdatePreferences(username string, preferences models.NotificationPreferences) (models.NotificationPreferences, error) {
+	if preferences.QuietHours != nil {
+		if _, _, err := preferences.QuietHours.Parse(); err != nil {
+			return preferences, errs.NewBadQuietHoursError()
+		}
+	}
+
+	if preferences.Digest == "" {
+		preferences.Digest = models.DigestOff
+	}
+
+	user, err := s.Users.Get(username)
+	if err != nil {
+		return preferences, err
+	}
+
+	user.Preferences = preferences
+
+	return preferences, s.Users.Update(user)
+}
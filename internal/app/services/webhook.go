@@ -0,0 +1,309 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/utils"
+)
+
+const (
+	WebhookSignatureHeader = "X-Workshop2-Signature"
+	WebhookEventHeader     = "X-Workshop2-Event"
+	WebhookDeliveryHeader  = "X-Workshop2-Delivery"
+)
+
+type WebhookRepositoryInterface interface {
+	GetAll() ([]models.Webhook, error)
+	Get(id int) (models.Webhook, error)
+	Create(webhook models.Webhook) (models.Webhook, error)
+	Update(webhook models.Webhook) (models.Webhook, error)
+	Delete(id int) error
+	RecordDeliveryResult(id int, success bool, disableAfter int) (models.Webhook, error)
+	AddDelivery(delivery models.WebhookDelivery) (models.WebhookDelivery, error)
+	GetDeliveries(webhookID int) ([]models.WebhookDelivery, error)
+}
+
+type webhookJob struct {
+	webhookID  int
+	deliveryID string
+	eventType  string
+	payload    []byte
+	attempt    int
+}
+
+type WebhookService struct {
+	Webhooks     WebhookRepositoryInterface
+	Validator    utils.ValidatorInterface
+	targets      WebhookTargets
+	client       *http.Client
+	maxAttempts  int
+	backoff      time.Duration
+	disableAfter int
+	queue        chan webhookJob
+}
+
+func NewWebhook(wr WebhookRepositoryInterface, val utils.ValidatorInterface, targets WebhookTargets, timeout time.Duration, maxAttempts int, backoff time.Duration, disableAfter int, queueSize int) *WebhookService {
+	return &WebhookService{
+		Webhooks:     wr,
+		Validator:    val,
+		targets:      targets,
+		client:       targets.Client(timeout),
+		maxAttempts:  maxAttempts,
+		backoff:      backoff,
+		disableAfter: disableAfter,
+		queue:        make(chan webhookJob, queueSize),
+	}
+}
+
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookService) GetAll(owner string) ([]models.Webhook, error) {
+	webhooks, err := s.Webhooks.GetAll()
+	if err != nil {
+		return webhooks, err
+	}
+
+	owned := make([]models.Webhook, 0)
+	for _, w := range webhooks {
+		if w.Owner == owner {
+			w.Secret = ""
+			owned = append(owned, w)
+		}
+	}
+
+	return owned, nil
+}
+
+func (s *WebhookService) Get(id int, owner string) (models.Webhook, error) {
+	webhook, err := s.get(id, owner)
+	webhook.Secret = ""
+
+	return webhook, err
+}
+
+func (s *WebhookService) get(id int, owner string) (models.Webhook, error) {
+	webhook, err := s.Webhooks.Get(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	if webhook.Owner != owner {
+		return models.Webhook{}, errs.NewWebhookNotFoundError()
+	}
+
+	return webhook, nil
+}
+
+func (s *WebhookService) Create(webhook models.Webhook, owner string) (models.Webhook, error) {
+	err := s.Validator.Struct(webhook)
+	if err != nil {
+		return webhook, errs.NewWebhookValidationError(err)
+	}
+
+	if err = s.targets.CheckURL(webhook.URL); err != nil {
+		return webhook, err
+	}
+
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err = rand.Read(secret); err != nil {
+			return webhook, err
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+
+	webhook.Owner = owner
+	webhook.Active = true
+	webhook.ConsecutiveFailures = 0
+	webhook.CreatedAt = time.Now().UTC()
+
+	return s.Webhooks.Create(webhook)
+}
+
+func (s *WebhookService) Delete(id int, owner string) error {
+	_, err := s.get(id, owner)
+	if err != nil {
+		return err
+	}
+
+	return s.Webhooks.Delete(id)
+}
+
+func (s *WebhookService) Enable(id int, owner string) (models.Webhook, error) {
+	webhook, err := s.get(id, owner)
+	if err != nil {
+		return webhook, err
+	}
+
+	webhook.Active = true
+	webhook.ConsecutiveFailures = 0
+
+	webhook, err = s.Webhooks.Update(webhook)
+	webhook.Secret = ""
+
+	return webhook, err
+}
+
+func (s *WebhookService) GetDeliveries(id int, owner string) ([]models.WebhookDelivery, error) {
+	_, err := s.get(id, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Webhooks.GetDeliveries(id)
+}
+
+func (s *WebhookService) Notify(change models.Change) {
+	webhooks, err := s.Webhooks.GetAll()
+	if err != nil {
+		return
+	}
+
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return
+	}
+
+	recipient := changeRecipient(change)
+	for _, w := range webhooks {
+		if !w.Active || !w.IsSubscribed(change.Type) {
+			continue
+		}
+
+		if recipient != "" && recipient != w.Owner {
+			continue
+		}
+
+		deliveryID, err := newDeliveryID()
+		if err != nil {
+			continue
+		}
+
+		s.enqueue(webhookJob{
+			webhookID:  w.ID,
+			deliveryID: deliveryID,
+			eventType:  change.Type,
+			payload:    payload,
+			attempt:    1,
+		})
+	}
+}
+
+func (s *WebhookService) Run(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-s.queue:
+					s.deliver(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+func (s *WebhookService) enqueue(job webhookJob) {
+	select {
+	case s.queue <- job:
+	default:
+		s.finish(job, models.WebhookDelivery{Error: "delivery queue is full"})
+	}
+}
+
+func (s *WebhookService) deliver(ctx context.Context, job webhookJob) {
+	webhook, err := s.Webhooks.Get(job.webhookID)
+	if err != nil || !webhook.Active {
+		return
+	}
+
+	start := time.Now()
+	delivery := models.WebhookDelivery{}
+
+	statusCode, err := s.send(ctx, webhook, job)
+	delivery.StatusCode = statusCode
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+	} else {
+		delivery.Success = true
+	}
+
+	if !delivery.Success && job.attempt < s.maxAttempts {
+		s.record(job, delivery)
+		next := job
+		next.attempt++
+		time.AfterFunc(s.backoff<<uint(job.attempt-1), func() {
+			s.enqueue(next)
+		})
+
+		return
+	}
+
+	s.finish(job, delivery)
+}
+
+func (s *WebhookService) send(ctx context.Context, webhook models.Webhook, job webhookJob) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(job.payload))
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookEventHeader, job.eventType)
+	request.Header.Set(WebhookDeliveryHeader, job.deliveryID)
+	request.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, job.payload))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("endpoint responded with status %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
+
+func (s *WebhookService) record(job webhookJob, delivery models.WebhookDelivery) {
+	delivery.WebhookID = job.webhookID
+	delivery.DeliveryID = job.deliveryID
+	delivery.EventType = job.eventType
+	delivery.Attempt = job.attempt
+	delivery.Timestamp = time.Now().UTC()
+	delivery.Payload = job.payload
+
+	_, _ = s.Webhooks.AddDelivery(delivery)
+}
+
+func (s *WebhookService) finish(job webhookJob, delivery models.WebhookDelivery) {
+	s.record(job, delivery)
+	_, _ = s.Webhooks.RecordDeliveryResult(job.webhookID, delivery.Success, s.disableAfter)
+}
+
+func newDeliveryID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"workshop2/internal/app/errs"
+)
+
+// WebhookTargets limits where webhooks can point, so users can't make the
+// server call internal services on their behalf. Plain HTTP and private
+// addresses are only meant for development.
+type WebhookTargets struct {
+	AllowHTTP    bool
+	AllowPrivate bool
+}
+
+var privateNetworks = parseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"fc00::/7",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+
+	return networks
+}
+
+// CheckURL rejects URLs that are refused outright. Host names are checked
+// again once they are resolved, see Client.
+func (t WebhookTargets) CheckURL(raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil || target.Hostname() == "" {
+		return errs.NewWebhookTargetError(raw)
+	}
+
+	switch target.Scheme {
+	case "https":
+	case "http":
+		if !t.AllowHTTP {
+			return errs.NewWebhookTargetError(raw)
+		}
+	default:
+		return errs.NewWebhookTargetError(raw)
+	}
+
+	host := strings.ToLower(target.Hostname())
+	if ip := net.ParseIP(host); ip != nil {
+		if !t.allowedIP(ip) {
+			return errs.NewWebhookTargetError(raw)
+		}
+	} else if !t.AllowPrivate && (host == "localhost" || strings.HasSuffix(host, ".localhost")) {
+		return errs.NewWebhookTargetError(raw)
+	}
+
+	return nil
+}
+
+// Client returns an HTTP client that checks every address it connects to,
+// so a host name that resolves to a private address, or starts to after
+// the webhook was created, is refused as well. Proxies and redirects are
+// not followed since either would hide the real target.
+func (t WebhookTargets) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !t.allowedIP(ip) {
+				return fmt.Errorf("webhook target %s is not allowed", host)
+			}
+
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, address)
+			},
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 4,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func (t WebhookTargets) allowedIP(ip net.IP) bool {
+	if t.AllowPrivate {
+		return true
+	}
+
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"workshop2/internal/app/errs"
+	"workshop2/internal/app/models"
+	"workshop2/internal/app/repositories"
+	"workshop2/internal/app/utils"
+)
+
+func newTestWebhookService(disableAfter int) *WebhookService {
+	return NewWebhook(
+		&repositories.WebhookRepository{},
+		utils.NewValidator(),
+		WebhookTargets{AllowHTTP: true, AllowPrivate: true},
+		time.Second,
+		3,
+		time.Millisecond,
+		disableAfter,
+		16,
+	)
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 2)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition was not met in time")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("delivers signed payload", func(t *testing.T) {
+		var received int32
+		secret := "s3cr3t"
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			if r.Header.Get(WebhookSignatureHeader) != SignWebhookPayload(secret, body) {
+				t.Errorf("signature mismatch")
+			}
+			if r.Header.Get(WebhookEventHeader) != models.ChangeEventCreated {
+				t.Errorf("unexpected event type %s", r.Header.Get(WebhookEventHeader))
+			}
+			atomic.AddInt32(&received, 1)
+		}))
+		defer receiver.Close()
+
+		service := newTestWebhookService(2)
+		service.Run(ctx, 1)
+
+		webhook, err := service.Create(models.Webhook{
+			URL:        receiver.URL,
+			Secret:     secret,
+			EventTypes: []string{models.ChangeEventCreated},
+		}, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		service.Notify(models.Change{Type: models.ChangeEventUpdated})
+		service.Notify(models.Change{Type: models.ChangeEventCreated, Data: models.Event{ID: 1}})
+
+		waitFor(t, func() bool {
+			deliveries, _ := service.GetDeliveries(webhook.ID, "alice")
+			return len(deliveries) == 1
+		})
+
+		deliveries, _ := service.GetDeliveries(webhook.ID, "alice")
+		if !deliveries[0].Success || atomic.LoadInt32(&received) != 1 {
+			t.Errorf("expected a single successful delivery, got %+v", deliveries)
+		}
+	})
+
+	t.Run("retries and disables failing endpoint", func(t *testing.T) {
+		var received int32
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&received, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer receiver.Close()
+
+		service := newTestWebhookService(2)
+		service.Run(ctx, 1)
+
+		webhook, err := service.Create(models.Webhook{
+			URL:        receiver.URL,
+			EventTypes: []string{models.ChangeEventDeleted},
+		}, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		service.Notify(models.Change{Type: models.ChangeEventDeleted})
+		waitFor(t, func() bool {
+			deliveries, _ := service.GetDeliveries(webhook.ID, "alice")
+			return len(deliveries) == 3
+		})
+
+		webhook, _ = service.Get(webhook.ID, "alice")
+		if !webhook.Active || webhook.ConsecutiveFailures != 1 {
+			t.Errorf("webhook must stay active after the first failed delivery, got %+v", webhook)
+		}
+
+		service.Notify(models.Change{Type: models.ChangeEventDeleted})
+		waitFor(t, func() bool {
+			webhook, _ = service.Get(webhook.ID, "alice")
+			return !webhook.Active
+		})
+
+		if atomic.LoadInt32(&received) != 6 {
+			t.Errorf("expected 6 attempts, got %d", received)
+		}
+	})
+
+	t.Run("hides webhooks of other users", func(t *testing.T) {
+		service := newTestWebhookService(2)
+		webhook, err := service.Create(models.Webhook{
+			URL:        "http://localhost/hook",
+			EventTypes: []string{models.ChangeNotificationDue},
+		}, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = service.Get(webhook.ID, "bob")
+		if err == nil {
+			t.Errorf("bob must not see alice's webhook")
+		}
+	})
+
+	t.Run("returns validation error", func(t *testing.T) {
+		service := newTestWebhookService(2)
+		_, err := service.Create(models.Webhook{
+			URL:        "http://localhost/hook",
+			EventTypes: []string{"event.exploded"},
+		}, "alice")
+		if err == nil {
+			t.Errorf("unknown event types must be rejected")
+		}
+	})
+}
+
+func TestWebhookTargets(t *testing.T) {
+	t.Run("rejects private and plain HTTP URLs", func(t *testing.T) {
+		targets := WebhookTargets{}
+		rejected := []string{
+			"http://hooks.example.com/hook",
+			"ftp://hooks.example.com/hook",
+			"https://localhost/hook",
+			"https://api.localhost/hook",
+			"https://127.0.0.1/hook",
+			"https://[::1]/hook",
+			"https://[::ffff:127.0.0.1]/hook",
+			"https://10.1.2.3/hook",
+			"https://172.16.0.1/hook",
+			"https://192.168.1.1/hook",
+			"https://169.254.169.254/latest/meta-data",
+			"https://[fe80::1]/hook",
+			"https://[fd00::1]/hook",
+			"https://0.0.0.0/hook",
+			"https:///hook",
+		}
+		for _, raw := range rejected {
+			if _, ok := targets.CheckURL(raw).(*errs.WebhookTargetError); !ok {
+				t.Errorf("expected %s to be rejected", raw)
+			}
+		}
+
+		for _, raw := range []string{"https://hooks.example.com/hook", "https://93.184.216.34:8443/hook"} {
+			if err := targets.CheckURL(raw); err != nil {
+				t.Errorf("expected %s to be allowed, got %v", raw, err)
+			}
+		}
+
+		relaxed := WebhookTargets{AllowHTTP: true, AllowPrivate: true}
+		if err := relaxed.CheckURL("http://127.0.0.1:8080/hook"); err != nil {
+			t.Errorf("expected the development switches to allow local HTTP, got %v", err)
+		}
+	})
+
+	t.Run("checks the resolved address", func(t *testing.T) {
+		var received int32
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&received, 1)
+		}))
+		defer receiver.Close()
+
+		// localhost passes a name-only check, like a public name that
+		// resolves to a private address would.
+		_, port, _ := net.SplitHostPort(receiver.Listener.Addr().String())
+		client := WebhookTargets{AllowHTTP: true}.Client(time.Second)
+		for _, target := range []string{receiver.URL, "http://localhost:" + port} {
+			response, err := client.Get(target)
+			if err == nil {
+				response.Body.Close()
+				t.Errorf("expected the connection to %s to be refused", target)
+			} else if !strings.Contains(err.Error(), "is not allowed") {
+				t.Errorf("expected %s to be refused by the target check, got %v", target, err)
+			}
+		}
+
+		if atomic.LoadInt32(&received) != 0 {
+			t.Errorf("expected no request to reach the receiver, got %d", received)
+		}
+	})
+
+	t.Run("does not follow redirects", func(t *testing.T) {
+		var redirected int32
+		internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&redirected, 1)
+		}))
+		defer internal.Close()
+
+		receiver := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusTemporaryRedirect))
+		defer receiver.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		service := newTestWebhookService(2)
+		service.Run(ctx, 1)
+		webhook, err := service.Create(models.Webhook{URL: receiver.URL, EventTypes: []string{models.ChangeEventCreated}}, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		service.Notify(models.Change{Type: models.ChangeEventCreated, Data: models.Event{ID: 1}})
+		waitFor(t, func() bool {
+			deliveries, _ := service.GetDeliveries(webhook.ID, "alice")
+			return len(deliveries) > 0
+		})
+
+		deliveries, _ := service.GetDeliveries(webhook.ID, "alice")
+		if deliveries[0].Success || deliveries[0].StatusCode != http.StatusTemporaryRedirect {
+			t.Errorf("expected the redirect to fail the delivery, got %+v", deliveries[0])
+		}
+		if atomic.LoadInt32(&redirected) != 0 {
+			t.Error("expected the redirect not to be followed")
+		}
+	})
+}
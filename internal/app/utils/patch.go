@@ -0,0 +1,420 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+	"workshop2/internal/app/errs"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+type PatchInterface interface {
+	Apply(document []byte) ([]byte, error)
+}
+
+func NewPatch(contentType string, data []byte) (PatchInterface, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, errs.NewUnsupportedMediaTypeError()
+	}
+
+	switch mediaType {
+	case MergePatchContentType:
+		return NewMergePatch(data)
+	case JSONPatchContentType:
+		return NewJSONPatch(data)
+	}
+
+	return nil, errs.NewUnsupportedMediaTypeError()
+}
+
+// MergePatch implements RFC 7396 JSON Merge Patch.
+type MergePatch struct {
+	patch interface{}
+}
+
+func NewMergePatch(data []byte) (*MergePatch, error) {
+	var patch interface{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, errs.NewInvalidPatchError(err.Error())
+	}
+
+	return &MergePatch{patch: patch}, nil
+}
+
+func (p *MergePatch) Apply(document []byte) ([]byte, error) {
+	var doc interface{}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergePatch(doc, p.patch))
+}
+
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+type jsonPatchOperation struct {
+	op    string
+	path  []string
+	from  []string
+	value interface{}
+}
+
+// JSONPatch implements RFC 6902 JSON Patch.
+type JSONPatch struct {
+	operations []jsonPatchOperation
+}
+
+func NewJSONPatch(data []byte) (*JSONPatch, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errs.NewInvalidPatchError(err.Error())
+	}
+
+	patch := &JSONPatch{}
+	for i, r := range raw {
+		operation, err := parseJSONPatchOperation(r)
+		if err != nil {
+			return nil, errs.NewInvalidPatchError(fmt.Sprintf("operation %d: %s", i, err.Error()))
+		}
+
+		patch.operations = append(patch.operations, operation)
+	}
+
+	return patch, nil
+}
+
+func parseJSONPatchOperation(raw map[string]json.RawMessage) (jsonPatchOperation, error) {
+	var operation jsonPatchOperation
+	var path, from string
+
+	if err := json.Unmarshal(raw["op"], &operation.op); err != nil {
+		return operation, fmt.Errorf("op is a required string")
+	}
+
+	if err := json.Unmarshal(raw["path"], &path); err != nil {
+		return operation, fmt.Errorf("path is a required string")
+	}
+
+	var err error
+	if operation.path, err = parsePointer(path); err != nil {
+		return operation, err
+	}
+
+	switch operation.op {
+	case "add", "replace", "test":
+		value, ok := raw["value"]
+		if !ok {
+			return operation, fmt.Errorf("value is required for %s", operation.op)
+		}
+
+		if err := json.Unmarshal(value, &operation.value); err != nil {
+			return operation, err
+		}
+	case "move", "copy":
+		if err := json.Unmarshal(raw["from"], &from); err != nil {
+			return operation, fmt.Errorf("from is required for %s", operation.op)
+		}
+
+		if operation.from, err = parsePointer(from); err != nil {
+			return operation, err
+		}
+	case "remove":
+	default:
+		return operation, fmt.Errorf("unknown op %q", operation.op)
+	}
+
+	return operation, nil
+}
+
+func (p *JSONPatch) Apply(document []byte) ([]byte, error) {
+	var doc interface{}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, err
+	}
+
+	for i, operation := range p.operations {
+		var err error
+		doc, err = operation.apply(doc)
+		if err != nil {
+			return nil, errs.NewInvalidPatchError(fmt.Sprintf("operation %d: %s", i, err.Error()))
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+func (o jsonPatchOperation) apply(doc interface{}) (interface{}, error) {
+	switch o.op {
+	case "add":
+		return pointerAdd(doc, o.path, o.value)
+	case "remove":
+		return pointerRemove(doc, o.path)
+	case "replace":
+		if _, err := pointerGet(doc, o.path); err != nil {
+			return nil, err
+		}
+
+		return pointerSet(doc, o.path, o.value)
+	case "move":
+		if isPointerPrefix(o.from, o.path) && len(o.from) < len(o.path) {
+			return nil, fmt.Errorf("can't move a value into one of its children")
+		}
+
+		value, err := pointerGet(doc, o.from)
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err = pointerRemove(doc, o.from)
+		if err != nil {
+			return nil, err
+		}
+
+		return pointerAdd(doc, o.path, value)
+	case "copy":
+		value, err := pointerGet(doc, o.from)
+		if err != nil {
+			return nil, err
+		}
+
+		return pointerAdd(doc, o.path, deepCopy(value))
+	case "test":
+		value, err := pointerGet(doc, o.path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reflect.DeepEqual(value, o.value) {
+			return nil, fmt.Errorf("test failed for %s", formatPointer(o.path))
+		}
+
+		return doc, nil
+	}
+
+	return nil, fmt.Errorf("unknown op %q", o.op)
+}
+
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func formatPointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+
+	return b.String()
+}
+
+func isPointerPrefix(prefix []string, tokens []string) bool {
+	if len(prefix) > len(tokens) {
+		return false
+	}
+
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("%q is not a valid array index", token)
+	}
+
+	if index > length || (index == length && !allowEnd) {
+		return 0, fmt.Errorf("array index %d is out of bounds", index)
+	}
+
+	return index, nil
+}
+
+func pointerGet(doc interface{}, tokens []string) (interface{}, error) {
+	current := doc
+	for i, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %s does not exist", formatPointer(tokens[:i+1]))
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path %s does not exist", formatPointer(tokens[:i+1]))
+		}
+	}
+
+	return current, nil
+}
+
+func pointerSet(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parent, err := pointerGet(doc, tokens[:len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[key] = value
+	case []interface{}:
+		index, err := arrayIndex(key, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = value
+	default:
+		return nil, fmt.Errorf("path %s does not exist", formatPointer(tokens))
+	}
+
+	return doc, nil
+}
+
+func pointerAdd(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parentTokens := tokens[:len(tokens)-1]
+	parent, err := pointerGet(doc, parentTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	key := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[key] = value
+		return doc, nil
+	case []interface{}:
+		index, err := arrayIndex(key, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]interface{}, 0, len(node)+1)
+		array = append(array, node[:index]...)
+		array = append(array, value)
+		array = append(array, node[index:]...)
+
+		return pointerSet(doc, parentTokens, array)
+	}
+
+	return nil, fmt.Errorf("path %s does not exist", formatPointer(tokens))
+}
+
+func pointerRemove(doc interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("can't remove the whole document")
+	}
+
+	parentTokens := tokens[:len(tokens)-1]
+	parent, err := pointerGet(doc, parentTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	key := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[key]; !ok {
+			return nil, fmt.Errorf("path %s does not exist", formatPointer(tokens))
+		}
+		delete(node, key)
+		return doc, nil
+	case []interface{}:
+		index, err := arrayIndex(key, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]interface{}, 0, len(node)-1)
+		array = append(array, node[:index]...)
+		array = append(array, node[index+1:]...)
+
+		return pointerSet(doc, parentTokens, array)
+	}
+
+	return nil, fmt.Errorf("path %s does not exist", formatPointer(tokens))
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	}
+
+	return value
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func assertJSONEqual(t *testing.T, expected string, actual []byte) {
+	t.Helper()
+
+	var e, a interface{}
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(actual, &a); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		expected string
+	}{
+		{"replaces value", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"adds value", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"removes value", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"replaces array", `{"a":["b"]}`, `{"a":["c","d"]}`, `{"a":["c","d"]}`},
+		{"merges nested", `{"a":{"b":"c","d":"e"}}`, `{"a":{"b":"x","d":null}}`, `{"a":{"b":"x"}}`},
+		{"replaces non object", `{"a":"b"}`, `["c"]`, `["c"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := NewMergePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := patch.Apply([]byte(tt.document))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertJSONEqual(t, tt.expected, result)
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		expected string
+	}{
+		{"adds member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`},
+		{"adds array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"appends array element", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"qux"}]`, `{"foo":["bar","qux"]}`},
+		{"removes member", `{"foo":"bar","baz":"qux"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"removes array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replaces value", `{"foo":"bar"}`, `[{"op":"replace","path":"/foo","value":null}]`, `{"foo":null}`},
+		{"moves value", `{"foo":{"bar":"baz"},"qux":{}}`, `[{"op":"move","from":"/foo/bar","path":"/qux/thud"}]`, `{"foo":{},"qux":{"thud":"baz"}}`},
+		{"copies value", `{"foo":{"bar":"baz"}}`, `[{"op":"copy","from":"/foo","path":"/qux"}]`, `{"foo":{"bar":"baz"},"qux":{"bar":"baz"}}`},
+		{"tests value", `{"foo":["a",2]}`, `[{"op":"test","path":"/foo","value":["a",2]}]`, `{"foo":["a",2]}`},
+		{"escapes pointer", `{"a/b":1,"m~n":2}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, `{"a/b":3}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := NewJSONPatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := patch.Apply([]byte(tt.document))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertJSONEqual(t, tt.expected, result)
+		})
+	}
+
+	failures := []struct {
+		name     string
+		document string
+		patch    string
+	}{
+		{"fails on missing path", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`},
+		{"fails on failed test", `{"foo":"bar"}`, `[{"op":"test","path":"/foo","value":"baz"}]`},
+		{"fails on out of bounds index", `{"foo":[]}`, `[{"op":"add","path":"/foo/1","value":1}]`},
+		{"fails on move into child", `{"foo":{}}`, `[{"op":"move","from":"/foo","path":"/foo/bar"}]`},
+	}
+
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := NewJSONPatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = patch.Apply([]byte(tt.document))
+			if err == nil {
+				t.Errorf("patch must fail")
+			}
+		})
+	}
+
+	t.Run("fails on unknown op", func(t *testing.T) {
+		_, err := NewJSONPatch([]byte(`[{"op":"frobnicate","path":"/foo"}]`))
+		if err == nil {
+			t.Errorf("patch must fail")
+		}
+	})
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"workshop2/internal/app/i18n"
 
+	ut "github.com/go-playground/universal-translator"
 	"gopkg.in/go-playground/validator.v9"
 )
 
@@ -38,22 +40,32 @@ func (v *Validator) Struct(s interface{}) error {
 	if errs != nil {
 		fieldErrors, _ := errs.(validator.ValidationErrors)
 		for _, err := range fieldErrors {
-			switch err.Tag() {
-			case "required":
-				return fmt.Errorf("%s is a required field", err.Field())
-			case "max":
-				return fmt.Errorf("%s must be maximum of %s in length", err.Field(), err.Param())
-			case "min":
-				return fmt.Errorf("%s must be minimum of %s in length", err.Field(), err.Param())
-			case "alphanum":
-				return fmt.Errorf("%s must containt only alphanumeric characters", err.Field())
-			case "containsany":
-				return fmt.Errorf("%s must containt at least one of %s characters", err.Field(), err.Param())
-			default:
-				return fmt.Errorf("something wrong on %s; %s", err.Field(), err.Tag())
+			return &ValidationError{
+				Field: err.Field(),
+				Tag:   err.Tag(),
+				Param: err.Param(),
 			}
 		}
 	}
 
 	return nil
 }
+
+type ValidationError struct {
+	Field string
+	Tag   string
+	Param string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Translate(i18n.Default())
+}
+
+func (e *ValidationError) Translate(trans ut.Translator) string {
+	message, err := trans.T("validation."+e.Tag, e.Field, e.Param)
+	if err != nil {
+		return i18n.T(trans, "validation.default", fmt.Sprintf("something wrong on %s; %s", e.Field, e.Tag), e.Field, e.Tag)
+	}
+
+	return message
+}